@@ -91,6 +91,211 @@ describe 'streaming-mysql-backup-client job' do
       end
     end
 
+    context('when an instance of the backup tool is named') do
+      let(:spec) {{
+        "cf-mysql-backup" => {
+          'symmetric_key' => 'some-symmetric-key',
+          'backup-client' => {
+            'tool_instance' => 'mysql-2'
+          },
+          'tls' => {
+            'ca_certificate' => 'some-ca'
+          }
+        }
+      }}
+
+      let(:tool_instances) {[
+        { 'name' => 'mysql-2', 'defaults_file' => '/var/vcap/jobs/mysql-2/config/mylogin.cnf' }
+      ]}
+      let(:links) {[
+        Bosh::Template::Test::Link.new(
+          name: 'mysql-backup-tool',
+          instances: [
+            Bosh::Template::Test::LinkInstance.new(address: 'backup-instance-address-1', id: 'instance-id-1'),
+            Bosh::Template::Test::LinkInstance.new(address: 'backup-instance-address-2', id: 'instance-id-2')
+          ],
+          properties: {
+            'cf-mysql-backup' => {
+              'endpoint_credentials' => {
+                'username' => 'some-username',
+                'password' => 'some-password'
+              },
+              'instances' => tool_instances
+            }
+          }
+        )
+      ]}
+
+      it 'backs up the named instance on every backup tool' do
+        tpl_output = template.render(spec, consumes: links)
+        tpl_yaml = YAML.load(tpl_output)
+        expect(tpl_yaml['Instances']).to contain_exactly(
+          { "Address" => "backup-instance-address-1", "UUID" => "instance-id-1", "Name" => "mysql-2" },
+          { "Address" => "backup-instance-address-2", "UUID" => "instance-id-2", "Name" => "mysql-2" },
+        )
+      end
+
+      context('and the instance has credentials of its own') do
+        let(:tool_instances) {[
+          {
+            'name' => 'mysql-2',
+            'defaults_file' => '/var/vcap/jobs/mysql-2/config/mylogin.cnf',
+            'credentials' => {
+              'username' => 'mysql-2-username',
+              'password' => 'mysql-2-password'
+            }
+          }
+        ]}
+
+        it 'authenticates to the named instance with them' do
+          tpl_output = template.render(spec, consumes: links)
+          tpl_yaml = YAML.load(tpl_output)
+          credentials = { "Username" => "mysql-2-username", "Password" => "mysql-2-password" }
+          expect(tpl_yaml['Instances']).to contain_exactly(
+            { "Address" => "backup-instance-address-1", "UUID" => "instance-id-1", "Name" => "mysql-2", "Credentials" => credentials },
+            { "Address" => "backup-instance-address-2", "UUID" => "instance-id-2", "Name" => "mysql-2", "Credentials" => credentials },
+          )
+        end
+      end
+
+      context('and the backup tool has no such instance') do
+        let(:tool_instances) { [] }
+
+        it 'fails to render' do
+          expect { template.render(spec, consumes: links) }.to raise_error(/tool_instance 'mysql-2' is not one of the cf-mysql-backup.instances/)
+        end
+      end
+    end
+
+    context('when a stall timeout is configured') do
+      let(:spec) {{
+        "cf-mysql-backup" => {
+          'symmetric_key' => 'some-symmetric-key',
+          'backup-client' => {
+            'stall_timeout' => '45m'
+          },
+          'tls' => {
+            'ca_certificate' => 'some-ca'
+          }
+        }
+      }}
+
+      it 'configures the stall timeout' do
+        tpl_output = template.render(spec, consumes: links)
+        tpl_yaml = YAML.load(tpl_output)
+        expect(tpl_yaml['StallTimeout']).to eq('45m')
+      end
+    end
+
+    context('when tracing is configured') do
+      let(:spec) {{
+        "cf-mysql-backup" => {
+          'symmetric_key' => 'some-symmetric-key',
+          'backup-client' => {
+            'tracing' => {
+              'exporter' => 'otlp',
+              'endpoint' => 'http://otel-collector.service.internal:4318/v1/traces'
+            }
+          },
+          'tls' => {
+            'ca_certificate' => 'some-ca'
+          }
+        }
+      }}
+
+      it 'exports spans as configured' do
+        tpl_output = template.render(spec, consumes: links)
+        tpl_yaml = YAML.load(tpl_output)
+        expect(tpl_yaml['Tracing']).to eq({
+          'Exporter' => 'otlp',
+          'Endpoint' => 'http://otel-collector.service.internal:4318/v1/traces',
+          'File' => '/var/vcap/sys/log/streaming-mysql-backup-client/spans.json',
+        })
+      end
+    end
+
+    context('when the backup tool uploads backups to object storage') do
+      let(:links) {[
+        Bosh::Template::Test::Link.new(
+          name: 'mysql-backup-tool',
+          instances: [
+            Bosh::Template::Test::LinkInstance.new(address: 'backup-instance-address-1', id: 'instance-id-1')
+          ],
+          properties: {
+            'cf-mysql-backup' => {
+              'endpoint_credentials' => {
+                'username' => 'some-username',
+                'password' => 'some-password'
+              },
+              'object_store' => {
+                'endpoint' => 'https://minio.example.com:9000'
+              }
+            }
+          }
+        )
+      ]}
+      let(:spec) {{
+        "cf-mysql-backup" => {
+          'symmetric_key' => 'some-symmetric-key',
+          'backup-client' => {
+            'upload_timeout' => '12h'
+          },
+          'tls' => {
+            'ca_certificate' => 'some-ca'
+          }
+        }
+      }}
+
+      it 'waits for the uploads instead of downloading backups' do
+        tpl_output = template.render(spec, consumes: links)
+        tpl_yaml = YAML.load(tpl_output)
+        expect(tpl_yaml['AsyncUpload']).to eq({
+          'Enabled' => true,
+          'PollInterval' => '30s',
+          'Timeout' => '12h',
+        })
+      end
+    end
+
+    context('when the backup tool is configured with a keyring') do
+      let(:links) {[
+        Bosh::Template::Test::Link.new(
+          name: 'mysql-backup-tool',
+          instances: [
+            Bosh::Template::Test::LinkInstance.new(address: 'backup-instance-address-1', id: 'instance-id-1')
+          ],
+          properties: {
+            'cf-mysql-backup' => {
+              'endpoint_credentials' => {
+                'username' => 'some-username',
+                'password' => 'some-password'
+              },
+              'keyring' => {
+                'encryption_key' => 'some-keyring-key'
+              }
+            }
+          }
+        )
+      ]}
+      let(:spec) {{
+        "cf-mysql-backup" => {
+          'symmetric_key' => 'some-symmetric-key',
+          'tls' => {
+            'ca_certificate' => 'some-ca'
+          }
+        }
+      }}
+
+      it 'prepares backups with the same keys' do
+        tpl_output = template.render(spec, consumes: links)
+        tpl_yaml = YAML.load(tpl_output)
+        expect(tpl_yaml['Keyring']).to eq({
+          'TransitionKey' => '',
+          'EncryptionKey' => 'some-keyring-key',
+        })
+      end
+    end
+
     context('when backup_local_node_only is not set') do
       let(:spec) {{
         "cf-mysql-backup" => {
@@ -55,6 +55,520 @@ describe 'streaming-mysql-backup-tool job' do
           expect(tpl_yaml['Credentials']['Password']).to eq('some-password')
         end
       end
+
+      context('when backup log retention is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'tls' => {
+              'server_certificate' => 'some-cert',
+              'server_key' => 'some-key',
+            },
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'backup_logs' => {
+              'max_count' => 5,
+              'max_age' => '24h'
+            }
+          }
+        }}
+
+        it 'configures per-backup logs' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['BackupLogs']['Directory']).to eq('/var/vcap/data/streaming-mysql-backup-tool/backup-logs')
+          expect(tpl_yaml['BackupLogs']['MaxCount']).to eq(5)
+          expect(tpl_yaml['BackupLogs']['MaxAge']).to eq('24h')
+        end
+      end
+
+      context('when backup history is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'tls' => {
+              'server_certificate' => 'some-cert',
+              'server_key' => 'some-key',
+            },
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'history' => {
+              'max_records' => 50
+            },
+            'xtrabackup_history_name' => 'nightly'
+          }
+        }}
+
+        it 'configures the backup history' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['History']['File']).to eq('/var/vcap/store/streaming-mysql-backup-tool/history.jsonl')
+          expect(tpl_yaml['History']['MaxRecords']).to eq(50)
+          expect(tpl_yaml['XtraBackup']['HistoryName']).to eq('nightly')
+        end
+      end
+
+      context('when the disk guard is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'tls' => {
+              'server_certificate' => 'some-cert',
+              'server_key' => 'some-key',
+            },
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'disk_guard' => {
+              'min_free_bytes' => 10737418240,
+              'min_free_percent' => 10,
+              'redo_bytes_per_second' => 1048576,
+              'expected_duration' => '1h',
+              'check_interval' => '10s'
+            }
+          }
+        }}
+
+        it 'configures the disk guard' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['DiskGuard']).to eq({
+            'MinFreeBytes' => 10737418240,
+            'MinFreePercent' => 10,
+            'RedoBytesPerSecond' => 1048576,
+            'ExpectedDuration' => '1h',
+            'CheckInterval' => '10s',
+          })
+        end
+      end
+
+      context('when the maximum pause duration and stall timeout are configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'tls' => {
+              'server_certificate' => 'some-cert',
+              'server_key' => 'some-key',
+            },
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'max_pause' => '5m',
+            'stall_timeout' => '20m'
+          }
+        }}
+
+        it 'configures the maximum pause duration and stall timeout' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['MaxPause']).to eq('5m')
+          expect(tpl_yaml['XtraBackup']['StallTimeout']).to eq('20m')
+        end
+      end
+
+      context('when replication options are set') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'replication' => {
+              'slave_info' => true,
+              'safe_slave_backup' => true,
+              'safe_slave_backup_timeout' => '5m',
+              'galera_info' => true
+            }
+          }
+        }}
+
+        it 'passes the replication options to xtrabackup' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['SlaveInfo']).to eq(true)
+          expect(tpl_yaml['XtraBackup']['SafeSlaveBackup']).to eq(true)
+          expect(tpl_yaml['XtraBackup']['SafeSlaveBackupTimeout']).to eq('5m')
+          expect(tpl_yaml['XtraBackup']['GaleraInfo']).to eq(true)
+        end
+      end
+
+      context('when redo log archiving is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'redo_log_archive_dir' => 'backup:/var/vcap/store/redo-log-archive'
+          }
+        }}
+
+        it 'passes the redo log archive dir to xtrabackup' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['RedoLogArchiveDir']).to eq('backup:/var/vcap/store/redo-log-archive')
+        end
+      end
+
+      context('when a bundle is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'bundle' => {
+              'files' => ['/var/vcap/jobs/pxc-mysql/config/my.cnf', '/var/vcap/store/pxc-mysql/grastate.dat'],
+              'snapshots' => ['global_variables', 'server_version']
+            }
+          }
+        }}
+
+        it 'adds the files and snapshots to backups' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['Bundle']).to eq({
+            'Files' => ['/var/vcap/jobs/pxc-mysql/config/my.cnf', '/var/vcap/store/pxc-mysql/grastate.dat'],
+            'Snapshots' => ['global_variables', 'server_version']
+          })
+        end
+      end
+
+      context('when a keyring is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'keyring' => {
+              'component_config' => '/var/vcap/jobs/pxc-mysql/config/component_keyring_file.cnf',
+              'encryption_key' => 'some-keyring-key'
+            }
+          }
+        }}
+
+        it 'configures the keyring of xtrabackup' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['Keyring']).to eq({
+            'FileData' => '',
+            'ComponentConfig' => '/var/vcap/jobs/pxc-mysql/config/component_keyring_file.cnf',
+            'TransitionKey' => '',
+            'EncryptionKey' => 'some-keyring-key'
+          })
+        end
+      end
+
+      context('when maintenance is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'maintenance' => {
+              'sentinel_file' => '/var/vcap/data/maintenance',
+              'in_flight' => 'cancel',
+              'admin_credentials' => {
+                'username' => 'some-admin',
+                'password' => 'some-admin-password'
+              }
+            }
+          }
+        }}
+
+        it 'configures maintenance mode' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['Maintenance']).to eq({
+            'SentinelFile' => '/var/vcap/data/maintenance',
+            'PollInterval' => '5s',
+            'InFlight' => 'cancel',
+            'AdminIdentities' => [],
+            'AdminCredentials' => {
+              'Username' => 'some-admin',
+              'Password' => 'some-admin-password',
+            },
+          })
+        end
+      end
+
+      context('when brute-force protection and rate limits are configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'auth_guard' => {
+              'max_failures' => 5,
+              'lockout' => '1h'
+            },
+            'rate_limit' => {
+              'requests' => 10
+            }
+          }
+        }}
+
+        it 'configures the auth guard and rate limit' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['AuthGuard']).to eq({
+            'MaxFailures' => 5,
+            'BaseDelay' => '1s',
+            'MaxDelay' => '1m',
+            'Lockout' => '1h',
+          })
+          expect(tpl_yaml['RateLimit']).to eq({
+            'Requests' => 10,
+            'Interval' => '1m',
+          })
+        end
+      end
+
+      context('when an authorization policy is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'policy' => {
+              'rules' => rules
+            }
+          }
+        }}
+        let(:rules) {[
+          {
+            'identities' => ['backup-client'],
+            'networks' => ['10.0.0.0/24'],
+            'operations' => ['backup', 'status'],
+            'formats' => ['xbstream']
+          },
+          {
+            'identities' => ['operator'],
+            'operations' => ['status', 'cancel']
+          }
+        ]}
+
+        it 'configures the policy' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['Policy']).to eq({
+            'Rules' => [
+              {
+                'Identities' => ['backup-client'],
+                'Networks' => ['10.0.0.0/24'],
+                'Operations' => ['backup', 'status'],
+                'Formats' => ['xbstream'],
+              },
+              {
+                'Identities' => ['operator'],
+                'Networks' => [],
+                'Operations' => ['status', 'cancel'],
+                'Formats' => [],
+              },
+            ]
+          })
+        end
+
+        context('when a rule grants restore') do
+          let(:rules) {[
+            {
+              'identities' => ['operator'],
+              'operations' => ['status', 'restore']
+            }
+          ]}
+
+          it 'raises an error' do
+            expect { template.render(spec) }.to raise_error(/cannot grant 'restore', the tool does not restore backups/)
+          end
+        end
+
+        context('when a rule sets partial backup filters') do
+          let(:rules) {[
+            {
+              'identities' => ['backup-client'],
+              'operations' => ['backup'],
+              'databases' => ['app']
+            }
+          ]}
+
+          it 'raises an error' do
+            expect { template.render(spec) }.to raise_error(/do not support databases/)
+          end
+        end
+      end
+
+      context('when tracing is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'tracing' => {
+              'exporter' => 'file'
+            }
+          }
+        }}
+
+        it 'exports spans as configured' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['Tracing']).to eq({
+            'Exporter' => 'file',
+            'Endpoint' => 'http://127.0.0.1:4318/v1/traces',
+            'File' => '/var/vcap/sys/log/streaming-mysql-backup-tool/spans.json',
+          })
+        end
+      end
+
+      context('when the backup engine is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'engine' => 'auto'
+          }
+        }}
+
+        it 'configures the backup engine' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['Engine']).to eq('auto')
+        end
+      end
+
+      context('when xtrabackup binaries are configured per MySQL series') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'xtrabackup_binaries' => {
+              '5.7' => '/var/vcap/packages/xtrabackup/bin/xtrabackup',
+              '8.0' => '/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup'
+            }
+          }
+        }}
+
+        it 'configures the xtrabackup binaries' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['XtraBackup']['Binaries']).to eq({
+            '5.7' => '/var/vcap/packages/xtrabackup/bin/xtrabackup',
+            '8.0' => '/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup',
+          })
+        end
+      end
+
+      context('when further instances are configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'instances' => [
+              {
+                'name' => 'mysql-2',
+                'defaults_file' => '/var/vcap/jobs/mysql-2/config/mylogin.cnf',
+                'binary' => '/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup',
+                'credentials' => {
+                  'username' => 'mysql-2-username',
+                  'password' => 'mysql-2-password'
+                }
+              }
+            ]
+          }
+        }}
+
+        it 'configures the instances' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['Instances']).to eq([{
+            'Name' => 'mysql-2',
+            'DefaultsFile' => '/var/vcap/jobs/mysql-2/config/mylogin.cnf',
+            'TmpDir' => '',
+            'Binary' => '/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup',
+            'ClientIdentities' => [],
+            'Credentials' => {
+              'Username' => 'mysql-2-username',
+              'Password' => 'mysql-2-password',
+            },
+          }])
+        end
+      end
+
+      context('when sharing backups between clients is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'fan_out' => {
+              'join_window' => '30s',
+              'max_buffer_bytes' => 1048576,
+              'slow_consumer_policy' => 'spill',
+              'spill_dir' => '/var/vcap/data/spill'
+            }
+          }
+        }}
+
+        it 'configures the fan out' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['FanOut']).to eq({
+            'JoinWindow' => '30s',
+            'MaxBufferBytes' => 1048576,
+            'SlowConsumerPolicy' => 'spill',
+            'SpillDir' => '/var/vcap/data/spill',
+          })
+        end
+      end
+
+      context('when an object store is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'endpoint_credentials' => {
+              'username' => 'some-username',
+              'password' => 'some-password'
+            },
+            'object_store' => {
+              'endpoint' => 'https://minio.example.com:9000',
+              'bucket' => 'backups',
+              'prefix' => 'mysql',
+              'access_key_id' => 'some-access-key',
+              'secret_access_key' => 'some-secret-key',
+              'encryption_key' => 'some-encryption-key'
+            }
+          }
+        }}
+
+        it 'configures uploads to the object store' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['ObjectStore']).to eq({
+            'Endpoint' => 'https://minio.example.com:9000',
+            'Region' => 'us-east-1',
+            'Bucket' => 'backups',
+            'Prefix' => 'mysql',
+            'AccessKeyID' => 'some-access-key',
+            'SecretAccessKey' => 'some-secret-key',
+            'CA' => '',
+            'ChunkSize' => 16777216,
+            'EncryptionKey' => 'some-encryption-key',
+          })
+        end
+      end
     end
 
     context('when mutual tls is set') do
@@ -118,6 +632,38 @@ describe 'streaming-mysql-backup-tool job' do
         end
       end
 
+      context('when revocation checking is configured') do
+        let(:spec) {{
+          "cf-mysql-backup" => {
+            'enable_mutual_tls' => true,
+            'tls' => {
+              'client_ca' => 'some-ca',
+              'server_certificate' => 'some-cert',
+              'server_key' => 'some-key',
+              'client_hostnames' => ['hostname1'],
+              'revocation' => {
+                'crl_file' => '/var/vcap/store/crl/client.crl',
+                'ocsp' => true,
+                'failure_policy' => 'open'
+              }
+            }
+          }
+        }}
+
+        it 'configures revocation checking of client certificates' do
+          tpl_output = template.render(spec)
+          tpl_yaml = YAML.load(tpl_output)
+          expect(tpl_yaml['TLS']['Revocation']).to eq({
+            'CRLFile' => '/var/vcap/store/crl/client.crl',
+            'CRLReloadInterval' => '5m',
+            'OCSP' => true,
+            'OCSPResponder' => '',
+            'OCSPTimeout' => '5s',
+            'FailurePolicy' => 'open',
+          })
+        end
+      end
+
     end
   end
 end
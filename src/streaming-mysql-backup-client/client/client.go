@@ -2,21 +2,32 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/lager/v3"
+	"github.com/cloudfoundry/tracing"
+	"github.com/cloudfoundry/xtrabackuplog"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-client/config"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/cryptkeeper"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/download"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/fileutils"
+	"github.com/cloudfoundry/streaming-mysql-backup-client/prepare"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/tarpit"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/xbstream"
 )
@@ -41,17 +52,20 @@ func (e MultiError) Error() string {
 
 //counterfeiter:generate . Downloader
 type Downloader interface {
-	DownloadBackup(url string, streamer download.StreamedWriter) error
+	DownloadBackup(ctx context.Context, url string, streamer download.StreamedWriter) (download.Backup, error)
+	DownloadBackupLog(ctx context.Context, url string, w io.Writer) error
+	StartUpload(ctx context.Context, url string) (download.Upload, error)
+	UploadStatus(ctx context.Context, url string) (download.Upload, error)
 }
 
 //counterfeiter:generate . BackupPreparer
 type BackupPreparer interface {
-	Command(string) *exec.Cmd
+	Command(string, prepare.Keyring) *exec.Cmd
 }
 
 //counterfeiter:generate . GaleraAgentCallerInterface
 type GaleraAgentCallerInterface interface {
-	WsrepLocalIndex(string) (int, error)
+	WsrepLocalIndex(context.Context, string) (int, error)
 }
 
 type Client struct {
@@ -67,6 +81,7 @@ type Client struct {
 	encryptDirectory  string
 	encryptor         *cryptkeeper.CryptKeeper
 	metadataFields    map[string]string
+	tracer            *tracing.Tracer
 }
 
 func NewClient(config config.Config, tarClient *tarpit.TarClient, backupPreparer BackupPreparer, downloader Downloader, galeraAgentCaller GaleraAgentCallerInterface) *Client {
@@ -80,6 +95,7 @@ func NewClient(config config.Config, tarClient *tarpit.TarClient, backupPreparer
 	client.logger = config.Logger
 	client.encryptor = cryptkeeper.NewCryptKeeper(config.SymmetricKey)
 	client.metadataFields = config.MetadataFields
+	client.tracer = config.Tracer
 	return client
 }
 
@@ -107,41 +123,24 @@ func (c *Client) finalMetadataLocation(uuid string) string {
 	return path.Join(c.config.OutputDir, fmt.Sprintf("%s.txt", c.artifactName(uuid)))
 }
 
-func (c *Client) Execute() error {
+func (c *Client) backupLogLocation(uuid string) string {
+	return path.Join(c.config.OutputDir, fmt.Sprintf("%s.log", c.artifactName(uuid)))
+}
+
+func (c *Client) Execute() (err error) {
+	ctx, span := c.tracer.Start(context.Background(), "backup")
+	defer func() { endSpan(span, err) }()
+
 	var allErrors MultiError
-	var instances []config.Instance
 
-	err := c.cleanTmpDirectories()
+	err = c.cleanTmpDirectories()
 	if err != nil {
 		return err
 	}
 
-	if c.config.BackupAllMasters {
-		instances = c.config.Instances
-	} else if c.config.BackupFromInactiveNode {
-		var largestIndexHealthy int
-		var largestIndexHealthyInstance config.Instance
-
-		for _, instance := range c.config.Instances {
-			wsrepIndex, err := c.galeraAgentCaller.WsrepLocalIndex(instance.Address)
-			if err != nil {
-				c.logger.Error("Fetching node status from galera agent failed", err, lager.Data{
-					"ip": instance.Address,
-				})
-			}
-			if wsrepIndex >= largestIndexHealthy {
-				largestIndexHealthy = wsrepIndex
-				largestIndexHealthyInstance = instance
-			}
-		}
-
-		if largestIndexHealthyInstance.Address == "" {
-			return errors.New("No healthy nodes found")
-		}
-
-		instances = []config.Instance{largestIndexHealthyInstance}
-	} else {
-		instances = []config.Instance{c.config.Instances[len(c.config.Instances)-1]}
+	instances, err := c.selectInstances(ctx)
+	if err != nil {
+		return err
 	}
 
 	for _, instance := range instances {
@@ -151,7 +150,7 @@ func (c *Client) Execute() error {
 			"ip": instance.Address,
 		})
 
-		err := c.BackupNode(instance)
+		err := c.BackupNode(ctx, instance)
 		if err != nil {
 			allErrors = append(allErrors, err)
 		}
@@ -164,25 +163,117 @@ func (c *Client) Execute() error {
 	return nil
 }
 
-func (c *Client) BackupNode(instance config.Instance) error {
-	var err error
+// selectInstances picks all instances, the healthy node with the largest wsrep_local_index, or the last one.
+func (c *Client) selectInstances(ctx context.Context) (instances []config.Instance, err error) {
+	ctx, span := c.tracer.Start(ctx, "select node")
+	defer func() { endSpan(span, err) }()
+
+	if c.config.BackupAllMasters {
+		return c.config.Instances, nil
+	}
+	if !c.config.BackupFromInactiveNode {
+		return []config.Instance{c.config.Instances[len(c.config.Instances)-1]}, nil
+	}
+
+	var largestIndexHealthy int
+	var largestIndexHealthyInstance config.Instance
+
+	for _, instance := range c.config.Instances {
+		wsrepIndex, err := c.wsrepLocalIndex(ctx, instance)
+		if err != nil {
+			c.logger.Error("Fetching node status from galera agent failed", err, lager.Data{
+				"ip": instance.Address,
+			})
+		}
+		if wsrepIndex >= largestIndexHealthy {
+			largestIndexHealthy = wsrepIndex
+			largestIndexHealthyInstance = instance
+		}
+	}
+
+	if largestIndexHealthyInstance.Address == "" {
+		return nil, errors.New("No healthy nodes found")
+	}
+
+	span.SetAttribute("instance.address", largestIndexHealthyInstance.Address)
+	return []config.Instance{largestIndexHealthyInstance}, nil
+}
+
+func (c *Client) wsrepLocalIndex(ctx context.Context, instance config.Instance) (index int, err error) {
+	ctx, span := c.tracer.Start(ctx, "galera-agent status")
+	span.SetKind(tracing.KindClient)
+	span.SetAttribute("instance.address", instance.Address)
+	defer func() {
+		span.SetAttribute("wsrep_local_index", index)
+		endSpan(span, err)
+	}()
+
+	return c.galeraAgentCaller.WsrepLocalIndex(ctx, instance.Address)
+}
+
+func (c *Client) BackupNode(ctx context.Context, instance config.Instance) (err error) {
+	ctx, span := c.tracer.Start(ctx, "backup node")
+	span.SetAttribute("instance.address", instance.Address)
+	span.SetAttribute("instance.uuid", instance.UUID)
+	if instance.Name != "" {
+		span.SetAttribute("instance.name", instance.Name)
+	}
+	defer func() { endSpan(span, err) }()
+
+	if instance.Credentials != nil {
+		ctx = download.WithCredentials(ctx, *instance.Credentials)
+	}
+
+	if c.config.AsyncUpload.Enabled {
+		return c.uploadBackup(ctx, instance)
+	}
+
 	err = c.createDirectories()
 	if err != nil {
 		return err
 	}
-	err = c.downloadAndUnpackBackup(instance.Address)
+	backup, err := c.downloadAndUnpackBackup(ctx, instance)
+	if backup.ID != "" {
+		span.SetAttribute("backup.id", backup.ID)
+		defer func() {
+			c.fetchBackupLog(ctx, instance, backup.ID, err)
+		}()
+	}
 	if err != nil {
 		return err
 	}
-	err = c.prepareBackup()
+	bundled, err := c.setAsideBundle()
 	if err != nil {
 		return err
 	}
-	err = c.writeMetadataFile(instance.UUID)
+	if backup.Metadata["backup_engine"] == prepare.Clone {
+		c.logger.Info("Skipping prepare of a backup taken with the CLONE plugin")
+	} else {
+		var keyring prepare.Keyring
+		keyring, err = c.unsealKeyring()
+		if err == nil {
+			err = c.prepareBackup(ctx, keyring)
+		}
+		c.removeUnsealedKeyring()
+		if err != nil {
+			return err
+		}
+	}
+	if bundled {
+		err = c.restoreBundle()
+		if err != nil {
+			return err
+		}
+		if backup.Metadata == nil {
+			backup.Metadata = map[string]string{}
+		}
+		backup.Metadata["bundle"] = bundleDir
+	}
+	err = c.writeMetadataFile(instance.UUID, backup.Metadata)
 	if err != nil {
 		return err
 	}
-	err = c.tarAndEncryptBackup(instance.UUID)
+	err = c.tarAndEncryptBackup(ctx, instance.UUID)
 	if err != nil {
 		return err
 	}
@@ -220,39 +311,354 @@ func (c *Client) createDirectories() error {
 	return nil
 }
 
-func (c *Client) downloadAndUnpackBackup(ip string) error {
+// A named instance is served below /instances/{name}.
+func (c *Client) instanceURL(instance config.Instance, path string) string {
+	if instance.Name != "" {
+		return fmt.Sprintf("https://%s:%d/instances/%s%s", instance.Address, c.config.BackupServerPort, url.PathEscape(instance.Name), path)
+	}
+	return fmt.Sprintf("https://%s:%d%s", instance.Address, c.config.BackupServerPort, path)
+}
+
+// backupURL is where the backup tool takes backups of the instance.
+func (c *Client) backupURL(instance config.Instance) string {
+	return c.instanceURL(instance, "/backup?format=xbstream")
+}
+
+func (c *Client) downloadAndUnpackBackup(ctx context.Context, instance config.Instance) (backup download.Backup, err error) {
+	ctx, span := c.tracer.Start(ctx, "download")
+	span.SetKind(tracing.KindClient)
+	defer func() { endSpan(span, err) }()
+
 	c.logger.Info("Starting download of backup", lager.Data{
 		"backup-prepare-path": c.prepareDirectory,
 	})
 
-	url := fmt.Sprintf("https://%s:%d/backup?format=xbstream", ip, c.config.BackupServerPort)
-	err := c.downloader.DownloadBackup(url, xbstream.NewUnpacker(c.prepareDirectory))
+	url := c.backupURL(instance)
+	span.SetAttribute("url.full", url)
+	unpacker := &tracedWriter{
+		StreamedWriter: xbstream.NewUnpacker(c.prepareDirectory),
+		ctx:            ctx,
+		tracer:         c.tracer,
+		name:           "unpack xbstream",
+	}
+	backup, err = c.downloader.DownloadBackup(ctx, url, unpacker)
 	if err != nil {
-		c.logger.Error("DownloadBackup failed", err)
-		return err
+		c.logger.Error("DownloadBackup failed", err, lager.Data{
+			"backup_id": backup.ID,
+		})
+		return backup, err
 	}
 
 	c.logger.Info("Finished downloading backup", lager.Data{
 		"backup-prepare-path": c.prepareDirectory,
+		"backup_id":           backup.ID,
+		"metadata":            backup.Metadata,
+	})
+
+	return backup, nil
+}
+
+type tracedWriter struct {
+	download.StreamedWriter
+	ctx    context.Context
+	tracer *tracing.Tracer
+	name   string
+}
+
+func (w *tracedWriter) WriteStream(reader io.Reader) (err error) {
+	_, span := w.tracer.Start(w.ctx, w.name)
+	defer func() { endSpan(span, err) }()
+
+	return w.StreamedWriter.WriteStream(reader)
+}
+
+func endSpan(span *tracing.Span, err error) {
+	span.SetError(err)
+	span.End()
+}
+
+func (c *Client) uploadBackup(ctx context.Context, instance config.Instance) (err error) {
+	ctx, span := c.tracer.Start(ctx, "upload")
+	span.SetKind(tracing.KindClient)
+	defer func() { endSpan(span, err) }()
+
+	url := c.backupURL(instance)
+	upload, err := c.downloader.StartUpload(ctx, url)
+	if err != nil {
+		c.logger.Error("StartUpload failed", err)
+		return err
+	}
+	span.SetAttribute("backup.id", upload.ID)
+	defer func() {
+		c.fetchBackupLog(ctx, instance, upload.ID, err)
+	}()
+
+	upload, err = c.waitForUpload(ctx, instance, upload)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]string{
+		"backup_id":  upload.ID,
+		"start_time": upload.StartedAt.UTC().Format(metadataTimeFormat),
+		"end_time":   upload.FinishedAt.UTC().Format(metadataTimeFormat),
+	}
+	for key, value := range upload.Metadata {
+		fields[key] = value
+	}
+	return c.writeMetadata(instance.UUID, fields)
+}
+
+const (
+	defaultUploadPollInterval = 10 * time.Second
+	// metadataTimeFormat matches the times in xtrabackup_info.
+	metadataTimeFormat = "2006-01-02 15:04:05"
+)
+
+func (c *Client) waitForUpload(ctx context.Context, instance config.Instance, upload download.Upload) (download.Upload, error) {
+	statusURL := c.instanceURL(instance, "/backups/"+upload.ID+"/upload")
+
+	interval := c.config.AsyncUpload.PollInterval
+	if interval <= 0 {
+		interval = defaultUploadPollInterval
+	}
+	var deadline time.Time
+	if timeout := c.config.AsyncUpload.Timeout; timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for upload.Outcome == download.UploadRunning {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return upload, fmt.Errorf("backup %s was not uploaded within %s", upload.ID, c.config.AsyncUpload.Timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return upload, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var err error
+		if upload, err = c.downloader.UploadStatus(ctx, statusURL); err != nil {
+			c.logger.Error("Fetching upload status failed", err, lager.Data{
+				"backup_id": upload.ID,
+			})
+			return upload, err
+		}
+	}
+
+	if upload.Outcome != download.UploadSucceeded {
+		return upload, fmt.Errorf("uploading backup %s failed: %s: %s", upload.ID, upload.Outcome, upload.Error)
+	}
+
+	c.logger.Info("Backup uploaded", lager.Data{
+		"backup_id": upload.ID,
+		"location":  upload.Metadata["location"],
+		"bytes":     upload.Bytes,
 	})
+	return upload, nil
+}
 
+// The log is kept next to the artifact so nobody needs access to the database VM to see it.
+func (c *Client) fetchBackupLog(ctx context.Context, instance config.Instance, backupID string, backupErr error) {
+	ctx, span := c.tracer.Start(ctx, "fetch backup log")
+	span.SetKind(tracing.KindClient)
+	defer span.End()
+
+	url := c.instanceURL(instance, "/backups/"+backupID+"/log")
+
+	var backupLog bytes.Buffer
+	if err := c.downloader.DownloadBackupLog(ctx, url, &backupLog); err != nil {
+		span.SetError(err)
+		c.logger.Error("Fetching backup log failed", err, lager.Data{
+			"backup_id": backupID,
+		})
+		return
+	}
+
+	dst := c.backupLogLocation(instance.UUID)
+	if err := os.WriteFile(dst, backupLog.Bytes(), 0644); err != nil {
+		c.logger.Error("Writing backup log failed", err, lager.Data{
+			"backup_id": backupID,
+		})
+		dst = ""
+	} else {
+		c.logger.Info("Stored backup log", lager.Data{
+			"backup_id": backupID,
+			"path":      dst,
+		})
+	}
+
+	if backupErr != nil {
+		c.logger.Error("Backup failed", backupErr, lager.Data{
+			"backup_id":       backupID,
+			"backup_log":      lastLines(backupLog.String(), backupLogTailLines),
+			"backup_log_path": dst,
+		})
+	}
+}
+
+const backupLogTailLines = 100
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// The bundle is set aside while preparing so that xtrabackup does not take it for a database.
+const bundleDir = "cf-mysql-backup-bundle"
+
+func (c *Client) setAsideBundle() (bool, error) {
+	src := path.Join(c.prepareDirectory, bundleDir)
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err := os.Rename(src, path.Join(c.downloadDirectory, bundleDir)); err != nil {
+		c.logger.Error("Setting aside the bundle of the backup failed", err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Client) restoreBundle() error {
+	err := os.Rename(path.Join(c.downloadDirectory, bundleDir), path.Join(c.prepareDirectory, bundleDir))
+	if err != nil {
+		c.logger.Error("Restoring the bundle of the backup failed", err)
+		return err
+	}
+	c.logger.Info("Kept the bundle of the backup in the artifact", lager.Data{
+		"bundle": bundleDir,
+	})
 	return nil
 }
 
-func (c *Client) prepareBackup() error {
-	backupPrepare := c.backupPreparer.Command(c.prepareDirectory)
+const keyringDir = "keyring"
+
+// The keyring is unsealed outside of the backup; the sealed one ends up in the artifact.
+func (c *Client) unsealKeyring() (prepare.Keyring, error) {
+	var keyring prepare.Keyring
+	dir := path.Join(c.downloadDirectory, keyringDir)
+
+	if key := c.config.Keyring.TransitionKey; key != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return keyring, err
+		}
+		keyring.DefaultsExtraFile = path.Join(dir, "transition-key.cnf")
+		if err := os.WriteFile(keyring.DefaultsExtraFile, prepare.TransitionKeyOptions(key), 0600); err != nil {
+			return keyring, err
+		}
+	}
+
+	var name string
+	for _, n := range []string{"keyring_file", "component_keyring_file"} {
+		if _, err := os.Stat(path.Join(c.downloadDirectory, bundleDir, keyringDir, n+".sealed")); err == nil {
+			name = n
+		}
+	}
+	if name == "" {
+		return keyring, nil
+	}
+	if c.config.Keyring.EncryptionKey == "" {
+		err := errors.New("the backup has a sealed keyring, but no Keyring.EncryptionKey is configured to unseal it with")
+		c.logger.Error("Unsealing the keyring of the backup failed", err)
+		return keyring, err
+	}
+
+	member := path.Join(bundleDir, keyringDir, name+".sealed")
+	sealed, err := os.ReadFile(path.Join(c.downloadDirectory, member))
+	if err != nil {
+		c.logger.Error("Unsealing the keyring of the backup failed", err)
+		return keyring, err
+	}
+	content, err := unseal(c.config.Keyring.EncryptionKey, sealed, member)
+	if err != nil {
+		c.logger.Error("Unsealing the keyring of the backup failed", err)
+		return keyring, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return keyring, err
+	}
+	file := path.Join(dir, name)
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return keyring, err
+	}
+
+	if name == "keyring_file" {
+		keyring.FileData = file
+	} else {
+		componentConfig, err := json.Marshal(map[string]any{"path": file, "read_only": true})
+		if err != nil {
+			return keyring, err
+		}
+		keyring.ComponentConfig = path.Join(dir, name+".cnf")
+		if err := os.WriteFile(keyring.ComponentConfig, componentConfig, 0600); err != nil {
+			return keyring, err
+		}
+	}
+	c.logger.Info("Unsealed the keyring of the backup", lager.Data{
+		"keyring": name,
+	})
+	return keyring, nil
+}
+
+func (c *Client) removeUnsealedKeyring() {
+	if err := os.RemoveAll(path.Join(c.downloadDirectory, keyringDir)); err != nil {
+		c.logger.Error("Failed to remove the unsealed keyring", err)
+	}
+}
+
+// unseal opens a keyring sealed by the backup tool with AES-256-GCM under the SHA-256 of key.
+func unseal(key string, sealed []byte, name string) ([]byte, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("the sealed keyring is truncated")
+	}
+	content, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("unsealing the keyring failed, check that Keyring.EncryptionKey matches the backup tool: %w", err)
+	}
+	return content, nil
+}
+
+func (c *Client) prepareBackup(ctx context.Context, keyring prepare.Keyring) (err error) {
+	_, span := c.tracer.Start(ctx, "prepare")
+	defer func() { endSpan(span, err) }()
+
+	backupPrepare := c.backupPreparer.Command(c.prepareDirectory, keyring)
 	c.logger.Debug("Backup prepare command", lager.Data{
-		"command": backupPrepare,
-		"args":    backupPrepare.Args,
+		"command": backupPrepare.Path,
 	})
 
 	c.logger.Info("Starting prepare of backup", lager.Data{
 		"prepareDirectory": c.prepareDirectory,
 	})
-	output, err := backupPrepare.CombinedOutput()
+
+	parser := xtrabackuplog.NewParser(func(e xtrabackuplog.Event) {
+		e.LogTo(c.logger, "xtrabackup-prepare")
+	})
+	var output bytes.Buffer
+	backupPrepare.Stdout = io.MultiWriter(&output, parser)
+	backupPrepare.Stderr = backupPrepare.Stdout
+	err = backupPrepare.Run()
+	parser.Flush()
 	if err != nil {
+		if failure := parser.Failure(); failure != nil {
+			err = fmt.Errorf("%w (%v)", failure, err)
+		}
 		c.logger.Error("Preparing the backup failed", err, lager.Data{
-			"output": output,
+			"output": output.Bytes(),
 		})
 		return err
 	}
@@ -271,7 +677,7 @@ func (c *Client) prepareBackup() error {
 // this concrete file dependency
 //
 // See: https://www.pivotaltracker.com/story/show/98994636
-func (c *Client) writeMetadataFile(uuid string) error {
+func (c *Client) writeMetadataFile(uuid string, backupMetadata map[string]string) error {
 	src := c.originalMetadataLocation()
 	dst := c.finalMetadataLocation(uuid)
 
@@ -280,9 +686,13 @@ func (c *Client) writeMetadataFile(uuid string) error {
 		"to":   dst,
 	})
 
-	_, err := os.Create(dst)
-	if err != nil {
-		return err
+	// A clone has no xtrabackup_info.
+	if backupMetadata["backup_engine"] == prepare.Clone {
+		fields := map[string]string{}
+		for key, value := range backupMetadata {
+			fields[key] = value
+		}
+		return c.writeMetadata(uuid, fields)
 	}
 
 	backupMetadataMap, err := fileutils.ExtractFileFields(src)
@@ -291,11 +701,42 @@ func (c *Client) writeMetadataFile(uuid string) error {
 		return err
 	}
 
-	for key, value := range c.metadataFields {
+	backupMetadataMap["backup_engine"] = prepare.Engine(c.prepareDirectory)
+	for key, value := range c.backupInfo().Metadata() {
+		backupMetadataMap[key] = value
+	}
+	for key, value := range backupMetadata {
 		backupMetadataMap[key] = value
 	}
 
-	for key, value := range backupMetadataMap {
+	return c.writeMetadata(uuid, backupMetadataMap)
+}
+
+func (c *Client) backupInfo() xtrabackuplog.BackupInfo {
+	var info xtrabackuplog.BackupInfo
+	for _, name := range []string{xtrabackuplog.BinlogInfoFile, xtrabackuplog.GaleraInfoFile, xtrabackuplog.CheckpointsFile} {
+		content, err := os.ReadFile(path.Join(c.prepareDirectory, name))
+		if err != nil {
+			continue
+		}
+		info.Parse(name, content)
+	}
+	return info
+}
+
+func (c *Client) writeMetadata(uuid string, fields map[string]string) error {
+	dst := c.finalMetadataLocation(uuid)
+
+	_, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range c.metadataFields {
+		fields[key] = value
+	}
+
+	for key, value := range fields {
 		keyValLine := fmt.Sprintf("%s = %s", key, value)
 		err = fileutils.WriteLineToFile(dst, keyValLine)
 		if err != nil {
@@ -309,7 +750,10 @@ func (c *Client) writeMetadataFile(uuid string) error {
 	return nil
 }
 
-func (c *Client) tarAndEncryptBackup(uuid string) error {
+func (c *Client) tarAndEncryptBackup(ctx context.Context, uuid string) (err error) {
+	_, span := c.tracer.Start(ctx, "tar and encrypt")
+	defer func() { endSpan(span, err) }()
+
 	c.logger.Info("Starting encrypting backup")
 
 	tarCmd := c.tarClient.Tar(c.prepareDirectory)
@@ -1,15 +1,21 @@
 package client_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/cloudfoundry/tracing"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
@@ -17,7 +23,9 @@ import (
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client/clientfakes"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/config"
+	"github.com/cloudfoundry/streaming-mysql-backup-client/cryptkeeper"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/download"
+	"github.com/cloudfoundry/streaming-mysql-backup-client/prepare"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/tarpit"
 )
 
@@ -65,12 +73,16 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 
 		fakeDownloader = &clientfakes.FakeDownloader{}
 
-		fakeDownloader.DownloadBackupStub = func(url string, streamedWriter download.StreamedWriter) error {
+		fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
 			file, err := os.Open("fixtures/xbstream.xb")
 			Expect(err).ToNot(HaveOccurred())
 			defer file.Close()
 
-			return streamedWriter.WriteStream(file)
+			return download.Backup{ID: "some-backup-id"}, streamedWriter.WriteStream(file)
+		}
+		fakeDownloader.DownloadBackupLogStub = func(_ context.Context, url string, w io.Writer) error {
+			_, err := io.WriteString(w, "xtrabackup output\ncompleted OK!\n")
+			return err
 		}
 
 		fakeGaleraAgent = &clientfakes.FakeGaleraAgentCallerInterface{}
@@ -114,6 +126,7 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 		Expect(backupMetadataStr).To(ContainSubstring("end_time ="))
 		Expect(backupMetadataStr).To(ContainSubstring("compressed ="))
 		Expect(backupMetadataStr).To(ContainSubstring("encrypted ="))
+		Expect(backupMetadataStr).To(MatchRegexp(`(?m)^backup_engine = xtrabackup$`))
 	})
 
 	It("Sets values for keys in metadata file based on MetadataFields", func() {
@@ -129,6 +142,488 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 		}
 	})
 
+	It("Records the metadata reported by the backup tool in the metadata file", func() {
+		downloadStub := fakeDownloader.DownloadBackupStub
+		fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+			backup, err := downloadStub(ctx, url, streamedWriter)
+			backup.Metadata = map[string]string{
+				"replica_binlog_file":     "mysql-bin.000003",
+				"replica_binlog_position": "157",
+			}
+			return backup, err
+		}
+
+		Expect(backupClient.Execute()).To(Succeed())
+		files, _ := filepath.Glob(outputDirectory + "/" + backupMetadataGlob)
+		Expect(files).To(HaveLen(1))
+		data, err := ioutil.ReadFile(files[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(data)).To(ContainSubstring("replica_binlog_file = mysql-bin.000003"))
+		Expect(string(data)).To(ContainSubstring("replica_binlog_position = 157"))
+	})
+
+	It("Records the binlog position, Galera state and LSN range of the backup in the metadata file", func() {
+		downloadStub := fakeDownloader.DownloadBackupStub
+		fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+			backup, err := downloadStub(ctx, url, streamedWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := os.Open("fixtures/backup-info.xb")
+			Expect(err).ToNot(HaveOccurred())
+			defer info.Close()
+			return backup, streamedWriter.WriteStream(info)
+		}
+
+		Expect(backupClient.Execute()).To(Succeed())
+		files, _ := filepath.Glob(outputDirectory + "/" + backupMetadataGlob)
+		Expect(files).To(HaveLen(1))
+		data, err := ioutil.ReadFile(files[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(data)).To(ContainSubstring("binlog_file = mysql-bin.000003\n"))
+		Expect(string(data)).To(ContainSubstring("binlog_position = 157\n"))
+		Expect(string(data)).To(ContainSubstring("gtid_executed = 3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5\n"))
+		Expect(string(data)).To(ContainSubstring("galera_position = 8f3e6cba-6c79-11ee-8c99-0242ac120002:42\n"))
+		Expect(string(data)).To(ContainSubstring("from_lsn = 0\n"))
+		Expect(string(data)).To(ContainSubstring("to_lsn = 1669231\n"))
+	})
+
+	It("Does not prepare backups taken with the CLONE plugin", func() {
+		fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+			return download.Backup{
+				ID: "some-backup-id",
+				Metadata: map[string]string{
+					"backup_engine":         "clone",
+					"backup_engine_version": "8.0.35",
+				},
+			}, nil
+		}
+
+		Expect(backupClient.Execute()).To(Succeed())
+		Expect(fakeBackupPreparer.CommandCallCount()).To(BeZero())
+
+		expectFileToExist(filepath.Join(outputDirectory, backupFileGlob))
+		files, _ := filepath.Glob(outputDirectory + "/" + backupMetadataGlob)
+		Expect(files).To(HaveLen(1))
+		data, err := ioutil.ReadFile(files[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("backup_engine = clone"))
+		Expect(string(data)).To(ContainSubstring("backup_engine_version = 8.0.35"))
+	})
+
+	It("Keeps the bundle of configuration and cluster state out of the prepare but in the artifact", func() {
+		downloadStub := fakeDownloader.DownloadBackupStub
+		fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+			backup, err := downloadStub(ctx, url, streamedWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			bundle, err := os.Open("fixtures/bundle.xb")
+			Expect(err).ToNot(HaveOccurred())
+			defer bundle.Close()
+			return backup, streamedWriter.WriteStream(bundle)
+		}
+
+		fakeBackupPreparer.CommandStub = func(backupDir string, _ prepare.Keyring) *exec.Cmd {
+			return exec.Command("test", "!", "-e", filepath.Join(backupDir, "cf-mysql-backup-bundle"))
+		}
+
+		Expect(backupClient.Execute()).To(Succeed())
+
+		artifacts, _ := filepath.Glob(filepath.Join(outputDirectory, backupFileGlob))
+		Expect(artifacts).To(HaveLen(1))
+		encrypted, err := os.Open(artifacts[0])
+		Expect(err).ToNot(HaveOccurred())
+		defer encrypted.Close()
+		var artifact bytes.Buffer
+		Expect(cryptkeeper.NewCryptKeeper("hello").Decrypt(encrypted, &artifact)).To(Succeed())
+
+		untar := exec.Command(tarClient.TarCommand, "tf", "-")
+		untar.Stdin = &artifact
+		listing, err := untar.Output()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(listing)).To(ContainSubstring("./xtrabackup_info\n"))
+		Expect(string(listing)).To(ContainSubstring("./cf-mysql-backup-bundle/files/var/vcap/store/pxc-mysql/grastate.dat\n"))
+		Expect(string(listing)).To(ContainSubstring("./cf-mysql-backup-bundle/snapshots/server_version.txt\n"))
+
+		files, _ := filepath.Glob(outputDirectory + "/" + backupMetadataGlob)
+		Expect(files).To(HaveLen(1))
+		data, err := ioutil.ReadFile(files[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("bundle = cf-mysql-backup-bundle"))
+	})
+
+	When("the backup has a sealed keyring", func() {
+		BeforeEach(func() {
+			downloadStub := fakeDownloader.DownloadBackupStub
+			fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+				backup, err := downloadStub(ctx, url, streamedWriter)
+				Expect(err).NotTo(HaveOccurred())
+
+				keyring, err := os.Open("fixtures/keyring.xb")
+				Expect(err).ToNot(HaveOccurred())
+				defer keyring.Close()
+				return backup, streamedWriter.WriteStream(keyring)
+			}
+			rootConfig.Keyring = config.Keyring{EncryptionKey: "some-keyring-key"}
+		})
+
+		It("prepares the backup with the unsealed keyring and keeps only the sealed one", func() {
+			var componentConfig, keyringFile string
+			fakeBackupPreparer.CommandStub = func(backupDir string, keyring prepare.Keyring) *exec.Cmd {
+				componentConfig = keyring.ComponentConfig
+				config, err := os.ReadFile(keyring.ComponentConfig)
+				Expect(err).NotTo(HaveOccurred())
+				var component struct {
+					Path     string `json:"path"`
+					ReadOnly bool   `json:"read_only"`
+				}
+				Expect(json.Unmarshal(config, &component)).To(Succeed())
+				Expect(component.ReadOnly).To(BeTrue())
+				keyringFile = component.Path
+				Expect(os.ReadFile(keyringFile)).To(Equal([]byte("some-keyring")))
+				Expect(filepath.Dir(keyringFile)).NotTo(HavePrefix(backupDir))
+				return exec.Command("true")
+			}
+
+			Expect(backupClient.Execute()).To(Succeed())
+
+			Expect(fakeBackupPreparer.CommandCallCount()).To(Equal(1))
+			Expect(componentConfig).NotTo(BeAnExistingFile())
+			Expect(keyringFile).NotTo(BeAnExistingFile())
+
+			artifacts, _ := filepath.Glob(filepath.Join(outputDirectory, backupFileGlob))
+			Expect(artifacts).To(HaveLen(1))
+			encrypted, err := os.Open(artifacts[0])
+			Expect(err).ToNot(HaveOccurred())
+			defer encrypted.Close()
+			var artifact bytes.Buffer
+			Expect(cryptkeeper.NewCryptKeeper("hello").Decrypt(encrypted, &artifact)).To(Succeed())
+
+			untar := exec.Command(tarClient.TarCommand, "tf", "-")
+			untar.Stdin = &artifact
+			listing, err := untar.Output()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(listing)).To(ContainSubstring("./cf-mysql-backup-bundle/keyring/component_keyring_file.sealed\n"))
+			Expect(string(listing)).NotTo(ContainSubstring("./cf-mysql-backup-bundle/keyring/component_keyring_file\n"))
+		})
+
+		When("a TransitionKey is configured", func() {
+			BeforeEach(func() {
+				rootConfig.Keyring.TransitionKey = "some-transition-key"
+			})
+
+			It("passes it along in an option file only the client can read", func() {
+				var options []byte
+				var mode os.FileMode
+				fakeBackupPreparer.CommandStub = func(backupDir string, keyring prepare.Keyring) *exec.Cmd {
+					info, err := os.Stat(keyring.DefaultsExtraFile)
+					Expect(err).NotTo(HaveOccurred())
+					mode = info.Mode().Perm()
+					options, err = os.ReadFile(keyring.DefaultsExtraFile)
+					Expect(err).NotTo(HaveOccurred())
+					return exec.Command("true")
+				}
+
+				Expect(backupClient.Execute()).To(Succeed())
+
+				Expect(string(options)).To(Equal("[xtrabackup]\ntransition-key=\"some-transition-key\"\n"))
+				Expect(mode).To(Equal(os.FileMode(0600)))
+				_, keyring := fakeBackupPreparer.CommandArgsForCall(0)
+				Expect(keyring.DefaultsExtraFile).NotTo(BeAnExistingFile())
+				Expect(string(logger.Buffer().Contents())).NotTo(ContainSubstring("some-transition-key"))
+			})
+		})
+
+		When("no EncryptionKey is configured", func() {
+			BeforeEach(func() {
+				rootConfig.Keyring = config.Keyring{}
+			})
+
+			It("fails without preparing the backup", func() {
+				Expect(backupClient.Execute()).To(MatchError(ContainSubstring("the backup has a sealed keyring, but no Keyring.EncryptionKey is configured to unseal it with")))
+				Expect(fakeBackupPreparer.CommandCallCount()).To(BeZero())
+			})
+		})
+
+		When("the EncryptionKey does not unseal the keyring", func() {
+			BeforeEach(func() {
+				rootConfig.Keyring = config.Keyring{EncryptionKey: "some-other-key"}
+			})
+
+			It("fails without preparing the backup", func() {
+				Expect(backupClient.Execute()).To(MatchError(ContainSubstring("unsealing the keyring failed")))
+				Expect(fakeBackupPreparer.CommandCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	It("Takes the backup of the instance served by /backup", func() {
+		Expect(backupClient.Execute()).To(Succeed())
+
+		_, url, _ := fakeDownloader.DownloadBackupArgsForCall(0)
+		Expect(url).To(Equal("https://node1:1234/backup?format=xbstream"))
+	})
+
+	When("the instance names an instance of the backup tool", func() {
+		BeforeEach(func() {
+			rootConfig.Instances[0].Name = "mysql-2"
+		})
+
+		It("Takes the backup of the named instance", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			_, url, _ := fakeDownloader.DownloadBackupArgsForCall(0)
+			Expect(url).To(Equal("https://node1:1234/instances/mysql-2/backup?format=xbstream"))
+		})
+
+		It("Fetches the log of the backup from the named instance", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			_, url, _ := fakeDownloader.DownloadBackupLogArgsForCall(0)
+			Expect(url).To(Equal("https://node1:1234/instances/mysql-2/backups/some-backup-id/log"))
+		})
+
+		It("Authenticates with the credentials of the configuration", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			ctx, _, _ := fakeDownloader.DownloadBackupArgsForCall(0)
+			_, ok := download.CredentialsFrom(ctx)
+			Expect(ok).To(BeFalse())
+		})
+
+		When("the instance has credentials of its own", func() {
+			BeforeEach(func() {
+				rootConfig.Instances[0].Credentials = &config.Credentials{Username: "mysql-2-username", Password: "mysql-2-password"}
+			})
+
+			It("Authenticates every request with them", func() {
+				Expect(backupClient.Execute()).To(Succeed())
+
+				expected := config.Credentials{Username: "mysql-2-username", Password: "mysql-2-password"}
+				ctx, _, _ := fakeDownloader.DownloadBackupArgsForCall(0)
+				credentials, ok := download.CredentialsFrom(ctx)
+				Expect(ok).To(BeTrue())
+				Expect(credentials).To(Equal(expected))
+				ctx, _, _ = fakeDownloader.DownloadBackupLogArgsForCall(0)
+				credentials, ok = download.CredentialsFrom(ctx)
+				Expect(ok).To(BeTrue())
+				Expect(credentials).To(Equal(expected))
+			})
+		})
+	})
+
+	It("Stores the log of the backup next to the artifact", func() {
+		Expect(backupClient.Execute()).To(Succeed())
+
+		Expect(fakeDownloader.DownloadBackupLogCallCount()).To(Equal(1))
+		_, url, _ := fakeDownloader.DownloadBackupLogArgsForCall(0)
+		Expect(url).To(Equal("https://node1:1234/backups/some-backup-id/log"))
+
+		files, err := filepath.Glob(filepath.Join(outputDirectory, "mysql-backup-*-uuid1.log"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(os.ReadFile(files[0])).To(BeEquivalentTo("xtrabackup output\ncompleted OK!\n"))
+	})
+
+	When("the backup fails", func() {
+		BeforeEach(func() {
+			fakeBackupPreparer.CommandReturns(exec.Command("false"))
+		})
+
+		It("includes the log of the backup in the error report", func() {
+			Expect(backupClient.Execute()).NotTo(Succeed())
+
+			Expect(logger.TestSink.Logs()).To(ContainElement(
+				MatchFields(IgnoreExtras, Fields{
+					"Message":  ContainSubstring("Backup failed"),
+					"LogLevel": Equal(lager.ERROR),
+					"Data":     HaveKeyWithValue("backup_log", "xtrabackup output\ncompleted OK!"),
+				}),
+			))
+		})
+
+		It("still stores the log of the backup in the output directory", func() {
+			Expect(backupClient.Execute()).NotTo(Succeed())
+
+			files, err := filepath.Glob(filepath.Join(outputDirectory, "mysql-backup-*-uuid1.log"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(os.ReadFile(files[0])).To(BeEquivalentTo("xtrabackup output\ncompleted OK!\n"))
+		})
+	})
+
+	When("the backup tool did not identify the backup", func() {
+		BeforeEach(func() {
+			fakeDownloader.DownloadBackupStub = func(ctx context.Context, url string, streamedWriter download.StreamedWriter) (download.Backup, error) {
+				file, err := os.Open("fixtures/xbstream.xb")
+				Expect(err).ToNot(HaveOccurred())
+				defer file.Close()
+
+				return download.Backup{}, streamedWriter.WriteStream(file)
+			}
+		})
+
+		It("does not try to fetch a backup log", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+			Expect(fakeDownloader.DownloadBackupLogCallCount()).To(Equal(0))
+		})
+	})
+
+	When("the backup tool uploads backups to object storage", func() {
+		BeforeEach(func() {
+			rootConfig.AsyncUpload = config.AsyncUpload{
+				Enabled:      true,
+				PollInterval: time.Millisecond,
+			}
+			fakeDownloader.StartUploadReturns(download.Upload{ID: "some-backup-id", Outcome: download.UploadRunning}, nil)
+			fakeDownloader.UploadStatusReturnsOnCall(0, download.Upload{ID: "some-backup-id", Outcome: download.UploadRunning}, nil)
+			fakeDownloader.UploadStatusReturnsOnCall(1, download.Upload{
+				ID:         "some-backup-id",
+				Outcome:    download.UploadSucceeded,
+				StartedAt:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+				FinishedAt: time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC),
+				Metadata:   map[string]string{"location": "s3://backups/some-backup-id"},
+			}, nil)
+		})
+
+		It("waits for the upload and only writes the metadata file", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			Expect(fakeDownloader.DownloadBackupCallCount()).To(BeZero())
+			Expect(fakeBackupPreparer.CommandCallCount()).To(BeZero())
+			_, url := fakeDownloader.StartUploadArgsForCall(0)
+			Expect(url).To(Equal("https://node1:1234/backup?format=xbstream"))
+			Expect(fakeDownloader.UploadStatusCallCount()).To(Equal(2))
+			_, url = fakeDownloader.UploadStatusArgsForCall(0)
+			Expect(url).To(Equal("https://node1:1234/backups/some-backup-id/upload"))
+
+			expectFileToNotExist(filepath.Join(outputDirectory, backupFileGlob))
+			files, _ := filepath.Glob(filepath.Join(outputDirectory, backupMetadataGlob))
+			Expect(files).To(HaveLen(1))
+			data, err := ioutil.ReadFile(files[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("backup_id = some-backup-id"))
+			Expect(string(data)).To(ContainSubstring("location = s3://backups/some-backup-id"))
+			Expect(string(data)).To(ContainSubstring("start_time = 2024-05-01 10:00:00"))
+			Expect(string(data)).To(ContainSubstring("end_time = 2024-05-01 11:00:00"))
+			Expect(string(data)).To(ContainSubstring("compressed = Y"))
+		})
+
+		When("the instance names an instance of the backup tool", func() {
+			BeforeEach(func() {
+				rootConfig.Instances[0].Name = "mysql-2"
+			})
+
+			It("polls the upload below the named instance", func() {
+				Expect(backupClient.Execute()).To(Succeed())
+
+				_, url := fakeDownloader.UploadStatusArgsForCall(0)
+				Expect(url).To(Equal("https://node1:1234/instances/mysql-2/backups/some-backup-id/upload"))
+			})
+		})
+
+		It("fails when the upload fails", func() {
+			fakeDownloader.UploadStatusReturnsOnCall(1, download.Upload{
+				ID:      "some-backup-id",
+				Outcome: "FAILED",
+				Error:   "storing object mysql/some-backup-id/00000001 failed: 403 Forbidden",
+			}, nil)
+
+			err := backupClient.Execute()
+			Expect(err).To(MatchError(ContainSubstring("uploading backup some-backup-id failed: FAILED: storing object mysql/some-backup-id/00000001 failed: 403 Forbidden")))
+			expectFileToNotExist(filepath.Join(outputDirectory, backupMetadataGlob))
+			Expect(fakeDownloader.DownloadBackupLogCallCount()).To(Equal(1))
+		})
+
+		When("the backup is cancelled while waiting for the upload", func() {
+			BeforeEach(func() {
+				rootConfig.AsyncUpload.PollInterval = time.Hour
+			})
+
+			It("stops polling", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+
+				err := backupClient.BackupNode(ctx, rootConfig.Instances[0])
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(fakeDownloader.UploadStatusCallCount()).To(BeZero())
+			})
+		})
+
+		When("the upload takes longer than the Timeout", func() {
+			BeforeEach(func() {
+				rootConfig.AsyncUpload.Timeout = time.Nanosecond
+				fakeDownloader.UploadStatusReturnsOnCall(1, download.Upload{ID: "some-backup-id", Outcome: download.UploadRunning}, nil)
+			})
+
+			It("gives up", func() {
+				err := backupClient.Execute()
+				Expect(err).To(MatchError(ContainSubstring("backup some-backup-id was not uploaded within 1ns")))
+			})
+		})
+	})
+
+	When("the backup log cannot be fetched", func() {
+		BeforeEach(func() {
+			fakeDownloader.DownloadBackupLogReturns(errors.New("404 Not Found"))
+			fakeDownloader.DownloadBackupLogStub = nil
+		})
+
+		It("still succeeds", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+			expectFileToExist(filepath.Join(outputDirectory, backupFileGlob))
+			expectFileToNotExist(filepath.Join(outputDirectory, "mysql-backup-*.log"))
+		})
+	})
+
+	When("tracing is enabled", func() {
+		var spans *spanRecorder
+
+		BeforeEach(func() {
+			spans = &spanRecorder{}
+			rootConfig.Tracer = &tracing.Tracer{Service: "streaming-mysql-backup-client", Exporter: spans}
+		})
+
+		It("records a span for each phase of the backup in one trace", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			Expect(spans.names()).To(Equal([]string{
+				"select node",
+				"unpack xbstream",
+				"download",
+				"prepare",
+				"tar and encrypt",
+				"fetch backup log",
+				"backup node",
+				"backup",
+			}))
+			root := spans.named("backup")
+			for _, span := range spans.spans {
+				Expect(span.SpanContext.TraceID).To(Equal(root.SpanContext.TraceID))
+			}
+			Expect(spans.named("download").Kind).To(Equal(tracing.KindClient))
+			Expect(spans.named("unpack xbstream").Parent).To(Equal(spans.named("download").SpanContext.SpanID))
+			Expect(spans.named("backup node").Attributes).To(HaveKeyWithValue("backup.id", "some-backup-id"))
+		})
+
+		It("has the backup tool continue the trace", func() {
+			Expect(backupClient.Execute()).To(Succeed())
+
+			ctx, _, _ := fakeDownloader.DownloadBackupArgsForCall(0)
+			Expect(tracing.SpanContextFromContext(ctx).SpanID).To(Equal(spans.named("download").SpanContext.SpanID))
+		})
+
+		It("marks the phase that failed", func() {
+			fakeBackupPreparer.CommandReturns(exec.Command("false"))
+
+			Expect(backupClient.Execute()).NotTo(Succeed())
+
+			Expect(spans.named("prepare").Error).To(ContainSubstring("exit status 1"))
+			Expect(spans.named("backup").Error).To(ContainSubstring("exit status 1"))
+		})
+	})
+
 	Context("When there are multiple URLs", func() {
 		BeforeEach(func() {
 			rootConfig.Instances = []config.Instance{
@@ -231,6 +726,24 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 				fakeGaleraAgent.WsrepLocalIndexReturnsOnCall(2, 2, nil)
 			})
 
+			It("Traces the calls to galera-agent", func() {
+				spans := &spanRecorder{}
+				rootConfig.Tracer = &tracing.Tracer{Service: "streaming-mysql-backup-client", Exporter: spans}
+				backupClient = client.NewClient(*rootConfig, tarClient, fakeBackupPreparer, fakeDownloader, fakeGaleraAgent)
+
+				Expect(backupClient.Execute()).To(Succeed())
+
+				Expect(fakeGaleraAgent.WsrepLocalIndexCallCount()).To(Equal(3))
+				selection := spans.named("select node")
+				Expect(selection.Attributes).To(HaveKeyWithValue("instance.address", "node2"))
+				for i := 0; i < 3; i++ {
+					ctx, _ := fakeGaleraAgent.WsrepLocalIndexArgsForCall(i)
+					Expect(tracing.SpanContextFromContext(ctx).TraceID).To(Equal(selection.SpanContext.TraceID))
+				}
+				Expect(spans.names()).To(HaveLen(len(spans.spans)))
+				Expect(spans.names()[:4]).To(Equal([]string{"galera-agent status", "galera-agent status", "galera-agent status", "select node"}))
+			})
+
 			Context("When successful", func() {
 				It("Creates a backup only for the inactive node", func() {
 					fakeBackupPreparer.CommandReturnsOnCall(0, exec.Command("true"))
@@ -245,7 +758,7 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(matches).To(HaveLen(1))
 
-					Expect(fakeDownloader.Invocations()["DownloadBackup"][0][0]).To(Equal("https://node2:1234/backup?format=xbstream"))
+					Expect(fakeDownloader.Invocations()["DownloadBackup"][0][1]).To(Equal("https://node2:1234/backup?format=xbstream"))
 				})
 			})
 
@@ -301,7 +814,7 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(matches).To(HaveLen(1))
 
-				Expect(fakeDownloader.Invocations()["DownloadBackup"][0][0]).To(Equal("https://node3:1234/backup?format=xbstream"))
+				Expect(fakeDownloader.Invocations()["DownloadBackup"][0][1]).To(Equal("https://node3:1234/backup?format=xbstream"))
 			})
 		})
 
@@ -333,11 +846,60 @@ var _ = Describe("Streaming MySQL Backup Client", func() {
 					}),
 				))
 			})
+
+			Context("When xtrabackup reports why the prepare failed", func() {
+				BeforeEach(func() {
+					fakeBackupPreparer.CommandReturns(exec.Command("sh", "-c", "echo 'xtrabackup: error: log block numbers mismatch:' >&2; exit 1"))
+				})
+
+				It("classifies the failure", func() {
+					err := backupClient.Execute()
+					Expect(err).To(MatchError(ContainSubstring("REDO_LOG_OVERRUN: error: log block numbers mismatch: (exit status 1)")))
+				})
+
+				It("logs the xtrabackup error", func() {
+					_ = backupClient.Execute()
+
+					Expect(logger.TestSink.Logs()).To(ContainElement(
+						MatchFields(IgnoreExtras, Fields{
+							"Message":  ContainSubstring("xtrabackup-prepare"),
+							"LogLevel": Equal(lager.ERROR),
+							"Data":     HaveKeyWithValue("type", "redo-log-overrun"),
+						}),
+					))
+				})
+			})
 		})
 	})
 
 })
 
+type spanRecorder struct {
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(span tracing.SpanData) { r.spans = append(r.spans, span) }
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func (r *spanRecorder) names() []string {
+	var names []string
+	for _, span := range r.spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func (r *spanRecorder) named(name string) tracing.SpanData {
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	Fail("no span named " + name)
+	return tracing.SpanData{}
+}
+
 func expectFileToNotExist(glob string) {
 	matches, err := filepath.Glob(glob)
 	Expect(err).ToNot(HaveOccurred())
@@ -6,13 +6,15 @@ import (
 	"sync"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client"
+	"github.com/cloudfoundry/streaming-mysql-backup-client/prepare"
 )
 
 type FakeBackupPreparer struct {
-	CommandStub        func(string) *exec.Cmd
+	CommandStub        func(string, prepare.Keyring) *exec.Cmd
 	commandMutex       sync.RWMutex
 	commandArgsForCall []struct {
 		arg1 string
+		arg2 prepare.Keyring
 	}
 	commandReturns struct {
 		result1 *exec.Cmd
@@ -24,18 +26,19 @@ type FakeBackupPreparer struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeBackupPreparer) Command(arg1 string) *exec.Cmd {
+func (fake *FakeBackupPreparer) Command(arg1 string, arg2 prepare.Keyring) *exec.Cmd {
 	fake.commandMutex.Lock()
 	ret, specificReturn := fake.commandReturnsOnCall[len(fake.commandArgsForCall)]
 	fake.commandArgsForCall = append(fake.commandArgsForCall, struct {
 		arg1 string
-	}{arg1})
+		arg2 prepare.Keyring
+	}{arg1, arg2})
 	stub := fake.CommandStub
 	fakeReturns := fake.commandReturns
-	fake.recordInvocation("Command", []interface{}{arg1})
+	fake.recordInvocation("Command", []interface{}{arg1, arg2})
 	fake.commandMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -49,17 +52,17 @@ func (fake *FakeBackupPreparer) CommandCallCount() int {
 	return len(fake.commandArgsForCall)
 }
 
-func (fake *FakeBackupPreparer) CommandCalls(stub func(string) *exec.Cmd) {
+func (fake *FakeBackupPreparer) CommandCalls(stub func(string, prepare.Keyring) *exec.Cmd) {
 	fake.commandMutex.Lock()
 	defer fake.commandMutex.Unlock()
 	fake.CommandStub = stub
 }
 
-func (fake *FakeBackupPreparer) CommandArgsForCall(i int) string {
+func (fake *FakeBackupPreparer) CommandArgsForCall(i int) (string, prepare.Keyring) {
 	fake.commandMutex.RLock()
 	defer fake.commandMutex.RUnlock()
 	argsForCall := fake.commandArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeBackupPreparer) CommandReturns(result1 *exec.Cmd) {
@@ -2,6 +2,8 @@
 package clientfakes
 
 import (
+	"context"
+	"io"
 	"sync"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client"
@@ -9,40 +11,85 @@ import (
 )
 
 type FakeDownloader struct {
-	DownloadBackupStub        func(string, download.StreamedWriter) error
+	DownloadBackupStub        func(context.Context, string, download.StreamedWriter) (download.Backup, error)
 	downloadBackupMutex       sync.RWMutex
 	downloadBackupArgsForCall []struct {
-		arg1 string
-		arg2 download.StreamedWriter
+		arg1 context.Context
+		arg2 string
+		arg3 download.StreamedWriter
 	}
 	downloadBackupReturns struct {
-		result1 error
+		result1 download.Backup
+		result2 error
 	}
 	downloadBackupReturnsOnCall map[int]struct {
+		result1 download.Backup
+		result2 error
+	}
+	DownloadBackupLogStub        func(context.Context, string, io.Writer) error
+	downloadBackupLogMutex       sync.RWMutex
+	downloadBackupLogArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 io.Writer
+	}
+	downloadBackupLogReturns struct {
 		result1 error
 	}
+	downloadBackupLogReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StartUploadStub        func(context.Context, string) (download.Upload, error)
+	startUploadMutex       sync.RWMutex
+	startUploadArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	startUploadReturns struct {
+		result1 download.Upload
+		result2 error
+	}
+	startUploadReturnsOnCall map[int]struct {
+		result1 download.Upload
+		result2 error
+	}
+	UploadStatusStub        func(context.Context, string) (download.Upload, error)
+	uploadStatusMutex       sync.RWMutex
+	uploadStatusArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	uploadStatusReturns struct {
+		result1 download.Upload
+		result2 error
+	}
+	uploadStatusReturnsOnCall map[int]struct {
+		result1 download.Upload
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeDownloader) DownloadBackup(arg1 string, arg2 download.StreamedWriter) error {
+func (fake *FakeDownloader) DownloadBackup(arg1 context.Context, arg2 string, arg3 download.StreamedWriter) (download.Backup, error) {
 	fake.downloadBackupMutex.Lock()
 	ret, specificReturn := fake.downloadBackupReturnsOnCall[len(fake.downloadBackupArgsForCall)]
 	fake.downloadBackupArgsForCall = append(fake.downloadBackupArgsForCall, struct {
-		arg1 string
-		arg2 download.StreamedWriter
-	}{arg1, arg2})
+		arg1 context.Context
+		arg2 string
+		arg3 download.StreamedWriter
+	}{arg1, arg2, arg3})
 	stub := fake.DownloadBackupStub
 	fakeReturns := fake.downloadBackupReturns
-	fake.recordInvocation("DownloadBackup", []interface{}{arg1, arg2})
+	fake.recordInvocation("DownloadBackup", []interface{}{arg1, arg2, arg3})
 	fake.downloadBackupMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeDownloader) DownloadBackupCallCount() int {
@@ -51,47 +98,249 @@ func (fake *FakeDownloader) DownloadBackupCallCount() int {
 	return len(fake.downloadBackupArgsForCall)
 }
 
-func (fake *FakeDownloader) DownloadBackupCalls(stub func(string, download.StreamedWriter) error) {
+func (fake *FakeDownloader) DownloadBackupCalls(stub func(context.Context, string, download.StreamedWriter) (download.Backup, error)) {
 	fake.downloadBackupMutex.Lock()
 	defer fake.downloadBackupMutex.Unlock()
 	fake.DownloadBackupStub = stub
 }
 
-func (fake *FakeDownloader) DownloadBackupArgsForCall(i int) (string, download.StreamedWriter) {
+func (fake *FakeDownloader) DownloadBackupArgsForCall(i int) (context.Context, string, download.StreamedWriter) {
 	fake.downloadBackupMutex.RLock()
 	defer fake.downloadBackupMutex.RUnlock()
 	argsForCall := fake.downloadBackupArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeDownloader) DownloadBackupReturns(result1 error) {
+func (fake *FakeDownloader) DownloadBackupReturns(result1 download.Backup, result2 error) {
 	fake.downloadBackupMutex.Lock()
 	defer fake.downloadBackupMutex.Unlock()
 	fake.DownloadBackupStub = nil
 	fake.downloadBackupReturns = struct {
-		result1 error
-	}{result1}
+		result1 download.Backup
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeDownloader) DownloadBackupReturnsOnCall(i int, result1 error) {
+func (fake *FakeDownloader) DownloadBackupReturnsOnCall(i int, result1 download.Backup, result2 error) {
 	fake.downloadBackupMutex.Lock()
 	defer fake.downloadBackupMutex.Unlock()
 	fake.DownloadBackupStub = nil
 	if fake.downloadBackupReturnsOnCall == nil {
 		fake.downloadBackupReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 download.Backup
+			result2 error
 		})
 	}
 	fake.downloadBackupReturnsOnCall[i] = struct {
+		result1 download.Backup
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDownloader) DownloadBackupLog(arg1 context.Context, arg2 string, arg3 io.Writer) error {
+	fake.downloadBackupLogMutex.Lock()
+	ret, specificReturn := fake.downloadBackupLogReturnsOnCall[len(fake.downloadBackupLogArgsForCall)]
+	fake.downloadBackupLogArgsForCall = append(fake.downloadBackupLogArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 io.Writer
+	}{arg1, arg2, arg3})
+	stub := fake.DownloadBackupLogStub
+	fakeReturns := fake.downloadBackupLogReturns
+	fake.recordInvocation("DownloadBackupLog", []interface{}{arg1, arg2, arg3})
+	fake.downloadBackupLogMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDownloader) DownloadBackupLogCallCount() int {
+	fake.downloadBackupLogMutex.RLock()
+	defer fake.downloadBackupLogMutex.RUnlock()
+	return len(fake.downloadBackupLogArgsForCall)
+}
+
+func (fake *FakeDownloader) DownloadBackupLogCalls(stub func(context.Context, string, io.Writer) error) {
+	fake.downloadBackupLogMutex.Lock()
+	defer fake.downloadBackupLogMutex.Unlock()
+	fake.DownloadBackupLogStub = stub
+}
+
+func (fake *FakeDownloader) DownloadBackupLogArgsForCall(i int) (context.Context, string, io.Writer) {
+	fake.downloadBackupLogMutex.RLock()
+	defer fake.downloadBackupLogMutex.RUnlock()
+	argsForCall := fake.downloadBackupLogArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeDownloader) DownloadBackupLogReturns(result1 error) {
+	fake.downloadBackupLogMutex.Lock()
+	defer fake.downloadBackupLogMutex.Unlock()
+	fake.DownloadBackupLogStub = nil
+	fake.downloadBackupLogReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDownloader) DownloadBackupLogReturnsOnCall(i int, result1 error) {
+	fake.downloadBackupLogMutex.Lock()
+	defer fake.downloadBackupLogMutex.Unlock()
+	fake.DownloadBackupLogStub = nil
+	if fake.downloadBackupLogReturnsOnCall == nil {
+		fake.downloadBackupLogReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.downloadBackupLogReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
+func (fake *FakeDownloader) StartUpload(arg1 context.Context, arg2 string) (download.Upload, error) {
+	fake.startUploadMutex.Lock()
+	ret, specificReturn := fake.startUploadReturnsOnCall[len(fake.startUploadArgsForCall)]
+	fake.startUploadArgsForCall = append(fake.startUploadArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.StartUploadStub
+	fakeReturns := fake.startUploadReturns
+	fake.recordInvocation("StartUpload", []interface{}{arg1, arg2})
+	fake.startUploadMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDownloader) StartUploadCallCount() int {
+	fake.startUploadMutex.RLock()
+	defer fake.startUploadMutex.RUnlock()
+	return len(fake.startUploadArgsForCall)
+}
+
+func (fake *FakeDownloader) StartUploadCalls(stub func(context.Context, string) (download.Upload, error)) {
+	fake.startUploadMutex.Lock()
+	defer fake.startUploadMutex.Unlock()
+	fake.StartUploadStub = stub
+}
+
+func (fake *FakeDownloader) StartUploadArgsForCall(i int) (context.Context, string) {
+	fake.startUploadMutex.RLock()
+	defer fake.startUploadMutex.RUnlock()
+	argsForCall := fake.startUploadArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDownloader) StartUploadReturns(result1 download.Upload, result2 error) {
+	fake.startUploadMutex.Lock()
+	defer fake.startUploadMutex.Unlock()
+	fake.StartUploadStub = nil
+	fake.startUploadReturns = struct {
+		result1 download.Upload
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDownloader) StartUploadReturnsOnCall(i int, result1 download.Upload, result2 error) {
+	fake.startUploadMutex.Lock()
+	defer fake.startUploadMutex.Unlock()
+	fake.StartUploadStub = nil
+	if fake.startUploadReturnsOnCall == nil {
+		fake.startUploadReturnsOnCall = make(map[int]struct {
+			result1 download.Upload
+			result2 error
+		})
+	}
+	fake.startUploadReturnsOnCall[i] = struct {
+		result1 download.Upload
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDownloader) UploadStatus(arg1 context.Context, arg2 string) (download.Upload, error) {
+	fake.uploadStatusMutex.Lock()
+	ret, specificReturn := fake.uploadStatusReturnsOnCall[len(fake.uploadStatusArgsForCall)]
+	fake.uploadStatusArgsForCall = append(fake.uploadStatusArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.UploadStatusStub
+	fakeReturns := fake.uploadStatusReturns
+	fake.recordInvocation("UploadStatus", []interface{}{arg1, arg2})
+	fake.uploadStatusMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDownloader) UploadStatusCallCount() int {
+	fake.uploadStatusMutex.RLock()
+	defer fake.uploadStatusMutex.RUnlock()
+	return len(fake.uploadStatusArgsForCall)
+}
+
+func (fake *FakeDownloader) UploadStatusCalls(stub func(context.Context, string) (download.Upload, error)) {
+	fake.uploadStatusMutex.Lock()
+	defer fake.uploadStatusMutex.Unlock()
+	fake.UploadStatusStub = stub
+}
+
+func (fake *FakeDownloader) UploadStatusArgsForCall(i int) (context.Context, string) {
+	fake.uploadStatusMutex.RLock()
+	defer fake.uploadStatusMutex.RUnlock()
+	argsForCall := fake.uploadStatusArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDownloader) UploadStatusReturns(result1 download.Upload, result2 error) {
+	fake.uploadStatusMutex.Lock()
+	defer fake.uploadStatusMutex.Unlock()
+	fake.UploadStatusStub = nil
+	fake.uploadStatusReturns = struct {
+		result1 download.Upload
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDownloader) UploadStatusReturnsOnCall(i int, result1 download.Upload, result2 error) {
+	fake.uploadStatusMutex.Lock()
+	defer fake.uploadStatusMutex.Unlock()
+	fake.UploadStatusStub = nil
+	if fake.uploadStatusReturnsOnCall == nil {
+		fake.uploadStatusReturnsOnCall = make(map[int]struct {
+			result1 download.Upload
+			result2 error
+		})
+	}
+	fake.uploadStatusReturnsOnCall[i] = struct {
+		result1 download.Upload
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDownloader) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.downloadBackupMutex.RLock()
 	defer fake.downloadBackupMutex.RUnlock()
+	fake.downloadBackupLogMutex.RLock()
+	defer fake.downloadBackupLogMutex.RUnlock()
+	fake.startUploadMutex.RLock()
+	defer fake.startUploadMutex.RUnlock()
+	fake.uploadStatusMutex.RLock()
+	defer fake.uploadStatusMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
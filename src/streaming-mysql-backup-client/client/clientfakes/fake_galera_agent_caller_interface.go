@@ -2,16 +2,18 @@
 package clientfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client"
 )
 
 type FakeGaleraAgentCallerInterface struct {
-	WsrepLocalIndexStub        func(string) (int, error)
+	WsrepLocalIndexStub        func(context.Context, string) (int, error)
 	wsrepLocalIndexMutex       sync.RWMutex
 	wsrepLocalIndexArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	wsrepLocalIndexReturns struct {
 		result1 int
@@ -25,18 +27,19 @@ type FakeGaleraAgentCallerInterface struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndex(arg1 string) (int, error) {
+func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndex(arg1 context.Context, arg2 string) (int, error) {
 	fake.wsrepLocalIndexMutex.Lock()
 	ret, specificReturn := fake.wsrepLocalIndexReturnsOnCall[len(fake.wsrepLocalIndexArgsForCall)]
 	fake.wsrepLocalIndexArgsForCall = append(fake.wsrepLocalIndexArgsForCall, struct {
-		arg1 string
-	}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
 	stub := fake.WsrepLocalIndexStub
 	fakeReturns := fake.wsrepLocalIndexReturns
-	fake.recordInvocation("WsrepLocalIndex", []interface{}{arg1})
+	fake.recordInvocation("WsrepLocalIndex", []interface{}{arg1, arg2})
 	fake.wsrepLocalIndexMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -50,17 +53,17 @@ func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexCallCount() int {
 	return len(fake.wsrepLocalIndexArgsForCall)
 }
 
-func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexCalls(stub func(string) (int, error)) {
+func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexCalls(stub func(context.Context, string) (int, error)) {
 	fake.wsrepLocalIndexMutex.Lock()
 	defer fake.wsrepLocalIndexMutex.Unlock()
 	fake.WsrepLocalIndexStub = stub
 }
 
-func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexArgsForCall(i int) string {
+func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexArgsForCall(i int) (context.Context, string) {
 	fake.wsrepLocalIndexMutex.RLock()
 	defer fake.wsrepLocalIndexMutex.RUnlock()
 	argsForCall := fake.wsrepLocalIndexArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeGaleraAgentCallerInterface) WsrepLocalIndexReturns(result1 int, result2 error) {
@@ -6,10 +6,14 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+	"unicode"
 
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagerflags"
 	"code.cloudfoundry.org/tlsconfig"
+	"github.com/cloudfoundry/tracing"
 	service_config "github.com/pivotal-cf-experimental/service-config"
 	"github.com/pkg/errors"
 )
@@ -27,7 +31,31 @@ type Config struct {
 	TLS                    TLSConfig   `yaml:"TLS"`
 	Logger                 lager.Logger
 	MetadataFields         map[string]string
-	BackendTLS             BackendTLS `yaml:"BackendTLS"`
+	BackendTLS             BackendTLS      `yaml:"BackendTLS"`
+	StallTimeout           time.Duration   `yaml:"StallTimeout"`
+	AsyncUpload            AsyncUpload     `yaml:"AsyncUpload"`
+	Keyring                Keyring         `yaml:"Keyring"`
+	Tracing                tracing.Config  `yaml:"Tracing"`
+	Tracer                 *tracing.Tracer `yaml:"-"`
+}
+
+type Keyring struct {
+	TransitionKey string `yaml:"TransitionKey"`
+	EncryptionKey string `yaml:"EncryptionKey"`
+}
+
+func (k Keyring) validate() error {
+	// The transition key is written into an option file for xtrabackup.
+	if strings.IndexFunc(k.TransitionKey, unicode.IsControl) >= 0 {
+		return errors.New("Keyring.TransitionKey must not contain control characters")
+	}
+	return nil
+}
+
+type AsyncUpload struct {
+	Enabled      bool          `yaml:"Enabled"`
+	PollInterval time.Duration `yaml:"PollInterval"`
+	Timeout      time.Duration `yaml:"Timeout"`
 }
 
 func (c Config) HTTPClient() *http.Client {
@@ -48,6 +76,9 @@ func (c Config) HTTPClient() *http.Client {
 type Instance struct {
 	Address string `yaml:"Address"`
 	UUID    string `yaml:"UUID"`
+	// Name selects a named instance of the backup tool; empty backs up /backup.
+	Name        string       `yaml:"Name"`
+	Credentials *Credentials `yaml:"Credentials"`
 }
 
 type Credentials struct {
@@ -143,6 +174,15 @@ func NewConfig(osArgs []string) (*Config, error) {
 		return &rootConfig, err
 	}
 
+	if err := rootConfig.Keyring.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	rootConfig.Tracer, err = rootConfig.Tracing.NewTracer("streaming-mysql-backup-client", rootConfig.Logger.Session("tracing"))
+	if err != nil {
+		return &rootConfig, err
+	}
+
 	if *encryptionKey != "" {
 		rootConfig.SymmetricKey = *encryptionKey
 	}
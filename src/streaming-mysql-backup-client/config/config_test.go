@@ -1,9 +1,12 @@
 package config_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/tlsconfig/certtest"
 
@@ -27,6 +30,9 @@ var _ = Describe("ClientConfig", func() {
 		galeraAgentCA     string
 		galeraAgentName   string
 		galeraAgentTLS    bool
+		tracingExporter   string
+		tracingFile       string
+		transitionKey     string
 	)
 
 	BeforeEach(func() {
@@ -34,6 +40,9 @@ var _ = Describe("ClientConfig", func() {
 		someEncryptionKey = "myEncryptionKey"
 		enableMutualTLS = false
 		galeraAgentTLS = false
+		tracingExporter = ""
+		tracingFile = ""
+		transitionKey = ""
 
 		ca, err := certtest.BuildCA("serverCA")
 		Expect(err).ToNot(HaveOccurred())
@@ -55,7 +64,7 @@ var _ = Describe("ClientConfig", func() {
 
 	JustBeforeEach(func() {
 		configurationTemplate := `{
-						"Instances": [ { "Address": "fakeIp", "UUID": "some-uuid" }],
+						"Instances": [ { "Address": "fakeIp", "UUID": "some-uuid", "Name": "mysql-2", "Credentials": { "Username": "mysql-2-username", "Password": "mysql-2-password" } }],
 						"BackupServerPort": 8081,
 						"BackupAllMasters": false,
 						"BackupFromInactiveNode": false,
@@ -74,16 +83,30 @@ var _ = Describe("ClientConfig", func() {
 						"TmpDir": "fakeTmp",
 						"OutputDir": "fakeOutput",
 						"SymmetricKey": "fakeKey",
+						"StallTimeout": "30m",
+						"AsyncUpload": {
+							"Enabled": true,
+							"PollInterval": "30s",
+							"Timeout": "12h",
+						},
+						"Keyring": {
+							"TransitionKey": %q,
+							"EncryptionKey": "some-keyring-key",
+						},
 						"BackendTLS": {
 							"Enabled": %t,
 							"ServerName": %q,
 							"CA": %q,
 						},
+						"Tracing": {
+							"Exporter": %q,
+							"File": %q,
+						},
 					}`
 
 		configuration = fmt.Sprintf(
 			configurationTemplate, enableMutualTLS, clientCert, clientKey, serverName, serverCA,
-			galeraAgentTLS, galeraAgentName, galeraAgentCA,
+			transitionKey, galeraAgentTLS, galeraAgentName, galeraAgentCA, tracingExporter, tracingFile,
 		)
 
 		osArgs = []string{
@@ -158,12 +181,85 @@ var _ = Describe("ClientConfig", func() {
 		})
 	})
 
+	It("Has a StallTimeout", func() {
+		rootConfig, err := configPkg.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.StallTimeout).To(Equal(30 * time.Minute))
+	})
+
+	It("Has an AsyncUpload", func() {
+		rootConfig, err := configPkg.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.AsyncUpload).To(Equal(configPkg.AsyncUpload{
+			Enabled:      true,
+			PollInterval: 30 * time.Second,
+			Timeout:      12 * time.Hour,
+		}))
+	})
+
+	It("Has a Keyring", func() {
+		rootConfig, err := configPkg.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Keyring).To(Equal(configPkg.Keyring{EncryptionKey: "some-keyring-key"}))
+	})
+
+	Context("When the TransitionKey contains control characters", func() {
+		BeforeEach(func() {
+			transitionKey = "some-key\nkeyring-file-data=/tmp/keyring"
+		})
+
+		It("Returns an error", func() {
+			_, err := configPkg.NewConfig(osArgs)
+			Expect(err).To(MatchError("Keyring.TransitionKey must not contain control characters"))
+		})
+	})
+
+	It("Records no spans by default", func() {
+		rootConfig, err := configPkg.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Tracer.Service).To(Equal("streaming-mysql-backup-client"))
+		Expect(rootConfig.Tracer.Exporter).To(BeNil())
+	})
+
+	Context("When spans are exported to a file", func() {
+		BeforeEach(func() {
+			tracingExporter = "file"
+			tracingFile = filepath.Join(GinkgoT().TempDir(), "spans.json")
+		})
+
+		It("Creates a Tracer exporting to it", func() {
+			rootConfig, err := configPkg.NewConfig(osArgs)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rootConfig.Tracer.Exporter).NotTo(BeNil())
+			Expect(rootConfig.Tracer.Shutdown(context.Background())).To(Succeed())
+
+			Expect(tracingFile).To(BeAnExistingFile())
+		})
+	})
+
+	Context("When the tracing exporter is unknown", func() {
+		BeforeEach(func() {
+			tracingExporter = "zipkin"
+		})
+
+		It("Returns an error", func() {
+			_, err := configPkg.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid Tracing.Exporter 'zipkin', must be 'otlp', 'file' or empty"))
+		})
+	})
+
 	It("Has data for the Instances", func() {
 		rootConfig, err := configPkg.NewConfig(osArgs)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(rootConfig.Instances[0].Address).To(Equal("fakeIp"))
 		Expect(rootConfig.Instances[0].UUID).To(Equal("some-uuid"))
+		Expect(rootConfig.Instances[0].Name).To(Equal("mysql-2"))
+		Expect(rootConfig.Instances[0].Credentials).To(Equal(&configPkg.Credentials{Username: "mysql-2-username", Password: "mysql-2-password"}))
 	})
 
 	Context("When server CA certificate does not exist", func() {
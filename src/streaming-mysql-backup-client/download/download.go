@@ -1,13 +1,17 @@
 package download
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"code.cloudfoundry.org/lager/v3"
+	"github.com/cloudfoundry/tracing"
 	"github.com/dustin/go-humanize"
 	"github.com/pkg/errors"
 
@@ -15,8 +19,40 @@ import (
 	"github.com/cloudfoundry/streaming-mysql-backup-client/config"
 )
 
+const (
+	BackupIDHeader        = "X-Backup-Id"
+	MetadataTrailerPrefix = "X-Backup-Metadata-"
+)
+
+const progressInterval = time.Minute
+
+var ErrStalled = errors.New("backup download stalled")
+
+type Backup struct {
+	ID       string
+	Metadata map[string]string
+}
+
+type Upload struct {
+	ID         string            `json:"id"`
+	Outcome    string            `json:"outcome"`
+	Bytes      int64             `json:"bytes"`
+	Error      string            `json:"error"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+const (
+	UploadRunning   = "RUNNING"
+	UploadSucceeded = "SUCCEEDED"
+)
+
 type DownloadBackup interface {
-	DownloadBackup(url string, backupWriter StreamedWriter) error
+	DownloadBackup(ctx context.Context, url string, backupWriter StreamedWriter) (Backup, error)
+	DownloadBackupLog(ctx context.Context, url string, w io.Writer) error
+	StartUpload(ctx context.Context, url string) (Upload, error)
+	UploadStatus(ctx context.Context, url string) (Upload, error)
 	TrailerKey() string
 }
 
@@ -69,29 +105,62 @@ type StreamedWriter interface {
 	WriteStream(reader io.Reader) error
 }
 
-func (b *HttpDownloadBackup) DownloadBackup(backupURL string, backupWriter StreamedWriter) error {
-	b.logger.Info("Starting to take backup", lager.Data{
-		"url": backupURL,
-	})
+type credentialsKey struct{}
+
+// WithCredentials makes requests with ctx authenticate with credentials.
+func WithCredentials(ctx context.Context, credentials config.Credentials) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, credentials)
+}
+
+// CredentialsFrom returns the credentials WithCredentials stored in ctx.
+func CredentialsFrom(ctx context.Context) (config.Credentials, bool) {
+	credentials, ok := ctx.Value(credentialsKey{}).(config.Credentials)
+	return credentials, ok
+}
+
+func (b *HttpDownloadBackup) credentials(ctx context.Context) config.Credentials {
+	if credentials, ok := CredentialsFrom(ctx); ok {
+		return credentials
+	}
+	return b.config.Credentials
+}
 
+func (b *HttpDownloadBackup) get(ctx context.Context, url string) (*http.Response, error) {
 	httpClient := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: b.config.TLS.Config,
 		},
 	}
 
-	request, err := http.NewRequest("GET", backupURL, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		b.logger.Error("Failed to create http request", err)
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
+	tracing.Inject(ctx, request.Header)
 
-	request.SetBasicAuth(b.config.Credentials.Username, b.config.Credentials.Password)
+	credentials := b.credentials(ctx)
+	request.SetBasicAuth(credentials.Username, credentials.Password)
 	resp, err := httpClient.Do(request)
 	if err != nil {
 		b.logger.Error("Failed to make http request", err)
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
+	}
+
+	return resp, nil
+}
+
+// DownloadBackup returns the id of the backup even if the download failed.
+func (b *HttpDownloadBackup) DownloadBackup(ctx context.Context, backupURL string, backupWriter StreamedWriter) (Backup, error) {
+	b.logger.Info("Starting to take backup", lager.Data{
+		"url": backupURL,
+	})
+
+	resp, err := b.get(ctx, backupURL)
+	if err != nil {
+		return Backup{}, err
 	}
+	backup := Backup{ID: resp.Header.Get(BackupIDHeader)}
 
 	/*
 	* http.Get() does not throw an error for non-2xx error
@@ -110,7 +179,7 @@ func (b *HttpDownloadBackup) DownloadBackup(backupURL string, backupWriter Strea
 		b.logger.Error("Response returned non-200", err, lager.Data{
 			"response status": resp.Status,
 		})
-		return err
+		return backup, err
 	}
 	defer resp.Body.Close()
 	trackingReader := &trackingReader{r: resp.Body}
@@ -118,32 +187,123 @@ func (b *HttpDownloadBackup) DownloadBackup(backupURL string, backupWriter Strea
 	copyErrChan := make(chan error)
 	go func() {
 		b.logger.Debug("Copying response body to backup writer")
-		err = backupWriter.WriteStream(trackingReader)
-		copyErrChan <- err
+		copyErrChan <- backupWriter.WriteStream(trackingReader)
 	}()
 
-	var copyErr error
+	interval := progressInterval
+	if stallTimeout := b.config.StallTimeout; stallTimeout > 0 && stallTimeout < interval {
+		interval = stallTimeout
+	}
+
+	var (
+		copyErr       error
+		stallErr      error
+		lastBytesRead int
+		idle          time.Duration
+	)
 	done := false
 	for done == false {
 		select {
-		case <-b.clock.After(1 * time.Minute):
-			b.logger.Info(fmt.Sprintf("Downloaded %s of backup so far", humanize.Bytes(uint64(trackingReader.getBytesRead()))))
+		case <-b.clock.After(interval):
+			bytesRead := trackingReader.getBytesRead()
+			b.logger.Info(fmt.Sprintf("Downloaded %s of backup so far", humanize.Bytes(uint64(bytesRead))))
+
+			if bytesRead != lastBytesRead {
+				lastBytesRead, idle = bytesRead, 0
+				continue
+			}
+			idle += interval
+			if stallErr == nil && b.config.StallTimeout > 0 && idle >= b.config.StallTimeout {
+				// Closing the body unblocks the backup writer.
+				stallErr = errors.Wrapf(ErrStalled, "no data received for %s after %s", idle, humanize.Bytes(uint64(bytesRead)))
+				_ = resp.Body.Close()
+			}
 		case copyErr = <-copyErrChan:
 			done = true
 		}
 	}
 
+	if stallErr != nil {
+		b.logger.Error("The download stalled", stallErr)
+		return backup, stallErr
+	}
+
 	if copyErr != nil {
 		b.logger.Error("Failed to copy response to writer", copyErr)
-		return errors.WithStack(err)
+		return backup, errors.WithStack(copyErr)
 	}
 
 	errorMessage := resp.Trailer.Get(b.TrailerKey())
 	if len(errorMessage) > 0 {
 		err := errors.New(errorMessage)
 		b.logger.Error("The download was incomplete", err)
+		return backup, err
+	}
+
+	backup.Metadata = metadataFromTrailer(resp.Trailer)
+	return backup, nil
+}
+
+func metadataFromTrailer(trailer http.Header) map[string]string {
+	metadata := map[string]string{}
+	for key := range trailer {
+		if !strings.HasPrefix(key, MetadataTrailerPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, MetadataTrailerPrefix), "-", "_"))
+		metadata[name] = trailer.Get(key)
+	}
+	return metadata
+}
+
+func (b *HttpDownloadBackup) DownloadBackupLog(ctx context.Context, logURL string, w io.Writer) error {
+	resp, err := b.get(ctx, logURL)
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Backup log endpoint returned %s", resp.Status)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return errors.WithStack(err)
+	}
 
 	return nil
 }
+
+func (b *HttpDownloadBackup) StartUpload(ctx context.Context, backupURL string) (Upload, error) {
+	b.logger.Info("Starting upload of backup", lager.Data{
+		"url": backupURL,
+	})
+
+	return b.getUpload(ctx, backupURL, http.StatusAccepted)
+}
+
+// UploadStatus returns the current state of an upload.
+func (b *HttpDownloadBackup) UploadStatus(ctx context.Context, statusURL string) (Upload, error) {
+	return b.getUpload(ctx, statusURL, http.StatusOK)
+}
+
+func (b *HttpDownloadBackup) getUpload(ctx context.Context, url string, expectedStatus int) (Upload, error) {
+	resp, err := b.get(ctx, url)
+	if err != nil {
+		return Upload{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		err := errors.Errorf("Upload endpoint returned %s", resp.Status)
+		b.logger.Error("Response returned unexpected status", err)
+		return Upload{}, err
+	}
+
+	var upload Upload
+	if err := json.NewDecoder(resp.Body).Decode(&upload); err != nil {
+		return Upload{}, errors.Wrap(err, "failed to decode upload")
+	}
+
+	return upload, nil
+}
@@ -1,6 +1,7 @@
 package download_test
 
 import (
+	"context"
 	"crypto/subtle"
 	"crypto/tls"
 	"fmt"
@@ -10,9 +11,11 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/tlsconfig/certtest"
+	"github.com/cloudfoundry/tracing"
 
 	"github.com/pkg/errors"
 
@@ -157,7 +160,13 @@ var _ = Describe("Downloading the backups", func() {
 				return
 			}
 
+			if r.URL.Path == "/backups/some-backup-id/log" {
+				_, _ = w.Write([]byte("xtrabackup output"))
+				return
+			}
+
 			w.Header().Add("Trailer", downloader.TrailerKey())
+			w.Header().Set(download.BackupIDHeader, "some-backup-id")
 			writeBody(w, expectedResponseBody)
 			writeTrailer(w, downloader.TrailerKey(), trailerError)
 		}
@@ -191,7 +200,7 @@ var _ = Describe("Downloading the backups", func() {
 		})
 
 		It("Returns a not authorized error", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).Should(ContainSubstring("Unauthorized"))
 			Expect(logger.Buffer()).Should(Say(`Unauthorized`))
@@ -203,12 +212,60 @@ var _ = Describe("Downloading the backups", func() {
 			It("downloads a backup and logs", func() {
 				expectedResponseBody = []byte("some response body")
 
-				err := downloader.DownloadBackup(testServer.URL, bufWriter)
+				_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(string(bufWriter.Buffer.Contents())).To(Equal("some response body"))
 				Expect(logger.Buffer()).Should(Say(`Downloaded`))
 			})
+
+			It("returns the id of the backup", func() {
+				backup, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(backup.ID).To(Equal("some-backup-id"))
+			})
+
+			When("a trace is in progress", func() {
+				var traceparent string
+
+				BeforeEach(func() {
+					serve := handlerFunc
+					handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+						traceparent = r.Header.Get("traceparent")
+						serve(w, r)
+					}
+				})
+
+				It("has the backup tool continue it", func() {
+					caller, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+					ctx := tracing.ContextWithRemoteSpanContext(context.Background(), caller)
+
+					_, err := downloader.DownloadBackup(ctx, testServer.URL, bufWriter)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(traceparent).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+				})
+			})
+
+			When("the backup tool reports backup metadata", func() {
+				BeforeEach(func() {
+					handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Trailer", downloader.TrailerKey())
+						w.Header().Set(download.BackupIDHeader, "some-backup-id")
+						writeBody(w, expectedResponseBody)
+						w.Header().Set(http.TrailerPrefix+"X-Backup-Metadata-Replica-Binlog-File", "mysql-bin.000003")
+						w.Header().Set(http.TrailerPrefix+"X-Backup-Metadata-Replica-Binlog-Position", "157")
+					}
+				})
+
+				It("returns the metadata of the backup", func() {
+					backup, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(backup.Metadata).To(Equal(map[string]string{
+						"replica_binlog_file":     "mysql-bin.000003",
+						"replica_binlog_position": "157",
+					}))
+				})
+			})
 		})
 
 		Context("and the CN is not the expected server name", func() {
@@ -228,7 +285,7 @@ var _ = Describe("Downloading the backups", func() {
 			})
 
 			It("returns an error with a stack", func() {
-				err := downloader.DownloadBackup(testServer.URL, bufWriter)
+				_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 				Expect(err).To(HaveOccurred())
 				Expect(reflect.TypeOf(err).String()).To(Equal("*errors.withStack"))
 				Expect(err).To(MatchError(ContainSubstring("certificate is valid for other, not expected-server-name")))
@@ -248,7 +305,7 @@ var _ = Describe("Downloading the backups", func() {
 		})
 
 		It("returns an error with a stack", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(err).To(HaveOccurred())
 			Expect(reflect.TypeOf(err).String()).To(Equal("*errors.withStack"))
 			Expect(err).To(MatchError(ContainSubstring("x509: certificate signed by unknown authority")))
@@ -284,7 +341,7 @@ var _ = Describe("Downloading the backups", func() {
 			})
 
 			It("returns an error with a stack", func() {
-				err := downloader.DownloadBackup(testServer.URL, bufWriter)
+				_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 				Expect(err).To(HaveOccurred())
 				Expect(reflect.TypeOf(err).String()).To(Equal("*errors.withStack"))
 				Expect(err).To(MatchError(ContainSubstring(`tls: bad certificate`)))
@@ -300,7 +357,7 @@ var _ = Describe("Downloading the backups", func() {
 		})
 
 		It("Returns non-200 error", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).Should(ContainSubstring("Non-200 http Response"))
 			Expect(logger.Buffer()).Should(Say(`Response returned non-200`))
@@ -313,7 +370,7 @@ var _ = Describe("Downloading the backups", func() {
 		})
 
 		It("because the download was incomplete", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring(trailerError))
 		})
@@ -342,19 +399,157 @@ var _ = Describe("Downloading the backups", func() {
 		})
 
 		It("returns the right error with a stack", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(reflect.TypeOf(err).String()).To(Equal("*errors.fundamental"))
 			Expect(err).To(MatchError(ContainSubstring(trailerError)))
 		})
 	})
 
+	Context("When the backup is incomplete", func() {
+		BeforeEach(func() {
+			trailerError = "backup was incomplete"
+		})
+
+		It("still returns the id of the backup", func() {
+			backup, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+			Expect(err).To(HaveOccurred())
+			Expect(backup.ID).To(Equal("some-backup-id"))
+		})
+	})
+
+	Context("When the backup stream stalls", func() {
+		BeforeEach(func() {
+			rootConfig.StallTimeout = 2 * time.Minute
+
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Trailer", downloader.TrailerKey())
+				w.Header().Set(download.BackupIDHeader, "some-backup-id")
+				writeBody(w, expectedResponseBody)
+				<-r.Context().Done()
+			}
+		})
+
+		It("aborts the download and returns a stalled error", func() {
+			backup, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+			Expect(err).To(MatchError(download.ErrStalled))
+			Expect(err).To(MatchError(ContainSubstring("no data received for 2m0s")))
+			Expect(backup.ID).To(Equal("some-backup-id"))
+			Expect(logger.Buffer()).To(Say("The download stalled"))
+		})
+
+		It("checks for progress at least as often as the stall timeout", func() {
+			rootConfig.StallTimeout = 30 * time.Second
+			downloader = download.DefaultDownloadBackup(fakeClock, *rootConfig)
+
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+			Expect(err).To(MatchError(download.ErrStalled))
+			Expect(fakeClock.AfterArgsForCall(0)).To(Equal(30 * time.Second))
+		})
+	})
+
+	Context("When the backup stream breaks off partway", func() {
+		BeforeEach(func() {
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Trailer", downloader.TrailerKey())
+				w.Header().Set(download.BackupIDHeader, "some-backup-id")
+				writeBody(w, expectedResponseBody)
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			}
+		})
+
+		It("returns the read error with a stack", func() {
+			backup, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
+			Expect(reflect.TypeOf(err).String()).To(Equal("*errors.withStack"))
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+			Expect(backup.ID).To(Equal("some-backup-id"))
+			Expect(bufWriter.Buffer.Contents()).To(Equal(expectedResponseBody))
+			Expect(logger.Buffer()).Should(Say("Failed to copy response to writer"))
+		})
+	})
+
+	Context("when the context carries credentials", func() {
+		BeforeEach(func() {
+			rootConfig.Credentials.Username = "bad_username"
+			rootConfig.Credentials.Password = "bad_password"
+		})
+
+		It("authenticates with them instead of the configured ones", func() {
+			ctx := download.WithCredentials(context.Background(), config.Credentials{Username: expectedUsername, Password: expectedPassword})
+			_, err := downloader.DownloadBackup(ctx, testServer.URL, bufWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("DownloadBackupLog", func() {
+		It("copies the backup log into the writer", func() {
+			var backupLog strings.Builder
+			Expect(downloader.DownloadBackupLog(context.Background(), testServer.URL+"/backups/some-backup-id/log", &backupLog)).To(Succeed())
+			Expect(backupLog.String()).To(Equal("xtrabackup output"))
+		})
+
+		It("returns an error when the backup tool does not have the log", func() {
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			}
+			testServer.Config.Handler = http.HandlerFunc(handlerFunc)
+
+			var backupLog strings.Builder
+			err := downloader.DownloadBackupLog(context.Background(), testServer.URL+"/backups/other-backup-id/log", &backupLog)
+			Expect(err).To(MatchError(ContainSubstring("Backup log endpoint returned 404 Not Found")))
+		})
+	})
+
+	Describe("StartUpload and UploadStatus", func() {
+		BeforeEach(func() {
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/backup":
+					w.WriteHeader(http.StatusAccepted)
+					_, _ = w.Write([]byte(`{"id":"some-backup-id","outcome":"RUNNING","started_at":"2024-05-01T10:00:00Z"}`))
+				case "/backups/some-backup-id/upload":
+					_, _ = w.Write([]byte(`{"id":"some-backup-id","outcome":"SUCCEEDED","bytes":1024,"metadata":{"location":"s3://backups/some-backup-id"}}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}
+		})
+
+		It("starts an upload", func() {
+			upload, err := downloader.StartUpload(context.Background(), testServer.URL+"/backup?format=xbstream")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upload.ID).To(Equal("some-backup-id"))
+			Expect(upload.Outcome).To(Equal(download.UploadRunning))
+			Expect(upload.StartedAt).To(Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+		})
+
+		It("returns the state of an upload", func() {
+			upload, err := downloader.UploadStatus(context.Background(), testServer.URL+"/backups/some-backup-id/upload")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upload.Outcome).To(Equal(download.UploadSucceeded))
+			Expect(upload.Bytes).To(BeEquivalentTo(1024))
+			Expect(upload.Metadata).To(HaveKeyWithValue("location", "s3://backups/some-backup-id"))
+		})
+
+		It("returns an error when the backup tool does not upload backups", func() {
+			handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+				writeBody(w, expectedResponseBody)
+			}
+			testServer.Config.Handler = http.HandlerFunc(handlerFunc)
+
+			_, err := downloader.StartUpload(context.Background(), testServer.URL+"/backup?format=xbstream")
+			Expect(err).To(MatchError("Upload endpoint returned 200 OK"))
+		})
+	})
+
 	Context("When backupWriter.WriteStream fails", func() {
 		JustBeforeEach(func() {
 			bufWriter.err = errors.New("i am a bad writer")
 		})
 
 		It("logs and returns an error with a stack", func() {
-			err := downloader.DownloadBackup(testServer.URL, bufWriter)
+			_, err := downloader.DownloadBackup(context.Background(), testServer.URL, bufWriter)
 			Expect(reflect.TypeOf(err).String()).To(Equal("*errors.withStack"))
 			Expect(err).To(MatchError("i am a bad writer"))
 			Expect(logger.Buffer()).Should(Say("Failed to copy response to writer"))
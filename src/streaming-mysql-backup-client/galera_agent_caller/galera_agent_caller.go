@@ -1,16 +1,19 @@
 package galera_agent_caller
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+
+	"github.com/cloudfoundry/tracing"
 )
 
 type GaleraAgentCallerInterface interface {
-	WsrepLocalIndex(string) (int, error)
+	WsrepLocalIndex(context.Context, string) (int, error)
 }
 
 type GaleraAgentCaller struct {
@@ -24,7 +27,7 @@ type status struct {
 	Healthy         bool `json:"healthy"`
 }
 
-func (g *GaleraAgentCaller) WsrepLocalIndex(ip string) (int, error) {
+func (g *GaleraAgentCaller) WsrepLocalIndex(ctx context.Context, ip string) (int, error) {
 	httpClient := g.HTTPClient
 	protocol := "http"
 	if g.TLSEnabled {
@@ -32,7 +35,13 @@ func (g *GaleraAgentCaller) WsrepLocalIndex(ip string) (int, error) {
 	}
 	url := fmt.Sprintf("%s://%s:%d/api/v1/status", protocol, ip, g.GaleraAgentPort)
 
-	resp, err := httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return -1, err
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return -1, err
 	}
@@ -1,11 +1,14 @@
 package galera_agent_caller_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 
+	"github.com/cloudfoundry/tracing"
+
 	. "github.com/cloudfoundry/streaming-mysql-backup-client/galera_agent_caller"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -48,14 +51,34 @@ var _ = Describe("Galera Agent", func() {
 	Describe("WsrepLocalIndex", func() {
 
 		It("returns the wsrep local index for that node", func() {
-			wsrepIndex, _ := galeraAgent.WsrepLocalIndex(addr)
+			wsrepIndex, _ := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 			Expect(wsrepIndex).To(Equal(42))
 		})
 
+		Context("a trace is in progress", func() {
+			var traceparent string
+
+			BeforeEach(func() {
+				handlerFunc = func(w http.ResponseWriter, r *http.Request) {
+					traceparent = r.Header.Get("traceparent")
+					writeBody(w, []byte(`{"wsrep_local_index":42,"healthy":true}`))
+				}
+			})
+
+			It("has galera-agent continue it", func() {
+				caller, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+				ctx := tracing.ContextWithRemoteSpanContext(context.Background(), caller)
+
+				_, err := galeraAgent.WsrepLocalIndex(ctx, addr)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(traceparent).To(Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+			})
+		})
+
 		Context("the galera agent server is not up", func() {
 			It("returns an error", func() {
 				testServer.Close()
-				_, err := galeraAgent.WsrepLocalIndex(addr)
+				_, err := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 				Expect(err).To(MatchError(ContainSubstring("connection refused")))
 			})
 		})
@@ -67,7 +90,7 @@ var _ = Describe("Galera Agent", func() {
 				}
 			})
 			It("returns an error", func() {
-				_, err := galeraAgent.WsrepLocalIndex(addr)
+				_, err := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 				Expect(err).To(MatchError(ContainSubstring("invalid character")))
 			})
 		})
@@ -79,7 +102,7 @@ var _ = Describe("Galera Agent", func() {
 				}
 			})
 			It("returns an error", func() {
-				_, err := galeraAgent.WsrepLocalIndex(addr)
+				_, err := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 				Expect(err).To(MatchError("500 Internal Server Error: Error response from node: Bad things happened"))
 			})
 		})
@@ -91,7 +114,7 @@ var _ = Describe("Galera Agent", func() {
 				}
 			})
 			It("returns an error", func() {
-				_, err := galeraAgent.WsrepLocalIndex(addr)
+				_, err := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 				Expect(err).To(MatchError(ContainSubstring("Node is not healthy")))
 			})
 		})
@@ -103,7 +126,7 @@ var _ = Describe("Galera Agent", func() {
 		})
 
 		It("connects via TLS", func() {
-			index, err := galeraAgent.WsrepLocalIndex(addr)
+			index, err := galeraAgent.WsrepLocalIndex(context.Background(), addr)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(index).To(Equal(42))
 		})
@@ -5,6 +5,8 @@ go 1.20
 require (
 	code.cloudfoundry.org/lager/v3 v3.0.3
 	code.cloudfoundry.org/tlsconfig v0.0.0-20240417163319-a2cf10de323a
+	github.com/cloudfoundry/tracing v0.0.0
+	github.com/cloudfoundry/xtrabackuplog v0.0.0
 	github.com/dustin/go-humanize v1.0.1
 	github.com/maxbrunsfeld/counterfeiter/v6 v6.8.1
 	github.com/onsi/ginkgo/v2 v2.17.1
@@ -36,3 +38,7 @@ require (
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/cloudfoundry/tracing => ../tracing
+
+replace github.com/cloudfoundry/xtrabackuplog => ../xtrabackuplog
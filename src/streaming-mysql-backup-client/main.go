@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-client/client"
 	"github.com/cloudfoundry/streaming-mysql-backup-client/clock"
@@ -12,6 +14,8 @@ import (
 	"github.com/cloudfoundry/streaming-mysql-backup-client/tarpit"
 )
 
+const tracerShutdownTimeout = 10 * time.Second
+
 func main() {
 
 	rootConfig, err := config.NewConfig(os.Args)
@@ -32,7 +36,16 @@ func main() {
 			HTTPClient:      rootConfig.HTTPClient(),
 		},
 	)
-	if err := c.Execute(); err != nil {
+	err = c.Execute()
+
+	// Export the spans still queued before exiting.
+	ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	if shutdownErr := rootConfig.Tracer.Shutdown(ctx); shutdownErr != nil {
+		logger.Error("Exporting spans failed", shutdownErr)
+	}
+	cancel()
+
+	if err != nil {
 		logger.Fatal("All backups failed. Not able to generate a valid backup artifact. See error(s) below: %s", err)
 	}
 }
@@ -2,6 +2,16 @@ package prepare
 
 import (
 	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-client/fileutils"
+)
+
+const (
+	XtraBackup  = "xtrabackup"
+	MariaBackup = "mariabackup"
+	Clone       = "clone"
 )
 
 type BackupPreparer struct {
@@ -11,6 +21,49 @@ func DefaultBackupPreparer() *BackupPreparer {
 	return &BackupPreparer{}
 }
 
-func (*BackupPreparer) Command(backupDir string) *exec.Cmd {
-	return exec.Command("xtrabackup", "--prepare", "--target-dir", backupDir)
+type Keyring struct {
+	DefaultsExtraFile string
+	FileData          string
+	ComponentConfig   string
+}
+
+func (k Keyring) args() []string {
+	var args []string
+	if k.FileData != "" {
+		args = append(args, "--keyring-file-data="+k.FileData)
+	}
+	if k.ComponentConfig != "" {
+		args = append(args, "--component-keyring-config="+k.ComponentConfig)
+	}
+	return args
+}
+
+// TransitionKeyOptions keeps key off the command line, where every user of the host could read it.
+func TransitionKeyOptions(key string) []byte {
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key)
+	return []byte("[xtrabackup]\ntransition-key=\"" + quoted + "\"\n")
+}
+
+// Command prepares with the tool that took the backup, as xtrabackup can not prepare mariabackup's.
+func (*BackupPreparer) Command(backupDir string, keyring Keyring) *exec.Cmd {
+	engine := Engine(backupDir)
+	var args []string
+	if engine == XtraBackup && keyring.DefaultsExtraFile != "" {
+		// --defaults-extra-file has to come first.
+		args = append(args, "--defaults-extra-file="+keyring.DefaultsExtraFile)
+	}
+	args = append(args, "--prepare", "--target-dir", backupDir)
+	if engine == XtraBackup {
+		args = append(args, keyring.args()...)
+	}
+	return exec.Command(engine, args...)
+}
+
+// Engine falls back to xtrabackup when xtrabackup_info cannot be read.
+func Engine(backupDir string) string {
+	fields, err := fileutils.ExtractFileFields(filepath.Join(backupDir, "xtrabackup_info"))
+	if err == nil && fields["tool_name"] == MariaBackup {
+		return MariaBackup
+	}
+	return XtraBackup
 }
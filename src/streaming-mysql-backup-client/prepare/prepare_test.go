@@ -1,6 +1,7 @@
 package prepare_test
 
 import (
+	"os"
 	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -10,12 +11,60 @@ import (
 )
 
 var _ = Describe("Prepare Command", func() {
-	It("Uses innobackupex", func() {
+	var backupDir string
+
+	BeforeEach(func() {
+		backupDir = GinkgoT().TempDir()
+	})
+
+	It("Uses xtrabackup", func() {
 		backupPrepare := prepare.DefaultBackupPreparer()
 
-		cmd := backupPrepare.Command("path/to/backup")
+		cmd := backupPrepare.Command("path/to/backup", prepare.Keyring{})
 
 		Expect(filepath.Base(cmd.Path)).To(Equal("xtrabackup"))
 		Expect(cmd.Args[1:]).To(Equal([]string{"--prepare", "--target-dir", "path/to/backup"}))
 	})
+
+	It("Uses xtrabackup for backups taken by xtrabackup", func() {
+		Expect(os.WriteFile(filepath.Join(backupDir, "xtrabackup_info"), []byte("tool_name = xtrabackup\ntool_version = 8.0.35-30\n"), 0600)).To(Succeed())
+
+		cmd := prepare.DefaultBackupPreparer().Command(backupDir, prepare.Keyring{})
+
+		Expect(cmd.Args[0]).To(Equal("xtrabackup"))
+	})
+
+	It("Uses mariabackup for backups taken by mariabackup", func() {
+		Expect(os.WriteFile(filepath.Join(backupDir, "xtrabackup_info"), []byte("tool_name = mariabackup\ntool_version = 10.6.16-MariaDB\n"), 0600)).To(Succeed())
+
+		cmd := prepare.DefaultBackupPreparer().Command(backupDir, prepare.Keyring{})
+
+		Expect(cmd.Args[0]).To(Equal("mariabackup"))
+		Expect(cmd.Args[1:]).To(Equal([]string{"--prepare", "--target-dir", backupDir}))
+	})
+
+	It("Passes the keyring to xtrabackup", func() {
+		cmd := prepare.DefaultBackupPreparer().Command(backupDir, prepare.Keyring{
+			DefaultsExtraFile: "/tmp/transition-key.cnf",
+			ComponentConfig:   "/tmp/component_keyring_file.cnf",
+		})
+
+		Expect(cmd.Args[1:]).To(Equal([]string{
+			"--defaults-extra-file=/tmp/transition-key.cnf",
+			"--prepare", "--target-dir", backupDir,
+			"--component-keyring-config=/tmp/component_keyring_file.cnf",
+		}))
+	})
+
+	It("Quotes the transition key in its option file", func() {
+		Expect(string(prepare.TransitionKeyOptions(`some"key\`))).To(Equal("[xtrabackup]\ntransition-key=\"some\\\"key\\\\\"\n"))
+	})
+
+	It("Does not pass the keyring to mariabackup", func() {
+		Expect(os.WriteFile(filepath.Join(backupDir, "xtrabackup_info"), []byte("tool_name = mariabackup\ntool_version = 10.6.16-MariaDB\n"), 0600)).To(Succeed())
+
+		cmd := prepare.DefaultBackupPreparer().Command(backupDir, prepare.Keyring{FileData: "/tmp/keyring"})
+
+		Expect(cmd.Args[1:]).To(Equal([]string{"--prepare", "--target-dir", backupDir}))
+	})
 })
@@ -1,25 +1,112 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"maps"
 	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
+	"github.com/cloudfoundry/tracing"
+	"github.com/cloudfoundry/xtrabackuplog"
+	"github.com/google/uuid"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/identity"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/inspect"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+)
+
+const (
+	TrailerKey     = "X-Backup-Error"
+	BackupIDHeader = "X-Backup-Id"
+	// MetadataTrailerPrefix prefixes the trailers carrying backup metadata.
+	MetadataTrailerPrefix = "X-Backup-Metadata-"
 )
 
-const TrailerKey = "X-Backup-Error"
+var validHistoryName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 
 type BackupHandler struct {
 	BackupWriter BackupWriter
-	Logger       lager.Logger
+	BackupLogs   BackupLogStore
+	History      BackupHistory
+	HistoryName  string
+	Running      *RunningBackups
+	// Uploader, when set, stores backups instead of streaming them.
+	Uploader    BackupUploader
+	Uploads     *Uploads
+	Instance    string
+	Maintenance *Maintenance
+	Tracer      *tracing.Tracer
+	Logger      lager.Logger
+}
+
+type BackupRequest struct {
+	ID          string
+	Format      string
+	HistoryName string
+	Log         io.Writer
+	Started     func(Process)
+	SetMetadata func(key, value string)
+}
+
+// AddMetadata passes key and value to SetMetadata, if set.
+func (r BackupRequest) AddMetadata(key, value string) {
+	if r.SetMetadata != nil {
+		r.SetMetadata(key, value)
+	}
 }
 
+// BackupWriter streams a backup to w until it is done or ctx is.
 type BackupWriter interface {
-	StreamTo(format string, w io.Writer) error
+	StreamTo(ctx context.Context, req BackupRequest, w io.Writer) error
+}
+
+// BackupUploader stores backups outside of the tool, e.g. in object storage.
+type BackupUploader interface {
+	Upload(ctx context.Context, id, format string) (Upload, error)
+}
+
+// Upload stores a backup; Complete is only called once all of it is written.
+type Upload interface {
+	io.Writer
+	Complete() (location string, err error)
+	Abort() error
+}
+
+type BackupLogStore interface {
+	Create(id string) (io.WriteCloser, error)
+	Open(id string) (io.ReadCloser, error)
+}
+
+type BackupHistory interface {
+	Append(r history.Record) error
+	List(limit int) ([]history.Record, error)
+	Get(id string) (history.Record, error)
 }
 
 func (b *BackupHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx, span := b.Tracer.Start(tracing.Extract(req.Context(), req.Header), "backup")
+	span.SetKind(tracing.KindServer)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.path", req.URL.Path)
+	defer span.End()
+
+	if b.Maintenance != nil {
+		if state := b.Maintenance.State(); state != nil {
+			b.Logger.Info("refusing backup during maintenance", lager.Data{"reason": state.Reason})
+			span.SetError(errors.New("refusing backup during maintenance"))
+			writeMaintenance(w, state)
+			return
+		}
+	}
+
 	var format = "tar"
 
 	switch f := req.URL.Query().Get("format"); f {
@@ -29,29 +116,301 @@ func (b *BackupHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		format = f
 	default:
 		b.Logger.Info("invalid request format", lager.Data{"format": f})
-		w.WriteHeader(http.StatusBadRequest)
-		msg, _ := json.Marshal(map[string]string{"error": "invalid backup format '" + f + "' requested"})
-		_, _ = w.Write(msg)
+		writeJSONError(w, http.StatusBadRequest, "invalid backup format '"+f+"' requested")
 		return
 	}
 
+	historyName := b.HistoryName
+	if name := req.URL.Query().Get("history_name"); name != "" {
+		switch {
+		case b.HistoryName == "":
+			writeJSONError(w, http.StatusBadRequest, "xtrabackup history is not enabled")
+			return
+		case !validHistoryName.MatchString(name):
+			writeJSONError(w, http.StatusBadRequest, "invalid history name '"+name+"' requested")
+			return
+		}
+		historyName = name
+	}
+
+	record := history.Record{
+		ID:         uuid.New().String(),
+		Requester:  requester(req),
+		RemoteAddr: req.RemoteAddr,
+		Options:    map[string]string{"format": format},
+		StartedAt:  time.Now(),
+	}
+	if historyName != "" {
+		record.Options["history_name"] = historyName
+	}
+	if b.Instance != "" {
+		record.Options["instance"] = b.Instance
+	}
+	span.SetAttribute("backup.id", record.ID)
+	for key, value := range record.Options {
+		span.SetAttribute("backup."+key, value)
+	}
+
 	b.Logger.Info("Responding to request", lager.Data{
-		"url":    req.URL.String(),
-		"method": req.Method,
+		"url":       req.URL.String(),
+		"method":    req.Method,
+		"backup_id": record.ID,
 	})
 
+	if b.Uploader != nil {
+		b.startUpload(ctx, w, record, historyName)
+		return
+	}
+
 	// NOTE: We set this in the Header because of the HTTP spec
 	// http://www.w3.org/Protocols/rfc2616/rfc2616-sec14.html#sec14.40
 	// Even though we cannot test it, because the `net/http.Get()` strips
 	// "Trailer" out of the Header
 	w.Header().Set("Trailer", TrailerKey)
 	w.Header().Set("Content-Type", "application/octet-stream; format="+format)
+	w.Header().Set(BackupIDHeader, record.ID)
+
+	b.takeBackup(ctx, &record, historyName, w)
 
-	var trailerValue string
-	if err := b.BackupWriter.StreamTo(format, w); err != nil {
-		b.Logger.Error("streaming backup failed", err)
-		trailerValue = err.Error()
+	w.Header().Set(TrailerKey, record.Error)
+	for key, value := range record.Metadata {
+		w.Header().Set(http.TrailerPrefix+MetadataTrailerPrefix+strings.ReplaceAll(key, "_", "-"), value)
+	}
+	if record.Error != "" {
+		span.SetError(errors.New(record.Error))
+	}
+	b.recordHistory(ctx, record)
+}
+
+// The backup outlives the request, but its spans still belong to its trace.
+func (b *BackupHandler) startUpload(ctx context.Context, w http.ResponseWriter, record history.Record, historyName string) {
+	ctx = context.WithoutCancel(ctx)
+
+	upload, err := b.Uploader.Upload(ctx, record.ID, record.Options["format"])
+	if err != nil {
+		b.Logger.Error("starting upload failed", err, lager.Data{"backup_id": record.ID})
+		writeJSONError(w, http.StatusInternalServerError, "failed to start the upload")
+		return
 	}
 
-	w.Header().Set(TrailerKey, trailerValue)
+	job := record
+	job.Outcome = UploadRunning
+	b.Uploads.Put(job)
+
+	go func() {
+		b.takeBackup(ctx, &record, historyName, upload)
+		if record.Outcome == history.Succeeded {
+			_, span := b.Tracer.Start(ctx, "complete upload")
+			location, err := upload.Complete()
+			record.FinishedAt = time.Now()
+			span.SetError(err)
+			span.End()
+			if err != nil {
+				b.Logger.Error("completing upload failed", err, lager.Data{"backup_id": record.ID})
+				record.Outcome, record.Error = history.Failed, err.Error()
+			} else {
+				if record.Metadata == nil {
+					record.Metadata = map[string]string{}
+				}
+				record.Metadata["location"] = location
+			}
+		}
+		if record.Outcome != history.Succeeded {
+			if err := upload.Abort(); err != nil {
+				b.Logger.Error("removing the incomplete upload failed", err, lager.Data{"backup_id": record.ID})
+			}
+		}
+		b.recordHistory(ctx, record)
+		b.Uploads.Put(record)
+	}()
+
+	w.Header().Set(BackupIDHeader, record.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job)
 }
+
+// takeBackup streams a backup to w and completes record with how it went.
+func (b *BackupHandler) takeBackup(ctx context.Context, record *history.Record, historyName string, w io.Writer) {
+	ctx, span := b.Tracer.Start(ctx, "take backup")
+	defer func() {
+		span.SetAttribute("backup.outcome", string(record.Outcome))
+		span.SetAttribute("backup.bytes", record.Bytes)
+		if engine := record.Metadata["backup_engine"]; engine != "" {
+			span.SetAttribute("backup.engine", engine)
+		}
+		if record.Error != "" {
+			span.SetError(errors.New(record.Error))
+		}
+		span.End()
+	}()
+
+	backupLog := b.createBackupLog(record.ID)
+	defer backupLog.Close()
+
+	var started func(Process)
+	if b.Running != nil {
+		var done func()
+		ctx, done = b.Running.Start(ctx, record.ID)
+		defer done()
+		started = func(p Process) { b.Running.Attach(record.ID, p) }
+	}
+
+	parser := xtrabackuplog.NewParser(nil)
+	inspector := inspect.New(record.Options["format"])
+	counter := &countingWriter{w: io.MultiWriter(w, inspector)}
+	reported := &reportedMetadata{}
+
+	err := b.BackupWriter.StreamTo(ctx, BackupRequest{
+		ID:          record.ID,
+		Format:      record.Options["format"],
+		HistoryName: historyName,
+		Log:         io.MultiWriter(backupLog, parser),
+		Started:     started,
+		SetMetadata: reported.set,
+	}, counter)
+	cause := context.Cause(ctx)
+	cancelled := err != nil && (errors.Is(cause, ErrCancelled) || errors.Is(cause, ErrMaintenance))
+	switch {
+	case cancelled:
+		b.Logger.Info("backup cancelled", lager.Data{"backup_id": record.ID, "cause": cause.Error()})
+		record.Error = cause.Error()
+	case err != nil:
+		b.Logger.Error("streaming backup failed", err, lager.Data{"backup_id": record.ID})
+		record.Error = err.Error()
+	}
+
+	parser.Flush()
+	progress := parser.Progress()
+	record.FinishedAt = time.Now()
+	record.Bytes = counter.n
+	record.FromLSN = progress.FromLSN
+	record.ToLSN = progress.LSN
+	record.Metadata = backupMetadata(progress, inspector.Close(), reported.values())
+	switch {
+	case cancelled:
+		record.Outcome = history.Cancelled
+	case err != nil:
+		record.Outcome = history.Failed
+	default:
+		record.Outcome = history.Succeeded
+	}
+}
+
+// What the backup writer reported itself takes precedence.
+func backupMetadata(progress xtrabackuplog.Progress, info xtrabackuplog.BackupInfo, reported map[string]string) map[string]string {
+	metadata := info.Metadata()
+
+	if r := progress.Replica; r != nil {
+		for key, value := range map[string]string{
+			"replica_source_host":     r.SourceHost,
+			"replica_binlog_file":     r.BinlogFile,
+			"replica_binlog_position": r.BinlogPosition,
+			"replica_gtid_set":        r.GTIDSet,
+			"replica_channel":         r.Channel,
+		} {
+			if value != "" {
+				metadata[key] = value
+			}
+		}
+	}
+
+	if e := progress.Engine; e != nil {
+		metadata["backup_engine"] = e.Name
+		metadata["backup_engine_version"] = e.Version
+	}
+
+	for key, value := range reported {
+		metadata[key] = value
+	}
+
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// reportedMetadata is written to from several goroutines.
+type reportedMetadata struct {
+	mu       sync.Mutex
+	metadata map[string]string
+}
+
+func (r *reportedMetadata) set(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.metadata == nil {
+		r.metadata = map[string]string{}
+	}
+	r.metadata[key] = value
+}
+
+func (r *reportedMetadata) values() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.metadata)
+}
+
+func (b *BackupHandler) recordHistory(ctx context.Context, r history.Record) {
+	if b.History == nil {
+		return
+	}
+
+	_, span := b.Tracer.Start(ctx, "record history")
+	defer span.End()
+
+	if err := b.History.Append(r); err != nil {
+		span.SetError(err)
+		b.Logger.Error("recording backup history failed", err, lager.Data{"backup_id": r.ID})
+	}
+}
+
+func requester(req *http.Request) string {
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		if name := identity.Name(req.TLS.PeerCertificates[0]); name != "" {
+			return name
+		}
+	}
+
+	username, _ := middleware.AuthenticatedUsername(req)
+	return username
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	msg, _ := json.Marshal(map[string]string{"error": message})
+	_, _ = w.Write(msg)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// A backup is still taken when its log cannot be stored.
+func (b *BackupHandler) createBackupLog(backupID string) io.WriteCloser {
+	if b.BackupLogs == nil {
+		return nopWriteCloser{io.Discard}
+	}
+
+	backupLog, err := b.BackupLogs.Create(backupID)
+	if err != nil {
+		b.Logger.Error("creating backup log failed", err, lager.Data{"backup_id": backupID})
+		return nopWriteCloser{io.Discard}
+	}
+
+	return backupLog
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -1,16 +1,27 @@
 package api_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"github.com/cloudfoundry/tracing"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/backuplog"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
 )
 
 var _ = Describe("BackupHandler", func() {
@@ -18,6 +29,8 @@ var _ = Describe("BackupHandler", func() {
 		testLogger         *lagertest.TestLogger
 		backupHandler      *BackupHandler
 		fakeBackupWriter   *stubBackupWriter
+		fakeBackupLogs     *stubBackupLogStore
+		fakeHistory        *stubBackupHistory
 		fakeResponseWriter *httptest.ResponseRecorder
 		request            *http.Request
 		err                error
@@ -26,8 +39,12 @@ var _ = Describe("BackupHandler", func() {
 	BeforeEach(func() {
 		testLogger = lagertest.NewTestLogger("collector-test")
 		fakeBackupWriter = &stubBackupWriter{}
+		fakeBackupLogs = &stubBackupLogStore{}
+		fakeHistory = &stubBackupHistory{}
 		backupHandler = &BackupHandler{
 			BackupWriter: fakeBackupWriter,
+			BackupLogs:   fakeBackupLogs,
+			History:      fakeHistory,
 			Logger:       testLogger,
 		}
 		fakeResponseWriter = httptest.NewRecorder()
@@ -51,6 +68,423 @@ var _ = Describe("BackupHandler", func() {
 		Expect(res.Trailer).To(HaveKeyWithValue(TrailerKey, ContainElement("some-error")))
 	})
 
+	It("identifies each backup in a response header", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		backupID := fakeResponseWriter.Result().Header.Get(BackupIDHeader)
+		Expect(backupID).NotTo(BeEmpty())
+		Expect(fakeBackupWriter.idArg).To(Equal(backupID))
+	})
+
+	It("stores the log of the backup under its id", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.log = "xtrabackup output"
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		backupID := fakeResponseWriter.Result().Header.Get(BackupIDHeader)
+		Expect(fakeBackupLogs.logs).To(HaveKey(backupID))
+		Expect(fakeBackupLogs.logs[backupID].String()).To(Equal("xtrabackup output"))
+	})
+
+	When("the backup log cannot be created", func() {
+		BeforeEach(func() {
+			fakeBackupLogs.createErr = errors.New("disk full")
+		})
+
+		It("still streams the backup", func() {
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+			fakeBackupWriter.content = "some-data"
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			body, err := io.ReadAll(fakeResponseWriter.Result().Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal("some-data"))
+			Expect(testLogger.LogMessages()).To(ContainElement("collector-test.creating backup log failed"))
+		})
+	})
+
+	It("records the backup in the history", func() {
+		request, err = http.NewRequest("GET", "/backup?format=xbstream", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request.SetBasicAuth("admin", "password")
+		request.RemoteAddr = "10.0.0.1:54321"
+		fakeBackupWriter.content = "some-data"
+		fakeBackupWriter.log = "Transaction log of lsn (100) to (200) was copied.\ncompleted OK!\n"
+
+		middleware.BasicAuth(backupHandler, "admin", "password").ServeHTTP(fakeResponseWriter, request)
+
+		Expect(fakeHistory.records).To(HaveLen(1))
+		record := fakeHistory.records[0]
+		Expect(record.ID).To(Equal(fakeResponseWriter.Result().Header.Get(BackupIDHeader)))
+		Expect(record.Requester).To(Equal("admin"))
+		Expect(record.RemoteAddr).To(Equal("10.0.0.1:54321"))
+		Expect(record.Options).To(Equal(map[string]string{"format": "xbstream"}))
+		Expect(record.Bytes).To(BeEquivalentTo(len("some-data")))
+		Expect(record.FromLSN).To(BeEquivalentTo(100))
+		Expect(record.ToLSN).To(BeEquivalentTo(200))
+		Expect(record.Outcome).To(Equal(history.Succeeded))
+		Expect(record.StartedAt).NotTo(BeZero())
+		Expect(record.FinishedAt).NotTo(BeTemporally("<", record.StartedAt))
+	})
+
+	It("records failed backups with their error", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.err = errors.New("some-error")
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		Expect(fakeHistory.records).To(HaveLen(1))
+		Expect(fakeHistory.records[0].Outcome).To(Equal(history.Failed))
+		Expect(fakeHistory.records[0].Error).To(Equal("some-error"))
+	})
+
+	When("the history cannot be written", func() {
+		It("still completes the backup", func() {
+			fakeHistory.appendErr = errors.New("disk full")
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.Result().Trailer.Get(TrailerKey)).To(BeEmpty())
+			Expect(testLogger.LogMessages()).To(ContainElement("collector-test.recording backup history failed"))
+		})
+	})
+
+	It("records the instance it backs up in the history", func() {
+		backupHandler.Instance = "mysql-2"
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		Expect(fakeHistory.records[0].Options).To(HaveKeyWithValue("instance", "mysql-2"))
+	})
+
+	When("xtrabackup history is enabled", func() {
+		BeforeEach(func() {
+			backupHandler.HistoryName = "streaming-backup"
+		})
+
+		It("passes the configured history name to the BackupWriter", func() {
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeBackupWriter.historyNameArg).To(Equal("streaming-backup"))
+			Expect(fakeHistory.records[0].Options).To(HaveKeyWithValue("history_name", "streaming-backup"))
+		})
+
+		It("allows the request to choose the history name", func() {
+			request, err = http.NewRequest("GET", "/backup?history_name=weekly-full", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeBackupWriter.historyNameArg).To(Equal("weekly-full"))
+		})
+
+		It("rejects invalid history names", func() {
+			request, err = http.NewRequest("GET", "/backup?history_name=a%27b", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.Code).To(Equal(http.StatusBadRequest))
+			Expect(fakeResponseWriter.Body.String()).To(MatchJSON(`{"error": "invalid history name 'a'b' requested"}`))
+			Expect(fakeBackupWriter.callCount).To(BeZero())
+		})
+	})
+
+	When("xtrabackup history is not enabled", func() {
+		It("does not pass a history name to the BackupWriter", func() {
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeBackupWriter.historyNameArg).To(BeEmpty())
+		})
+
+		It("rejects requests for a history name", func() {
+			request, err = http.NewRequest("GET", "/backup?history_name=weekly-full", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.Code).To(Equal(http.StatusBadRequest))
+			Expect(fakeResponseWriter.Body.String()).To(MatchJSON(`{"error": "xtrabackup history is not enabled"}`))
+		})
+	})
+
+	It("returns the replica position reported by xtrabackup as metadata trailers", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.log = "MySQL slave binlog position: master host '10.0.0.1', filename 'mysql-bin.000002', position '157', channel name: ''\n"
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		trailer := fakeResponseWriter.Result().Trailer
+		Expect(trailer.Get(MetadataTrailerPrefix + "Replica-Source-Host")).To(Equal("10.0.0.1"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Replica-Binlog-File")).To(Equal("mysql-bin.000002"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Replica-Binlog-Position")).To(Equal("157"))
+		Expect(trailer).NotTo(HaveKey(MetadataTrailerPrefix + "Replica-Channel"))
+		Expect(fakeHistory.records[0].Metadata).To(Equal(map[string]string{
+			"replica_source_host":     "10.0.0.1",
+			"replica_binlog_file":     "mysql-bin.000002",
+			"replica_binlog_position": "157",
+		}))
+	})
+
+	It("returns the engine that took the backup as metadata trailers", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.log = "mariabackup based on MariaDB server 10.6.16-MariaDB Linux (x86_64)\n"
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		trailer := fakeResponseWriter.Result().Trailer
+		Expect(trailer.Get(MetadataTrailerPrefix + "Backup-Engine")).To(Equal("mariabackup"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Backup-Engine-Version")).To(Equal("10.6.16-MariaDB"))
+		Expect(fakeHistory.records[0].Metadata).To(Equal(map[string]string{
+			"backup_engine":         "mariabackup",
+			"backup_engine_version": "10.6.16-MariaDB",
+		}))
+	})
+
+	It("returns the metadata the backup writer reports as metadata trailers", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.metadata = map[string]string{
+			"backup_engine":         "clone",
+			"backup_engine_version": "8.0.35",
+		}
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		trailer := fakeResponseWriter.Result().Trailer
+		Expect(trailer.Get(MetadataTrailerPrefix + "Backup-Engine")).To(Equal("clone"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Backup-Engine-Version")).To(Equal("8.0.35"))
+		Expect(fakeHistory.records[0].Metadata).To(Equal(fakeBackupWriter.metadata))
+	})
+
+	It("returns whether the redo log was archived as a metadata trailer", func() {
+		request, err = http.NewRequest("GET", "/backup", nil)
+		Expect(err).NotTo(HaveOccurred())
+		fakeBackupWriter.metadata = map[string]string{"redo_log_archiving": "false"}
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		trailer := fakeResponseWriter.Result().Trailer
+		Expect(trailer.Get(MetadataTrailerPrefix + "Redo-Log-Archiving")).To(Equal("false"))
+		Expect(fakeHistory.records[0].Metadata).To(HaveKeyWithValue("redo_log_archiving", "false"))
+	})
+
+	It("returns what xtrabackup recorded in the backup as metadata trailers", func() {
+		request, err = http.NewRequest("GET", "/backup?format=xbstream", nil)
+		Expect(err).NotTo(HaveOccurred())
+		var archive bytes.Buffer
+		w := xbstream.NewWriter(&archive)
+		Expect(w.WriteFile("ibdata1", strings.NewReader("some-tablespace"))).To(Succeed())
+		Expect(w.WriteFile("xtrabackup_binlog_info", strings.NewReader("binlog.000003\t157\t3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5\n"))).To(Succeed())
+		Expect(w.WriteFile("xtrabackup_galera_info", strings.NewReader("8f3e6cba-6c79-11ee-8c99-0242ac120002:42\n"))).To(Succeed())
+		Expect(w.WriteFile("xtrabackup_checkpoints", strings.NewReader("backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 19006600\nlast_lsn = 19006610\n"))).To(Succeed())
+		fakeBackupWriter.content = archive.String()
+
+		backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+		Expect(fakeResponseWriter.Body.String()).To(Equal(archive.String()))
+		trailer := fakeResponseWriter.Result().Trailer
+		Expect(trailer.Get(MetadataTrailerPrefix + "Binlog-File")).To(Equal("binlog.000003"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Binlog-Position")).To(Equal("157"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Gtid-Executed")).To(Equal("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "Galera-Position")).To(Equal("8f3e6cba-6c79-11ee-8c99-0242ac120002:42"))
+		Expect(trailer.Get(MetadataTrailerPrefix + "To-Lsn")).To(Equal("19006600"))
+		Expect(fakeHistory.records[0].Metadata).To(Equal(map[string]string{
+			"binlog_file":     "binlog.000003",
+			"binlog_position": "157",
+			"gtid_executed":   "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5",
+			"galera_uuid":     "8f3e6cba-6c79-11ee-8c99-0242ac120002",
+			"galera_seqno":    "42",
+			"galera_position": "8f3e6cba-6c79-11ee-8c99-0242ac120002:42",
+			"from_lsn":        "0",
+			"to_lsn":          "19006600",
+			"last_lsn":        "19006610",
+		}))
+	})
+
+	When("an operator cancels the backup", func() {
+		BeforeEach(func() {
+			backupHandler.Running = &RunningBackups{Logger: testLogger}
+			fakeBackupWriter.started = make(chan struct{})
+			fakeBackupWriter.process = &stubProcess{}
+		})
+
+		It("stops the backup and reports CANCELLED in the trailer", func() {
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				backupHandler.ServeHTTP(fakeResponseWriter, request)
+			}()
+
+			Eventually(fakeBackupWriter.started).Should(BeClosed())
+			Expect(backupHandler.Running.Cancel(fakeBackupWriter.idArg)).To(Succeed())
+			Eventually(done).Should(BeClosed())
+
+			Expect(fakeResponseWriter.Result().Trailer.Get(TrailerKey)).To(Equal("CANCELLED: backup cancelled by operator"))
+			Expect(fakeHistory.records[0].Outcome).To(Equal(history.Cancelled))
+			Expect(backupHandler.Running.Cancel(fakeBackupWriter.idArg)).To(MatchError(ErrBackupNotRunning))
+		})
+	})
+
+	When("the tool is in maintenance", func() {
+		BeforeEach(func() {
+			backupHandler.Running = &RunningBackups{Logger: testLogger}
+			backupHandler.Maintenance = &Maintenance{Running: []*RunningBackups{backupHandler.Running}, Logger: testLogger}
+		})
+
+		It("refuses backups with 503, the reason and when to retry", func() {
+			until := time.Now().Add(time.Hour)
+			backupHandler.Maintenance.Enter(MaintenanceState{Reason: "upgrading MySQL", Until: &until})
+
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			res := fakeResponseWriter.Result()
+			Expect(res.StatusCode).To(Equal(http.StatusServiceUnavailable))
+			Expect(res.Header.Get("Retry-After")).To(Equal("3600"))
+			body, err := io.ReadAll(res.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`"error":"the backup tool is in maintenance: upgrading MySQL, expected to end at `))
+			Expect(string(body)).To(ContainSubstring(`"reason":"upgrading MySQL"`))
+			Expect(fakeBackupWriter.idArg).To(BeEmpty())
+			Expect(fakeHistory.records).To(BeEmpty())
+		})
+
+		It("cancels a running backup when maintenance asks for it", func() {
+			fakeBackupWriter.started = make(chan struct{})
+			fakeBackupWriter.process = &stubProcess{}
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				backupHandler.ServeHTTP(fakeResponseWriter, request)
+			}()
+
+			Eventually(fakeBackupWriter.started).Should(BeClosed())
+			backupHandler.Maintenance.Enter(MaintenanceState{Reason: "repairing the cluster", InFlight: InFlightCancel})
+			Eventually(done).Should(BeClosed())
+
+			Expect(fakeResponseWriter.Result().Trailer.Get(TrailerKey)).To(Equal("CANCELLED: backup cancelled for maintenance"))
+			Expect(fakeHistory.records[0].Outcome).To(Equal(history.Cancelled))
+		})
+	})
+
+	When("backups are uploaded to object storage", func() {
+		var (
+			uploader *stubUploader
+			uploads  *Uploads
+		)
+
+		BeforeEach(func() {
+			uploader = &stubUploader{}
+			uploads = &Uploads{}
+			backupHandler.Uploader = uploader
+			backupHandler.Uploads = uploads
+
+			request, err = http.NewRequest("GET", "/backup?format=xbstream", nil)
+			Expect(err).NotTo(HaveOccurred())
+			fakeBackupWriter.content = "some-data"
+		})
+
+		finishedUpload := func(id string) history.Record {
+			var upload history.Record
+			Eventually(func() history.Outcome {
+				upload, _ = uploads.Get(id)
+				return upload.Outcome
+			}).ShouldNot(Equal(UploadRunning))
+			return upload
+		}
+
+		It("accepts the request and returns the upload job", func() {
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			res := fakeResponseWriter.Result()
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			backupID := res.Header.Get(BackupIDHeader)
+			Expect(backupID).NotTo(BeEmpty())
+
+			var job history.Record
+			Expect(json.NewDecoder(res.Body).Decode(&job)).To(Succeed())
+			Expect(job.ID).To(Equal(backupID))
+			Expect(job.Outcome).To(Equal(UploadRunning))
+			Expect(job.Options).To(HaveKeyWithValue("format", "xbstream"))
+		})
+
+		It("uploads the backup and records where it was stored", func() {
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+			backupID := fakeResponseWriter.Result().Header.Get(BackupIDHeader)
+
+			upload := finishedUpload(backupID)
+			Expect(upload.Outcome).To(Equal(history.Succeeded))
+			Expect(upload.Bytes).To(BeEquivalentTo(len("some-data")))
+			Expect(upload.Metadata).To(HaveKeyWithValue("location", "s3://backups/"+backupID))
+			Expect(uploader.content.String()).To(Equal("some-data"))
+			Expect(uploader.completed).To(BeTrue())
+			Expect(uploader.aborted).To(BeFalse())
+
+			Expect(fakeHistory.records).To(HaveLen(1))
+			Expect(fakeHistory.records[0].Metadata).To(HaveKeyWithValue("location", "s3://backups/"+backupID))
+		})
+
+		It("removes instead of completing the upload of a failed backup", func() {
+			fakeBackupWriter.err = errors.New("some-error")
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			upload := finishedUpload(fakeResponseWriter.Result().Header.Get(BackupIDHeader))
+			Expect(upload.Outcome).To(Equal(history.Failed))
+			Expect(upload.Error).To(Equal("some-error"))
+			Expect(uploader.completed).To(BeFalse())
+			Expect(uploader.aborted).To(BeTrue())
+		})
+
+		It("fails the backup when the upload cannot be completed", func() {
+			uploader.completeErr = errors.New("storing object manifest.json failed")
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			upload := finishedUpload(fakeResponseWriter.Result().Header.Get(BackupIDHeader))
+			Expect(upload.Outcome).To(Equal(history.Failed))
+			Expect(upload.Error).To(Equal("storing object manifest.json failed"))
+			Expect(uploader.aborted).To(BeTrue())
+		})
+
+		It("returns an error when the upload cannot be started", func() {
+			uploader.uploadErr = errors.New("invalid key")
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(fakeResponseWriter.Code).To(Equal(http.StatusInternalServerError))
+			Expect(fakeBackupWriter.callCount).To(BeZero())
+		})
+	})
+
 	When("the `format` parameter is NOT specified", func() {
 		It("sets the Content-Type header to tar by default", func() {
 			request, err = http.NewRequest("GET", "/backups", nil)
@@ -126,6 +560,67 @@ var _ = Describe("BackupHandler", func() {
 		})
 	})
 
+	When("tracing is enabled", func() {
+		var spans *spanRecorder
+
+		BeforeEach(func() {
+			spans = &spanRecorder{}
+			backupHandler.Tracer = &tracing.Tracer{Service: "streaming-mysql-backup-tool", Exporter: spans}
+			backupHandler.Instance = "mysql-2"
+		})
+
+		It("continues the trace of the client", func() {
+			request, err = http.NewRequest("GET", "/backup?format=xbstream", nil)
+			Expect(err).NotTo(HaveOccurred())
+			request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			fakeBackupWriter.content = "some-data"
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(spans.names()).To(Equal([]string{"take backup", "record history", "backup"}))
+			server := spans.named("backup")
+			Expect(server.Kind).To(Equal(tracing.KindServer))
+			Expect(server.SpanContext.TraceID.String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(server.Parent.String()).To(Equal("00f067aa0ba902b7"))
+			Expect(server.Attributes).To(SatisfyAll(
+				HaveKeyWithValue("backup.id", fakeResponseWriter.Result().Header.Get(BackupIDHeader)),
+				HaveKeyWithValue("backup.format", "xbstream"),
+				HaveKeyWithValue("backup.instance", "mysql-2"),
+			))
+
+			backup := spans.named("take backup")
+			Expect(backup.Parent).To(Equal(server.SpanContext.SpanID))
+			Expect(backup.Attributes).To(SatisfyAll(
+				HaveKeyWithValue("backup.outcome", "SUCCEEDED"),
+				HaveKeyWithValue("backup.bytes", BeEquivalentTo(len("some-data"))),
+			))
+		})
+
+		It("marks failed backups", func() {
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+			fakeBackupWriter.err = errors.New("some-error")
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Expect(spans.named("take backup").Error).To(Equal("some-error"))
+			Expect(spans.named("backup").Error).To(Equal("some-error"))
+		})
+
+		It("traces backups uploaded to object storage after the request", func() {
+			backupHandler.Uploader = &stubUploader{}
+			backupHandler.Uploads = &Uploads{}
+			request, err = http.NewRequest("GET", "/backup", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			backupHandler.ServeHTTP(fakeResponseWriter, request)
+
+			Eventually(spans.names).Should(ConsistOf("backup", "take backup", "complete upload", "record history"))
+			server := spans.named("backup")
+			Expect(spans.named("complete upload").SpanContext.TraceID).To(Equal(server.SpanContext.TraceID))
+		})
+	})
+
 	When("the backup fails halfway through", func() {
 		It("has HTTP 200 status code but writes the error to the trailer", func() {
 			request, err = http.NewRequest("GET", "/backups", nil)
@@ -146,18 +641,198 @@ var _ = Describe("BackupHandler", func() {
 	})
 })
 
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func (r *spanRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, span := range r.spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func (r *spanRecorder) named(name string) tracing.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	Fail("no span named " + name)
+	return tracing.SpanData{}
+}
+
 type stubBackupWriter struct {
-	callCount int
-	formatArg string
-	content   string
-	err       error
+	callCount      int
+	formatArg      string
+	idArg          string
+	historyNameArg string
+	content        string
+	log            string
+	metadata       map[string]string
+	err            error
+	// started, when set, is closed once the backup blocks on its context.
+	started chan struct{}
+	process *stubProcess
 }
 
-func (f *stubBackupWriter) StreamTo(format string, w io.Writer) error {
+func (f *stubBackupWriter) StreamTo(ctx context.Context, req BackupRequest, w io.Writer) error {
 	f.callCount++
-	f.formatArg = format
+	f.formatArg = req.Format
+	f.idArg = req.ID
+	f.historyNameArg = req.HistoryName
+	_, _ = io.WriteString(req.Log, f.log)
+	for key, value := range f.metadata {
+		req.AddMetadata(key, value)
+	}
 	_, _ = w.Write([]byte(f.content))
+	if f.started != nil {
+		if req.Started != nil {
+			req.Started(f.process)
+		}
+		close(f.started)
+		<-ctx.Done()
+		return errors.New("signal: killed")
+	}
 	return f.err
 }
 
+type stubUploader struct {
+	uploadErr   error
+	completeErr error
+	content     bytes.Buffer
+	completed   bool
+	aborted     bool
+	id          string
+}
+
+func (u *stubUploader) Upload(_ context.Context, id, _ string) (Upload, error) {
+	u.id = id
+	return u, u.uploadErr
+}
+
+func (u *stubUploader) Write(p []byte) (int, error) {
+	return u.content.Write(p)
+}
+
+func (u *stubUploader) Complete() (string, error) {
+	u.completed = true
+	return "s3://backups/" + u.id, u.completeErr
+}
+
+func (u *stubUploader) Abort() error {
+	u.aborted = true
+	return nil
+}
+
+type stubProcess struct {
+	mu        sync.Mutex
+	suspended bool
+	err       error
+}
+
+func (p *stubProcess) Suspend() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.suspended = true
+	return nil
+}
+
+func (p *stubProcess) Resume() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.suspended = false
+	return nil
+}
+
+func (p *stubProcess) Suspended() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.suspended
+}
+
+type stubBackupLogStore struct {
+	logs      map[string]*bytes.Buffer
+	createErr error
+}
+
+func (s *stubBackupLogStore) Create(id string) (io.WriteCloser, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	if s.logs == nil {
+		s.logs = map[string]*bytes.Buffer{}
+	}
+	s.logs[id] = &bytes.Buffer{}
+	return nopCloser{s.logs[id]}, nil
+}
+
+func (s *stubBackupLogStore) Open(id string) (io.ReadCloser, error) {
+	buf, ok := s.logs[id]
+	if !ok {
+		return nil, backuplog.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+}
+
+type stubBackupHistory struct {
+	records   []history.Record
+	appendErr error
+	err       error
+}
+
+func (s *stubBackupHistory) Append(r history.Record) error {
+	if s.appendErr != nil {
+		return s.appendErr
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *stubBackupHistory) List(limit int) ([]history.Record, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	records := s.records
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (s *stubBackupHistory) Get(id string) (history.Record, error) {
+	if s.err != nil {
+		return history.Record{}, s.err
+	}
+	for _, r := range s.records {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return history.Record{}, history.ErrNotFound
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
 var _ BackupWriter = &stubBackupWriter{}
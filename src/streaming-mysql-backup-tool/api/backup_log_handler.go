@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/backuplog"
+)
+
+type BackupLogHandler struct {
+	BackupLogs BackupLogStore
+	Logger     lager.Logger
+}
+
+func (h *BackupLogHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := backupIDFromPath(req.URL.Path, "log")
+	if !ok || h.BackupLogs == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	backupLog, err := h.BackupLogs.Open(id)
+	if errors.Is(err, backuplog.ErrNotFound) {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("opening backup log failed", err, lager.Data{"backup_id": id})
+		http.Error(w, "failed to open backup log", http.StatusInternalServerError)
+		return
+	}
+	defer backupLog.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := io.Copy(w, backupLog); err != nil {
+		h.Logger.Error("sending backup log failed", err, lager.Data{"backup_id": id})
+	}
+}
+
+// backupIDFromPath extracts {id} from a path of the form /backups/{id}/{resource}
+func backupIDFromPath(path, resource string) (string, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "backups" || parts[1] == "" || parts[2] != resource {
+		return "", false
+	}
+
+	return parts[1], true
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("BackupLogHandler", func() {
+	var (
+		backupLogs *stubBackupLogStore
+		handler    *BackupLogHandler
+		recorder   *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		backupLogs = &stubBackupLogStore{}
+		w, err := backupLogs.Create("some-id")
+		Expect(err).NotTo(HaveOccurred())
+		_, _ = io.WriteString(w, "xtrabackup output")
+
+		handler = &BackupLogHandler{
+			BackupLogs: backupLogs,
+			Logger:     lagertest.NewTestLogger("backup-log-handler"),
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	It("returns the log of the requested backup", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/log", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(recorder.Body.String()).To(Equal("xtrabackup output"))
+	})
+
+	It("returns 404 for unknown backups", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/other-id/log", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns 404 for other paths", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/log/extra", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("only allows GET requests", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/backups/some-id/log", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	When("the log cannot be opened", func() {
+		It("returns 500", func() {
+			handler.BackupLogs = erroringBackupLogStore{}
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/log", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+})
+
+type erroringBackupLogStore struct{}
+
+func (erroringBackupLogStore) Create(string) (io.WriteCloser, error) {
+	return nil, errors.New("create failed")
+}
+
+func (erroringBackupLogStore) Open(string) (io.ReadCloser, error) {
+	return nil, errors.New("permission denied")
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Resources serves /backups/{id}/{resource} by resource name.
+type BackupsRouter struct {
+	Collection http.Handler
+	Resources  map[string]http.Handler
+}
+
+func (r BackupsRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) == 0 || parts[0] != "backups" {
+		http.NotFound(w, req)
+		return
+	}
+
+	if len(parts) <= 2 {
+		if r.Collection == nil {
+			http.NotFound(w, req)
+			return
+		}
+		r.Collection.ServeHTTP(w, req)
+		return
+	}
+
+	handler, ok := r.Resources[parts[2]]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("BackupsRouter", func() {
+	var router BackupsRouter
+
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, name)
+		})
+	}
+
+	BeforeEach(func() {
+		router = BackupsRouter{
+			Collection: named("collection"),
+			Resources:  map[string]http.Handler{"log": named("log")},
+		}
+	})
+
+	DescribeTable("dispatches by path",
+		func(path string, expected string) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+			Expect(recorder.Body.String()).To(Equal(expected))
+		},
+		Entry("the collection", "/backups", "collection"),
+		Entry("a single backup", "/backups/some-id", "collection"),
+		Entry("a backup resource", "/backups/some-id/log", "log"),
+	)
+
+	It("returns 404 for unknown resources", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/unknown", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type ControlHandler struct {
+	Running *RunningBackups
+	Logger  lager.Logger
+}
+
+type controlResponse struct {
+	ID       string     `json:"id"`
+	State    string     `json:"state"`
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+}
+
+func (h *ControlHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "backups" || parts[1] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	id, action := parts[1], parts[2]
+
+	logger := h.Logger.WithData(lager.Data{"backup_id": id, "action": action, "requester": requester(req)})
+
+	var (
+		resp = controlResponse{ID: id}
+		err  error
+	)
+	switch action {
+	case "cancel":
+		err = h.Running.Cancel(id)
+		resp.State = "cancelling"
+	case "pause":
+		var d time.Duration
+		if v := req.URL.Query().Get("duration"); v != "" {
+			if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid duration '"+v+"' requested")
+				return
+			}
+		}
+		var resumeAt time.Time
+		resumeAt, err = h.Running.Pause(id, d)
+		resp.State = "paused"
+		resp.ResumeAt = &resumeAt
+	case "resume":
+		err = h.Running.Resume(id)
+		resp.State = "running"
+	default:
+		http.NotFound(w, req)
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrBackupNotRunning):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrAlreadyPaused), errors.Is(err, ErrNotPaused), errors.Is(err, ErrNotPausable):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		logger.Error("backup control failed", err)
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+	default:
+		logger.Info("backup control succeeded", lager.Data{"state": resp.State})
+		writeJSON(w, resp)
+	}
+}
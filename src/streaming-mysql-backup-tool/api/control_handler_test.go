@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("ControlHandler", func() {
+	var (
+		running  *RunningBackups
+		process  *stubProcess
+		handler  *ControlHandler
+		recorder *httptest.ResponseRecorder
+		ctx      context.Context
+		done     func()
+	)
+
+	BeforeEach(func() {
+		running = &RunningBackups{
+			MaxPause: time.Hour,
+			Logger:   lagertest.NewTestLogger("running-backups"),
+		}
+		process = &stubProcess{}
+		ctx, done = running.Start(context.Background(), "some-id")
+		running.Attach("some-id", process)
+
+		handler = &ControlHandler{
+			Running: running,
+			Logger:  lagertest.NewTestLogger("control-handler"),
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	AfterEach(func() {
+		done()
+	})
+
+	It("cancels a running backup", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/cancel", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "some-id", "state": "cancelling"}`))
+		Expect(context.Cause(ctx)).To(MatchError(ErrCancelled))
+	})
+
+	It("pauses and resumes a running backup", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/pause?duration=5m", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"state":"paused"`))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"resume_at":`))
+		Expect(process.Suspended()).To(BeTrue())
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/resume", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"id": "some-id", "state": "running"}`))
+		Expect(process.Suspended()).To(BeFalse())
+	})
+
+	It("rejects an invalid pause duration", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/pause?duration=forever", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		Expect(process.Suspended()).To(BeFalse())
+	})
+
+	It("returns 409 when resuming a backup that is not paused", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/resume", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusConflict))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"error": "backup is not paused"}`))
+	})
+
+	It("returns 404 for backups that are not running", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/other-id/cancel", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("only allows POST requests", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/cancel", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(ctx.Err()).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+)
+
+// An empty Instance is the default instance.
+type HistoryHandler struct {
+	History  BackupHistory
+	Instance string
+	Logger   lager.Logger
+}
+
+func (h *HistoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.History == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "backups":
+		h.list(w, req)
+	case len(parts) == 2 && parts[0] == "backups" && parts[1] != "":
+		h.show(w, req, parts[1])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (h *HistoryHandler) list(w http.ResponseWriter, req *http.Request) {
+	var limit int
+	if l := req.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid limit '"+l+"' requested")
+			return
+		}
+	}
+
+	all, err := h.History.List(0)
+	if err != nil {
+		h.Logger.Error("reading backup history failed", err)
+		http.Error(w, "failed to read backup history", http.StatusInternalServerError)
+		return
+	}
+	records := []history.Record{}
+	for _, record := range all {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		if record.Options["instance"] == h.Instance {
+			records = append(records, record)
+		}
+	}
+
+	writeJSON(w, records)
+}
+
+func (h *HistoryHandler) show(w http.ResponseWriter, req *http.Request, id string) {
+	record, err := h.History.Get(id)
+	if errors.Is(err, history.ErrNotFound) || err == nil && record.Options["instance"] != h.Instance {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		h.Logger.Error("reading backup history failed", err, lager.Data{"backup_id": id})
+		http.Error(w, "failed to read backup history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, record)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+)
+
+var _ = Describe("HistoryHandler", func() {
+	var (
+		backupHistory *stubBackupHistory
+		handler       *HistoryHandler
+		recorder      *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		startedAt := time.Date(2024, 4, 22, 18, 0, 0, 0, time.UTC)
+		backupHistory = &stubBackupHistory{records: []history.Record{
+			{ID: "newer-id", StartedAt: startedAt.Add(time.Hour), Outcome: history.Failed, Error: "FATAL: some-error"},
+			{ID: "older-id", Requester: "admin", StartedAt: startedAt, Bytes: 1024, FromLSN: 100, ToLSN: 200, Outcome: history.Succeeded},
+		}}
+
+		handler = &HistoryHandler{
+			History: backupHistory,
+			Logger:  lagertest.NewTestLogger("history-handler"),
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	It("lists the recorded backups", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON(`[
+			{"id": "newer-id", "started_at": "2024-04-22T19:00:00Z", "finished_at": "0001-01-01T00:00:00Z", "bytes": 0, "outcome": "FAILED", "error": "FATAL: some-error"},
+			{"id": "older-id", "requester": "admin", "started_at": "2024-04-22T18:00:00Z", "finished_at": "0001-01-01T00:00:00Z", "bytes": 1024, "from_lsn": 100, "to_lsn": 200, "outcome": "SUCCEEDED"}
+		]`))
+	})
+
+	It("limits the number of backups listed", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups?limit=1", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("newer-id"))
+		Expect(recorder.Body.String()).NotTo(ContainSubstring("older-id"))
+	})
+
+	It("rejects an invalid limit", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups?limit=-1", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns an empty list before any backup was taken", func() {
+		backupHistory.records = nil
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups", nil))
+
+		Expect(recorder.Body.String()).To(MatchJSON(`[]`))
+	})
+
+	It("returns a single backup", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/older-id", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"id":"older-id"`))
+	})
+
+	It("returns 404 for unknown backups", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/unknown-id", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	When("other instances have backups", func() {
+		BeforeEach(func() {
+			backupHistory.records = append([]history.Record{
+				{ID: "instance-id", Options: map[string]string{"instance": "mysql-2"}, StartedAt: time.Date(2024, 4, 22, 20, 0, 0, 0, time.UTC), Outcome: history.Succeeded},
+			}, backupHistory.records...)
+		})
+
+		It("lists only the backups of its instance", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups?limit=1", nil))
+
+			Expect(recorder.Body.String()).To(ContainSubstring("newer-id"))
+			Expect(recorder.Body.String()).NotTo(ContainSubstring("instance-id"))
+
+			handler.Instance = "mysql-2"
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups", nil))
+
+			Expect(recorder.Body.String()).To(ContainSubstring("instance-id"))
+			Expect(recorder.Body.String()).NotTo(ContainSubstring("newer-id"))
+		})
+
+		It("returns 404 for backups of other instances", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/instance-id", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	It("only allows GET requests", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	When("the history cannot be read", func() {
+		It("returns 500", func() {
+			backupHistory.err = errors.New("permission denied")
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	When("no history is configured", func() {
+		It("returns 404", func() {
+			handler.History = nil
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
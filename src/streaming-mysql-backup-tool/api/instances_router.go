@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// InstancesRouter serves /instances/{name}/... with the prefix stripped.
+type InstancesRouter struct {
+	Instances map[string]http.Handler
+}
+
+func (r InstancesRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.SplitN(strings.Trim(req.URL.Path, "/"), "/", 3)
+	if len(parts) < 3 || parts[0] != "instances" {
+		http.NotFound(w, req)
+		return
+	}
+
+	handler, ok := r.Instances[parts[1]]
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown instance '"+parts[1]+"'")
+		return
+	}
+
+	http.StripPrefix("/instances/"+parts[1], handler).ServeHTTP(w, req)
+}
@@ -0,0 +1,75 @@
+package api_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("InstancesRouter", func() {
+	var router InstancesRouter
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/backup", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "mysql-2 backup")
+		})
+		mux.Handle("/backups/", BackupsRouter{
+			Collection: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, _ = io.WriteString(w, "history at "+req.URL.Path)
+			}),
+			Resources: map[string]http.Handler{
+				"log": http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					_, _ = io.WriteString(w, "log at "+req.URL.Path)
+				}),
+			},
+		})
+		router = InstancesRouter{
+			Instances: map[string]http.Handler{"mysql-2": mux},
+		}
+	})
+
+	It("dispatches to the handler of the instance", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/instances/mysql-2/backup?format=xbstream", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("mysql-2 backup"))
+	})
+
+	It("dispatches the resources of the backups of the instance", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/instances/mysql-2/backups/some-id/log", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("log at /backups/some-id/log"))
+	})
+
+	It("dispatches the history of the backups of the instance", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/instances/mysql-2/backups/some-id", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("history at /backups/some-id"))
+	})
+
+	It("returns 404 for unknown instances", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/instances/mysql-3/backup", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"error": "unknown instance 'mysql-3'"}`))
+	})
+
+	It("returns 404 without a resource", func() {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/instances/mysql-2", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	InFlightFinish = "finish"
+	InFlightCancel = "cancel"
+)
+
+type MaintenanceState struct {
+	Reason   string     `json:"reason"`
+	Since    time.Time  `json:"since"`
+	Until    *time.Time `json:"until,omitempty"`
+	InFlight string     `json:"in_flight"`
+	Source   string     `json:"source"`
+}
+
+// Maintenance is held by /maintenance or by SentinelFile, each on its own.
+type Maintenance struct {
+	SentinelFile string
+	InFlight     string
+	Running      []*RunningBackups
+	Logger       lager.Logger
+
+	mu       sync.Mutex
+	entered  *MaintenanceState
+	sentinel *MaintenanceState
+}
+
+// State returns nil when the tool is not in maintenance.
+func (m *Maintenance) State() *MaintenanceState {
+	m.mu.Lock()
+	entered := m.entered
+	m.mu.Unlock()
+	if entered != nil {
+		return entered
+	}
+
+	state, err := m.readSentinel()
+	if err != nil {
+		m.Logger.Error("reading the maintenance sentinel file failed", err, lager.Data{"sentinel_file": m.SentinelFile})
+	}
+	return state
+}
+
+func (m *Maintenance) Enter(state MaintenanceState) {
+	if state.InFlight == "" {
+		state.InFlight = m.inFlight()
+	}
+	state.Source = "api"
+
+	m.mu.Lock()
+	m.entered = &state
+	m.mu.Unlock()
+
+	m.started(state)
+}
+
+// Leave does not end maintenance held by the sentinel file.
+func (m *Maintenance) Leave() {
+	m.mu.Lock()
+	entered := m.entered
+	m.entered = nil
+	m.mu.Unlock()
+
+	if entered != nil {
+		m.Logger.Info("left maintenance", lager.Data{"source": entered.Source})
+	}
+}
+
+func (m *Maintenance) Watch(ctx context.Context, interval time.Duration) {
+	if m.SentinelFile == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := m.readSentinel()
+		if err != nil {
+			m.Logger.Error("reading the maintenance sentinel file failed", err, lager.Data{"sentinel_file": m.SentinelFile})
+		}
+
+		m.mu.Lock()
+		appeared := state != nil && m.sentinel == nil
+		if m.sentinel != nil && state == nil {
+			m.Logger.Info("left maintenance", lager.Data{"source": "sentinel"})
+		}
+		m.sentinel = state
+		m.mu.Unlock()
+
+		if appeared {
+			m.started(*state)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Maintenance) started(state MaintenanceState) {
+	data := lager.Data{"reason": state.Reason, "in_flight": state.InFlight, "source": state.Source}
+	if state.Until != nil {
+		data["until"] = state.Until.Format(time.RFC3339)
+	}
+
+	if state.InFlight == InFlightCancel {
+		cancelled := 0
+		for _, running := range m.Running {
+			cancelled += running.CancelAll(ErrMaintenance)
+		}
+		data["cancelled"] = cancelled
+	}
+	m.Logger.Info("entered maintenance", data)
+}
+
+func (m *Maintenance) inFlight() string {
+	if m.InFlight == "" {
+		return InFlightFinish
+	}
+	return m.InFlight
+}
+
+func (m *Maintenance) readSentinel() (*MaintenanceState, error) {
+	if m.SentinelFile == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(m.SentinelFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(m.SentinelFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state MaintenanceState
+	trimmed := strings.TrimSpace(string(contents))
+	if !strings.HasPrefix(trimmed, "{") || json.Unmarshal(contents, &state) != nil {
+		state = MaintenanceState{Reason: trimmed}
+	}
+	if state.Reason == "" {
+		state.Reason = "maintenance sentinel file " + m.SentinelFile + " exists"
+	}
+	if state.InFlight != InFlightFinish && state.InFlight != InFlightCancel {
+		state.InFlight = m.inFlight()
+	}
+	state.Since = info.ModTime()
+	state.Source = "sentinel"
+	return &state, nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type MaintenanceHandler struct {
+	Maintenance *Maintenance
+	Logger      lager.Logger
+}
+
+type maintenanceRequest struct {
+	Reason   string     `json:"reason"`
+	Until    *time.Time `json:"until"`
+	Duration string     `json:"duration"`
+	InFlight string     `json:"in_flight"`
+}
+
+type maintenanceResponse struct {
+	Maintenance bool `json:"maintenance"`
+	*MaintenanceState
+}
+
+func (h *MaintenanceHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.Logger.WithData(lager.Data{"method": req.Method, "requester": requester(req)})
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body maintenanceRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid maintenance request: "+err.Error())
+			return
+		}
+
+		state := MaintenanceState{Reason: body.Reason, Since: time.Now(), Until: body.Until, InFlight: body.InFlight}
+		switch {
+		case body.Reason == "":
+			writeJSONError(w, http.StatusBadRequest, "a reason for the maintenance is required")
+			return
+		case body.InFlight != "" && body.InFlight != InFlightFinish && body.InFlight != InFlightCancel:
+			writeJSONError(w, http.StatusBadRequest, "invalid in_flight '"+body.InFlight+"', must be 'finish' or 'cancel'")
+			return
+		case body.Duration != "" && body.Until != nil:
+			writeJSONError(w, http.StatusBadRequest, "until and duration are mutually exclusive")
+			return
+		case body.Duration != "":
+			d, err := time.ParseDuration(body.Duration)
+			if err != nil || d <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "invalid duration '"+body.Duration+"' requested")
+				return
+			}
+			until := state.Since.Add(d)
+			state.Until = &until
+		}
+
+		h.Maintenance.Enter(state)
+		logger.Info("maintenance entered through the api", lager.Data{"reason": state.Reason})
+	case http.MethodDelete:
+		h.Maintenance.Leave()
+		logger.Info("maintenance left through the api")
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := h.Maintenance.State()
+	writeJSON(w, maintenanceResponse{Maintenance: state != nil, MaintenanceState: state})
+}
+
+func writeMaintenance(w http.ResponseWriter, state *MaintenanceState) {
+	message := "the backup tool is in maintenance: " + state.Reason
+	if state.Until != nil {
+		message += ", expected to end at " + state.Until.UTC().Format(time.RFC3339)
+		if retry := time.Until(*state.Until); retry > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry.Round(time.Second)/time.Second)))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		maintenanceResponse
+	}{message, maintenanceResponse{Maintenance: true, MaintenanceState: state}})
+}
@@ -0,0 +1,192 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("Maintenance", func() {
+	var (
+		running     *RunningBackups
+		maintenance *Maintenance
+		sentinel    string
+		ctx         context.Context
+		done        func()
+	)
+
+	BeforeEach(func() {
+		running = &RunningBackups{
+			MaxPause: time.Hour,
+			Logger:   lagertest.NewTestLogger("running-backups"),
+		}
+		ctx, done = running.Start(context.Background(), "some-id")
+
+		sentinel = filepath.Join(GinkgoT().TempDir(), "maintenance")
+		maintenance = &Maintenance{
+			SentinelFile: sentinel,
+			InFlight:     InFlightFinish,
+			Running:      []*RunningBackups{running},
+			Logger:       lagertest.NewTestLogger("maintenance"),
+		}
+	})
+
+	AfterEach(func() {
+		done()
+	})
+
+	It("is not in maintenance by default", func() {
+		Expect(maintenance.State()).To(BeNil())
+	})
+
+	It("lets running backups finish by default", func() {
+		maintenance.Enter(MaintenanceState{Reason: "upgrading MySQL"})
+
+		Expect(maintenance.State()).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"Reason":   Equal("upgrading MySQL"),
+			"InFlight": Equal(InFlightFinish),
+			"Source":   Equal("api"),
+		})))
+		Expect(ctx.Done()).NotTo(BeClosed())
+
+		maintenance.Leave()
+		Expect(maintenance.State()).To(BeNil())
+	})
+
+	It("cancels running backups when asked to", func() {
+		maintenance.Enter(MaintenanceState{Reason: "repairing the cluster", InFlight: InFlightCancel})
+
+		Expect(context.Cause(ctx)).To(MatchError(ErrMaintenance))
+	})
+
+	It("cancels the running backups of every instance", func() {
+		instance := &RunningBackups{Logger: lagertest.NewTestLogger("running-backups")}
+		instanceCtx, instanceDone := instance.Start(context.Background(), "instance-id")
+		defer instanceDone()
+		maintenance.Running = append(maintenance.Running, instance)
+
+		maintenance.Enter(MaintenanceState{Reason: "repairing the cluster", InFlight: InFlightCancel})
+
+		Expect(context.Cause(ctx)).To(MatchError(ErrMaintenance))
+		Expect(context.Cause(instanceCtx)).To(MatchError(ErrMaintenance))
+	})
+
+	Describe("the sentinel file", func() {
+		It("puts the tool into maintenance while it exists", func() {
+			Expect(os.WriteFile(sentinel, []byte("upgrading MySQL\n"), 0644)).To(Succeed())
+
+			Expect(maintenance.State()).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Reason": Equal("upgrading MySQL"),
+				"Source": Equal("sentinel"),
+			})))
+
+			maintenance.Leave()
+			Expect(maintenance.State()).NotTo(BeNil())
+
+			Expect(os.Remove(sentinel)).To(Succeed())
+			Expect(maintenance.State()).To(BeNil())
+		})
+
+		It("may describe the maintenance as JSON", func() {
+			Expect(os.WriteFile(sentinel, []byte(`{"reason": "upgrading MySQL", "until": "2030-01-02T03:04:05Z", "in_flight": "cancel"}`), 0644)).To(Succeed())
+
+			Expect(maintenance.State()).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Reason":   Equal("upgrading MySQL"),
+				"Until":    PointTo(BeTemporally("==", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))),
+				"InFlight": Equal(InFlightCancel),
+			})))
+		})
+
+		It("gives a reason when it is empty", func() {
+			Expect(os.WriteFile(sentinel, nil, 0644)).To(Succeed())
+
+			Expect(maintenance.State().Reason).To(Equal("maintenance sentinel file " + sentinel + " exists"))
+		})
+
+		It("cancels running backups once it appears, if it says so", func() {
+			watchCtx, stop := context.WithCancel(context.Background())
+			defer stop()
+			go maintenance.Watch(watchCtx, 10*time.Millisecond)
+
+			Consistently(ctx.Done()).ShouldNot(BeClosed())
+
+			Expect(os.WriteFile(sentinel, []byte(`{"reason": "repairing the cluster", "in_flight": "cancel"}`), 0644)).To(Succeed())
+
+			Eventually(ctx.Done()).Should(BeClosed())
+			Expect(context.Cause(ctx)).To(MatchError(ErrMaintenance))
+		})
+	})
+})
+
+var _ = Describe("MaintenanceHandler", func() {
+	var (
+		maintenance *Maintenance
+		handler     *MaintenanceHandler
+		recorder    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		maintenance = &Maintenance{
+			Running: []*RunningBackups{{Logger: lagertest.NewTestLogger("running-backups")}},
+			Logger:  lagertest.NewTestLogger("maintenance"),
+		}
+		handler = &MaintenanceHandler{
+			Maintenance: maintenance,
+			Logger:      lagertest.NewTestLogger("maintenance-handler"),
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	It("reports that the tool is not in maintenance", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/maintenance", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"maintenance": false}`))
+	})
+
+	It("enters and leaves maintenance", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("PUT", "/maintenance", strings.NewReader(`{"reason": "upgrading MySQL", "duration": "2h"}`)))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"maintenance":true`))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"reason":"upgrading MySQL"`))
+		Expect(maintenance.State().Until).To(PointTo(BeTemporally("~", time.Now().Add(2*time.Hour), time.Minute)))
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/maintenance", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(MatchJSON(`{"maintenance": false}`))
+	})
+
+	DescribeTable("rejects invalid requests",
+		func(body, message string) {
+			handler.ServeHTTP(recorder, httptest.NewRequest("PUT", "/maintenance", strings.NewReader(body)))
+
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			Expect(recorder.Body.String()).To(ContainSubstring(message))
+			Expect(maintenance.State()).To(BeNil())
+		},
+		Entry("without a reason", `{}`, "a reason for the maintenance is required"),
+		Entry("with an unknown in_flight", `{"reason": "r", "in_flight": "pause"}`, "invalid in_flight 'pause'"),
+		Entry("with an invalid duration", `{"reason": "r", "duration": "forever"}`, "invalid duration 'forever'"),
+		Entry("with both until and duration", `{"reason": "r", "duration": "1h", "until": "2030-01-02T03:04:05Z"}`, "mutually exclusive"),
+	)
+
+	It("only allows GET, PUT and DELETE", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/maintenance", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
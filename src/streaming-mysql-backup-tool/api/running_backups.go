@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+var (
+	ErrBackupNotRunning = errors.New("backup is not running")
+	ErrAlreadyPaused    = errors.New("backup is already paused")
+	ErrNotPaused        = errors.New("backup is not paused")
+	ErrNotPausable      = errors.New("backup has no process to pause yet")
+
+	ErrCancelled   = errors.New("CANCELLED: backup cancelled by operator")
+	ErrMaintenance = errors.New("CANCELLED: backup cancelled for maintenance")
+)
+
+// Process is the running backup process, e.g. the xtrabackup process group.
+type Process interface {
+	Suspend() error
+	Resume() error
+}
+
+// A paused backup is resumed after MaxPause, since xtrabackup cannot copy
+// redo log while it is suspended.
+type RunningBackups struct {
+	MaxPause time.Duration
+	Logger   lager.Logger
+
+	mu      sync.Mutex
+	backups map[string]*runningBackup
+}
+
+type runningBackup struct {
+	cancel      context.CancelCauseFunc
+	process     Process
+	pausedUntil time.Time
+	resumeTimer *time.Timer
+}
+
+func (r *RunningBackups) Start(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	if r.backups == nil {
+		r.backups = map[string]*runningBackup{}
+	}
+	r.backups[id] = &runningBackup{cancel: cancel}
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		if b, ok := r.backups[id]; ok && b.resumeTimer != nil {
+			b.resumeTimer.Stop()
+		}
+		delete(r.backups, id)
+		r.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// Attach records the process of a backup once it has been started.
+func (r *RunningBackups) Attach(id string, p Process) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if b, ok := r.backups[id]; ok {
+		b.process = p
+	}
+}
+
+func (r *RunningBackups) Cancel(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.backups[id]
+	if !ok {
+		return ErrBackupNotRunning
+	}
+
+	b.cancel(ErrCancelled)
+	return nil
+}
+
+// CancelAll returns how many backups it cancelled.
+func (r *RunningBackups) CancelAll(cause error) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.backups {
+		b.cancel(cause)
+	}
+	return len(r.backups)
+}
+
+// Pause returns the time at which the backup will be resumed.
+func (r *RunningBackups) Pause(id string, d time.Duration) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.backups[id]
+	switch {
+	case !ok:
+		return time.Time{}, ErrBackupNotRunning
+	case b.process == nil:
+		return time.Time{}, ErrNotPausable
+	case b.resumeTimer != nil:
+		return time.Time{}, ErrAlreadyPaused
+	}
+
+	if d <= 0 || d > r.MaxPause {
+		d = r.MaxPause
+	}
+
+	if err := b.process.Suspend(); err != nil {
+		return time.Time{}, err
+	}
+
+	b.pausedUntil = time.Now().Add(d)
+	b.resumeTimer = time.AfterFunc(d, func() {
+		r.Logger.Info("maximum pause duration reached, resuming backup", lager.Data{"backup_id": id, "max_pause": d.String()})
+		if err := r.Resume(id); err != nil && !errors.Is(err, ErrBackupNotRunning) && !errors.Is(err, ErrNotPaused) {
+			r.Logger.Error("resuming backup failed", err, lager.Data{"backup_id": id})
+		}
+	})
+
+	return b.pausedUntil, nil
+}
+
+func (r *RunningBackups) Resume(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.backups[id]
+	switch {
+	case !ok:
+		return ErrBackupNotRunning
+	case b.resumeTimer == nil:
+		return ErrNotPaused
+	}
+
+	if err := b.process.Resume(); err != nil {
+		return err
+	}
+
+	b.resumeTimer.Stop()
+	b.resumeTimer = nil
+	b.pausedUntil = time.Time{}
+
+	return nil
+}
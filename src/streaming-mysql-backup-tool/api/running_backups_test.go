@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+var _ = Describe("RunningBackups", func() {
+	var (
+		running *RunningBackups
+		process *stubProcess
+		ctx     context.Context
+		done    func()
+	)
+
+	BeforeEach(func() {
+		running = &RunningBackups{
+			MaxPause: time.Hour,
+			Logger:   lagertest.NewTestLogger("running-backups"),
+		}
+		process = &stubProcess{}
+		ctx, done = running.Start(context.Background(), "some-id")
+	})
+
+	AfterEach(func() {
+		done()
+	})
+
+	It("cancels the context of a backup with ErrCancelled", func() {
+		Expect(running.Cancel("some-id")).To(Succeed())
+
+		Expect(ctx.Done()).To(BeClosed())
+		Expect(context.Cause(ctx)).To(MatchError(ErrCancelled))
+	})
+
+	It("cancels every backup with the given cause", func() {
+		otherCtx, otherDone := running.Start(context.Background(), "other-id")
+		defer otherDone()
+
+		Expect(running.CancelAll(ErrMaintenance)).To(Equal(2))
+
+		Expect(context.Cause(ctx)).To(MatchError(ErrMaintenance))
+		Expect(context.Cause(otherCtx)).To(MatchError(ErrMaintenance))
+	})
+
+	It("forgets backups once they are done", func() {
+		done()
+
+		Expect(running.Cancel("some-id")).To(MatchError(ErrBackupNotRunning))
+		Expect(context.Cause(ctx)).NotTo(MatchError(ErrCancelled))
+	})
+
+	It("returns ErrBackupNotRunning for unknown backups", func() {
+		Expect(running.Cancel("unknown-id")).To(MatchError(ErrBackupNotRunning))
+		_, err := running.Pause("unknown-id", 0)
+		Expect(err).To(MatchError(ErrBackupNotRunning))
+		Expect(running.Resume("unknown-id")).To(MatchError(ErrBackupNotRunning))
+	})
+
+	When("the backup process has been attached", func() {
+		BeforeEach(func() {
+			running.Attach("some-id", process)
+		})
+
+		It("suspends and resumes the process", func() {
+			resumeAt, err := running.Pause("some-id", 10*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resumeAt).To(BeTemporally("~", time.Now().Add(10*time.Minute), time.Minute))
+			Expect(process.Suspended()).To(BeTrue())
+
+			Expect(running.Resume("some-id")).To(Succeed())
+			Expect(process.Suspended()).To(BeFalse())
+		})
+
+		It("caps the pause at MaxPause", func() {
+			resumeAt, err := running.Pause("some-id", 24*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resumeAt).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		})
+
+		It("resumes the process automatically after MaxPause", func() {
+			running.MaxPause = 50 * time.Millisecond
+
+			_, err := running.Pause("some-id", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(process.Suspended()).To(BeTrue())
+
+			Eventually(process.Suspended).Should(BeFalse())
+			Expect(running.Resume("some-id")).To(MatchError(ErrNotPaused))
+		})
+
+		It("refuses to pause a paused backup", func() {
+			_, err := running.Pause("some-id", 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = running.Pause("some-id", 0)
+			Expect(err).To(MatchError(ErrAlreadyPaused))
+		})
+
+		It("refuses to resume a backup that is not paused", func() {
+			Expect(running.Resume("some-id")).To(MatchError(ErrNotPaused))
+		})
+
+		It("returns errors from suspending the process", func() {
+			process.err = errors.New("no such process")
+
+			_, err := running.Pause("some-id", 0)
+			Expect(err).To(MatchError("no such process"))
+			Expect(running.Resume("some-id")).To(MatchError(ErrNotPaused))
+		})
+	})
+
+	When("the backup process has not been attached yet", func() {
+		It("refuses to pause the backup", func() {
+			_, err := running.Pause("some-id", 0)
+			Expect(err).To(MatchError(ErrNotPausable))
+		})
+	})
+})
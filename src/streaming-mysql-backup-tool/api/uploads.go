@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+)
+
+const UploadRunning history.Outcome = "RUNNING"
+
+const defaultMaxFinishedUploads = 100
+
+type Uploads struct {
+	MaxFinished int
+
+	mu       sync.Mutex
+	uploads  map[string]history.Record
+	finished []string
+}
+
+// Put records the current state of an upload.
+func (u *Uploads) Put(r history.Record) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.uploads == nil {
+		u.uploads = map[string]history.Record{}
+	}
+	u.uploads[r.ID] = r
+
+	if r.Outcome == UploadRunning {
+		return
+	}
+	u.finished = append(u.finished, r.ID)
+
+	maxFinished := u.MaxFinished
+	if maxFinished <= 0 {
+		maxFinished = defaultMaxFinishedUploads
+	}
+	for len(u.finished) > maxFinished {
+		delete(u.uploads, u.finished[0])
+		u.finished = u.finished[1:]
+	}
+}
+
+func (u *Uploads) Get(id string) (history.Record, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	r, ok := u.uploads[id]
+	return r, ok
+}
+
+type UploadHandler struct {
+	Uploads *Uploads
+}
+
+func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := backupIDFromPath(req.URL.Path, "upload")
+	if !ok || h.Uploads == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	upload, ok := h.Uploads.Get(id)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	writeJSON(w, upload)
+}
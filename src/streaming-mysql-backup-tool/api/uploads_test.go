@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+)
+
+var _ = Describe("Uploads", func() {
+	It("forgets the oldest finished uploads beyond MaxFinished", func() {
+		uploads := &Uploads{MaxFinished: 2}
+		uploads.Put(history.Record{ID: "running", Outcome: UploadRunning})
+		for _, id := range []string{"first", "second", "third"} {
+			uploads.Put(history.Record{ID: id, Outcome: history.Succeeded})
+		}
+
+		_, ok := uploads.Get("first")
+		Expect(ok).To(BeFalse())
+		for _, id := range []string{"running", "second", "third"} {
+			_, ok := uploads.Get(id)
+			Expect(ok).To(BeTrue())
+		}
+	})
+})
+
+var _ = Describe("UploadHandler", func() {
+	var (
+		handler  *UploadHandler
+		recorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		uploads := &Uploads{}
+		uploads.Put(history.Record{ID: "some-id", Outcome: UploadRunning})
+		handler = &UploadHandler{Uploads: uploads}
+		recorder = httptest.NewRecorder()
+	})
+
+	It("returns the state of the requested upload", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/some-id/upload", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var upload history.Record
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &upload)).To(Succeed())
+		Expect(upload.ID).To(Equal("some-id"))
+		Expect(upload.Outcome).To(Equal(UploadRunning))
+	})
+
+	It("returns 404 for unknown uploads", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backups/other-id/upload", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("only allows GET requests", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/backups/some-id/upload", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,13 @@
+package backuplog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBackupLog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Backup Log Suite")
+}
@@ -0,0 +1,102 @@
+package backuplog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+var ErrNotFound = errors.New("backup log not found")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+const extension = ".log"
+
+// Store keeps the xtrabackup output of each backup in its own file.
+type Store struct {
+	Directory string
+	MaxCount  int
+	MaxAge    time.Duration
+	Logger    lager.Logger
+}
+
+func (s Store) Create(id string) (io.WriteCloser, error) {
+	if !validID.MatchString(id) {
+		return nil, errors.New("invalid backup id '" + id + "'")
+	}
+
+	if err := os.MkdirAll(s.Directory, 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(s.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s.prune()
+
+	return f, nil
+}
+
+func (s Store) Open(id string) (io.ReadCloser, error) {
+	if !validID.MatchString(id) {
+		return nil, ErrNotFound
+	}
+
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+func (s Store) path(id string) string {
+	return filepath.Join(s.Directory, id+extension)
+}
+
+func (s Store) prune() {
+	entries, err := os.ReadDir(s.Directory)
+	if err != nil {
+		s.Logger.Error("listing backup logs failed", err)
+		return
+	}
+
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+
+	var files []logFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), extension) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{path: filepath.Join(s.Directory, e.Name()), modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	for i, f := range files {
+		expired := s.MaxAge > 0 && time.Since(f.modTime) > s.MaxAge
+		if (s.MaxCount > 0 && i >= s.MaxCount) || expired {
+			if err := os.Remove(f.path); err != nil {
+				s.Logger.Error("removing backup log failed", err, lager.Data{"path": f.path})
+			}
+		}
+	}
+}
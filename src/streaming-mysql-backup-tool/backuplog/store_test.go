@@ -0,0 +1,108 @@
+package backuplog_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/backuplog"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		dir   string
+		store backuplog.Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "backup-logs")
+		Expect(err).NotTo(HaveOccurred())
+
+		store = backuplog.Store{
+			Directory: filepath.Join(dir, "logs"),
+			Logger:    lagertest.NewTestLogger("backuplog"),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	createLog := func(id, content string) {
+		w, err := store.Create(id)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(w, content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+	}
+
+	It("stores the log of a backup so that it can be read back", func() {
+		createLog("some-id", "xtrabackup output")
+
+		r, err := store.Open("some-id")
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+
+		Expect(io.ReadAll(r)).To(BeEquivalentTo("xtrabackup output"))
+	})
+
+	It("returns ErrNotFound for unknown backups", func() {
+		_, err := store.Open("unknown-id")
+		Expect(err).To(MatchError(backuplog.ErrNotFound))
+	})
+
+	It("refuses ids that could escape the log directory", func() {
+		_, err := store.Create("../../etc/passwd")
+		Expect(err).To(MatchError(ContainSubstring("invalid backup id")))
+
+		_, err = store.Open("../config")
+		Expect(err).To(MatchError(backuplog.ErrNotFound))
+	})
+
+	It("refuses to overwrite the log of an earlier backup", func() {
+		createLog("some-id", "first")
+
+		_, err := store.Create("some-id")
+		Expect(err).To(HaveOccurred())
+	})
+
+	When("MaxCount is set", func() {
+		BeforeEach(func() {
+			store.MaxCount = 2
+		})
+
+		It("only keeps the most recent logs", func() {
+			createLog("first", "1")
+			Expect(os.Chtimes(filepath.Join(store.Directory, "first.log"), time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))).To(Succeed())
+			createLog("second", "2")
+			Expect(os.Chtimes(filepath.Join(store.Directory, "second.log"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))).To(Succeed())
+			createLog("third", "3")
+
+			_, err := store.Open("first")
+			Expect(err).To(MatchError(backuplog.ErrNotFound))
+			Expect(filepath.Join(store.Directory, "second.log")).To(BeAnExistingFile())
+			Expect(filepath.Join(store.Directory, "third.log")).To(BeAnExistingFile())
+		})
+	})
+
+	When("MaxAge is set", func() {
+		BeforeEach(func() {
+			store.MaxAge = time.Hour
+		})
+
+		It("removes logs older than MaxAge", func() {
+			createLog("old", "1")
+			Expect(os.Chtimes(filepath.Join(store.Directory, "old.log"), time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour))).To(Succeed())
+			createLog("new", "2")
+
+			Expect(filepath.Join(store.Directory, "old.log")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(store.Directory, "new.log")).To(BeAnExistingFile())
+		})
+	})
+})
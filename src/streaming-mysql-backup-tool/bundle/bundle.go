@@ -0,0 +1,195 @@
+// Package bundle adds configuration and cluster state to backups.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mysqlcli"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+// Dir is the directory of the archive the bundle is added under.
+const Dir = "cf-mysql-backup-bundle"
+
+var snapshots = map[string]string{
+	"global_variables":       "SHOW GLOBAL VARIABLES",
+	"server_version":         "SELECT VERSION()",
+	"wsrep_provider_options": "SHOW GLOBAL VARIABLES LIKE 'wsrep_provider_options'",
+}
+
+// Writer fails a backup whose Keyring cannot be added, as it could not be prepared.
+type Writer struct {
+	BackupWriter api.BackupWriter
+	DefaultsFile string
+	Files        []string
+	Snapshots    []string
+	Keyring      *Keyring
+	Logger       lager.Logger
+}
+
+type member struct {
+	name    string
+	content []byte
+}
+
+func (b Writer) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	logger := b.Logger.WithData(lager.Data{"backup_id": req.ID})
+	log := req.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	members := b.collect(ctx, logger, log)
+	if b.Keyring != nil {
+		m, err := b.Keyring.member()
+		if err != nil {
+			logger.Error("failed to add the keyring to the bundle", err)
+			return fmt.Errorf("FATAL: adding the keyring to the backup failed: %w", err)
+		}
+		members = append(members, m)
+	}
+
+	if req.Format == "tar" {
+		return b.streamTar(ctx, logger, req, w, members)
+	}
+
+	if err := b.BackupWriter.StreamTo(ctx, req, w); err != nil {
+		return err
+	}
+	archive := xbstream.NewWriter(w)
+	for _, m := range members {
+		if err := archive.WriteFile(m.name, bytes.NewReader(m.content)); err != nil {
+			return fmt.Errorf("adding %s to the backup failed: %w", m.name, err)
+		}
+	}
+	logger.Info("added bundle to backup", lager.Data{"members": len(members)})
+	return nil
+}
+
+// streamTar appends the members to a tar archive, in place of its trailer.
+func (b Writer) streamTar(ctx context.Context, logger lager.Logger, req api.BackupRequest, w io.Writer, members []member) error {
+	trimmer := &trailerTrimmer{w: w}
+	if err := b.BackupWriter.StreamTo(ctx, req, trimmer); err != nil {
+		return err
+	}
+	if !trimmer.trailer() {
+		return errors.New("FATAL: the tar archive of the backup does not end in a trailer the bundle can be added in place of")
+	}
+
+	archive := tar.NewWriter(w)
+	for _, m := range members {
+		err := archive.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     m.name,
+			Size:     int64(len(m.content)),
+			Mode:     0640,
+			ModTime:  time.Now(),
+			Format:   tar.FormatPAX,
+		})
+		if err == nil {
+			_, err = archive.Write(m.content)
+		}
+		if err != nil {
+			return fmt.Errorf("adding %s to the backup failed: %w", m.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	logger.Info("added bundle to backup", lager.Data{"members": len(members)})
+	return nil
+}
+
+func (b Writer) collect(ctx context.Context, logger lager.Logger, log io.Writer) []member {
+	var members []member
+
+	for _, file := range b.Files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			logger.Info("skipping bundle file", lager.Data{"file": file, "error": err.Error()})
+			_, _ = fmt.Fprintf(log, "bundle: skipping %s: %v\n", file, err)
+			continue
+		}
+		members = append(members, member{
+			name:    path.Join(Dir, "files", strings.TrimPrefix(path.Clean(file), "/")),
+			content: content,
+		})
+	}
+
+	for _, name := range b.Snapshots {
+		content, err := b.query(ctx, snapshots[name])
+		if err != nil {
+			logger.Info("skipping bundle snapshot", lager.Data{"snapshot": name, "error": err.Error()})
+			_, _ = fmt.Fprintf(log, "bundle: skipping snapshot %s: %v\n", name, err)
+			continue
+		}
+		members = append(members, member{
+			name:    path.Join(Dir, "snapshots", name+".txt"),
+			content: content,
+		})
+	}
+
+	return members
+}
+
+func (b Writer) query(ctx context.Context, statement string) ([]byte, error) {
+	if statement == "" {
+		return nil, errors.New("unknown snapshot")
+	}
+
+	return mysqlcli.Query(ctx, b.DefaultsFile, statement)
+}
+
+// trailerSize is the two zero blocks archive/tar ends an archive with.
+const trailerSize = 2 * 512
+
+// trailerTrimmer drops the tar trailer so that more members can be appended.
+type trailerTrimmer struct {
+	w    io.Writer
+	held []byte
+}
+
+func (t *trailerTrimmer) Write(p []byte) (int, error) {
+	if len(t.held)+len(p) <= trailerSize {
+		t.held = append(t.held, p...)
+		return len(p), nil
+	}
+
+	flush := len(t.held) + len(p) - trailerSize
+	if flush <= len(t.held) {
+		if _, err := t.w.Write(t.held[:flush]); err != nil {
+			return 0, err
+		}
+		t.held = append(t.held[:0], t.held[flush:]...)
+		t.held = append(t.held, p...)
+		return len(p), nil
+	}
+
+	if _, err := t.w.Write(t.held); err != nil {
+		return 0, err
+	}
+	if _, err := t.w.Write(p[:flush-len(t.held)]); err != nil {
+		return 0, err
+	}
+	t.held = append(t.held[:0], p[flush-len(t.held):]...)
+	return len(p), nil
+}
+
+// trailer reports whether the held back bytes are a tar trailer.
+func (t *trailerTrimmer) trailer() bool {
+	return len(t.held) == trailerSize && bytes.Count(t.held, []byte{0}) == trailerSize
+}
+
+var _ api.BackupWriter = Writer{}
@@ -0,0 +1,172 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/bundle"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+// archiveWriter writes a single file in the requested format, like a backup.
+type archiveWriter struct {
+	err error
+}
+
+func (a archiveWriter) StreamTo(_ context.Context, req api.BackupRequest, w io.Writer) error {
+	if a.err != nil {
+		return a.err
+	}
+	if req.Format == "tar" {
+		tw := tar.NewWriter(w)
+		Expect(tw.WriteHeader(&tar.Header{Name: "ibdata1", Mode: 0640, Size: 4})).To(Succeed())
+		_, err := tw.Write([]byte("data"))
+		Expect(err).NotTo(HaveOccurred())
+		return tw.Close()
+	}
+	return xbstream.NewWriter(w).WriteFile("ibdata1", strings.NewReader("data"))
+}
+
+func untar(r io.Reader) map[string]string {
+	files := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(tr)
+		Expect(err).NotTo(HaveOccurred())
+		files[header.Name] = string(content)
+	}
+}
+
+func unxbstream(r io.Reader) map[string]string {
+	files := map[string]string{}
+	xr := xbstream.NewReader(r)
+	for {
+		c, err := xr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		Expect(err).NotTo(HaveOccurred())
+		files[c.Path] += string(c.Payload)
+	}
+}
+
+var _ = Describe("bundle.Writer", func() {
+	var (
+		writer    bundle.Writer
+		grastate  string
+		output    bytes.Buffer
+		backupLog bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		grastate = filepath.Join(GinkgoT().TempDir(), "grastate.dat")
+		Expect(os.WriteFile(grastate, []byte("seqno: 42\n"), 0600)).To(Succeed())
+
+		writer = bundle.Writer{
+			BackupWriter: archiveWriter{},
+			DefaultsFile: "/etc/my.cnf",
+			Files:        []string{grastate, "/nonexistent/my.cnf"},
+			Snapshots:    []string{"server_version", "global_variables", "wsrep_provider_options"},
+			Logger:       lagertest.NewTestLogger("bundle"),
+		}
+		output.Reset()
+		backupLog.Reset()
+	})
+
+	expectedFiles := func() map[string]string {
+		return map[string]string{
+			"ibdata1": "data",
+			"cf-mysql-backup-bundle/files" + grastate:               "seqno: 42\n",
+			"cf-mysql-backup-bundle/snapshots/server_version.txt":   "8.0.35-27.1\n",
+			"cf-mysql-backup-bundle/snapshots/global_variables.txt": "innodb_buffer_pool_size\t134217728\nwsrep_on\tON\n",
+		}
+	}
+
+	It("appends the bundle to tar backups", func() {
+		err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &backupLog}, &output)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(untar(&output)).To(Equal(expectedFiles()))
+	})
+
+	It("appends the bundle to xbstream backups", func() {
+		err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &backupLog}, &output)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(unxbstream(&output)).To(Equal(expectedFiles()))
+	})
+
+	It("skips files and snapshots it cannot collect with a warning", func() {
+		err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &backupLog}, &output)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(backupLog.String()).To(ContainSubstring("bundle: skipping /nonexistent/my.cnf: open /nonexistent/my.cnf: no such file or directory\n"))
+		Expect(backupLog.String()).To(ContainSubstring("bundle: skipping snapshot wsrep_provider_options: exit status 1: ERROR 2002 (HY000): Can't connect to local MySQL server\n"))
+	})
+
+	It("adds nothing to failed backups", func() {
+		writer.BackupWriter = archiveWriter{err: errors.New("FATAL: some-error")}
+
+		err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar"}, &output)
+		Expect(err).To(MatchError("FATAL: some-error"))
+		Expect(output.Len()).To(BeZero())
+	})
+
+	When("a keyring is configured", func() {
+		var keyring string
+
+		BeforeEach(func() {
+			keyring = filepath.Join(GinkgoT().TempDir(), "component_keyring_file")
+			Expect(os.WriteFile(keyring, []byte("some-keyring"), 0600)).To(Succeed())
+
+			writer.Keyring = &bundle.Keyring{File: keyring, Component: true, EncryptionKey: "some-keyring-key"}
+		})
+
+		It("adds the keyring sealed with the EncryptionKey", func() {
+			err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &backupLog}, &output)
+			Expect(err).NotTo(HaveOccurred())
+
+			const name = "cf-mysql-backup-bundle/keyring/component_keyring_file.sealed"
+			files := untar(&output)
+			Expect(files).To(HaveKey(name))
+			Expect(files[name]).NotTo(ContainSubstring("some-keyring"))
+
+			key := sha256.Sum256([]byte("some-keyring-key"))
+			block, err := aes.NewCipher(key[:])
+			Expect(err).NotTo(HaveOccurred())
+			aead, err := cipher.NewGCM(block)
+			Expect(err).NotTo(HaveOccurred())
+			sealed := []byte(files[name])
+			keyringContent, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(keyringContent)).To(Equal("some-keyring"))
+		})
+
+		It("fails the backup when the keyring cannot be read", func() {
+			Expect(os.Remove(keyring)).To(Succeed())
+
+			err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &backupLog}, &output)
+			Expect(err).To(MatchError(HavePrefix("FATAL: adding the keyring to the backup failed:")))
+			Expect(output.Len()).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"path"
+)
+
+// Keyring is sealed with AES-256-GCM under the SHA-256 of EncryptionKey.
+type Keyring struct {
+	File string
+	// component_keyring_file and the keyring_file plugin are prepared differently.
+	Component     bool
+	EncryptionKey string
+}
+
+func (k Keyring) member() (member, error) {
+	name := path.Join(Dir, "keyring", "keyring_file.sealed")
+	if k.Component {
+		name = path.Join(Dir, "keyring", "component_keyring_file.sealed")
+	}
+
+	content, err := os.ReadFile(k.File)
+	if err != nil {
+		return member{}, err
+	}
+
+	key := sha256.Sum256([]byte(k.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return member{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return member{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return member{}, err
+	}
+	return member{name: name, content: aead.Seal(nonce, nonce, content, []byte(name))}, nil
+}
@@ -0,0 +1,255 @@
+// Package clone takes backups with the CLONE plugin of MySQL 8.0.17 and later.
+package clone
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mysqlcli"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+type Writer struct {
+	DefaultsFile string
+	TmpDir       string
+	DiskGuard    *diskguard.Guard
+	StopTimeout  time.Duration
+	Logger       lager.Logger
+}
+
+// Engine is what the metadata of a clone records as its backup_engine.
+const Engine = "clone"
+
+const (
+	defaultStopTimeout = time.Minute
+	stopPollInterval   = 500 * time.Millisecond
+)
+
+func (c Writer) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	logger := c.Logger.WithData(lager.Data{"backup_id": req.ID})
+	log := req.Log
+	if log == nil {
+		log = io.Discard
+	}
+
+	version, err := serverversion.Query(ctx, c.DefaultsFile)
+	if err != nil {
+		logger.Error("detecting the server version failed", err)
+		return fmt.Errorf("FATAL: %w", err)
+	}
+	if !supportsClone(version) {
+		return fmt.Errorf("FATAL: the CLONE plugin needs MySQL 8.0.17 or later, the server is %s", version)
+	}
+	req.AddMetadata("backup_engine", Engine)
+	req.AddMetadata("backup_engine_version", version.String())
+
+	if c.DiskGuard != nil {
+		if err := c.DiskGuard.Check(); err != nil {
+			logger.Error("not enough free disk space to start the clone", err)
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if c.DiskGuard != nil {
+		go c.DiskGuard.Watch(ctx, cancel)
+	}
+
+	dir := filepath.Join(c.TmpDir, "clone-"+req.ID)
+	keep := false
+	defer func() {
+		if keep {
+			return
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Error("removing the clone failed", err, lager.Data{"dir": dir})
+		}
+	}()
+
+	logger.Info("cloning the data directory", lager.Data{"dir": dir, "server_version": version.String()})
+	_, _ = fmt.Fprintf(log, "Cloning the data directory of MySQL server %s into %s\n", version, dir)
+
+	if _, err := mysqlcli.Query(ctx, c.DefaultsFile, "CLONE LOCAL DATA DIRECTORY = "+quote(dir)); err != nil {
+		_, _ = fmt.Fprintln(log, err)
+		if ctx.Err() != nil {
+			// Killing the mysql client does not stop the clone on the server.
+			if stopErr := c.stopClone(dir); stopErr != nil {
+				keep = true
+				logger.Error("stopping the clone on the server failed, leaving it in place", stopErr, lager.Data{"dir": dir})
+				_, _ = fmt.Fprintf(log, "Stopping the clone on the server failed, leaving %s in place: %v\n", dir, stopErr)
+			}
+		}
+		if cause := context.Cause(ctx); errors.Is(cause, diskguard.ErrDiskFull) {
+			logger.Error("the clone was stopped", cause)
+			return fmt.Errorf("%w (%v)", cause, err)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("the clone was stopped: %w (%v)", context.Cause(ctx), err)
+		}
+		return fmt.Errorf("FATAL: cloning the data directory failed: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(log, "Streaming %s\n", dir)
+	if err := stream(ctx, dir, req.Format, w); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("streaming the clone was stopped: %w (%v)", context.Cause(ctx), err)
+		}
+		return fmt.Errorf("streaming the clone failed: %w", err)
+	}
+
+	logger.Info("clone finished")
+	_, _ = fmt.Fprintln(log, "completed OK!")
+	return nil
+}
+
+func (c Writer) stopClone(dir string) error {
+	timeout := c.StopTimeout
+	if timeout <= 0 {
+		timeout = defaultStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	running := "SELECT PID FROM performance_schema.clone_status WHERE STATE = 'In Progress' AND TRIM(TRAILING '/' FROM DESTINATION) = " + quote(dir)
+	for {
+		out, err := mysqlcli.Query(ctx, c.DefaultsFile, running)
+		if err != nil {
+			return fmt.Errorf("checking whether the clone is still running failed: %w", err)
+		}
+		field := strings.TrimSpace(string(out))
+		if field == "" {
+			return nil
+		}
+		pid, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unexpected clone_status PID %q", field)
+		}
+
+		// The statement may end before it is killed, which the next check tells.
+		_, _ = mysqlcli.Query(ctx, c.DefaultsFile, "KILL QUERY "+strconv.FormatUint(pid, 10))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the server is still cloning after %s", timeout)
+		case <-time.After(stopPollInterval):
+		}
+	}
+}
+
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+func supportsClone(v serverversion.Version) bool {
+	if v.MariaDB() {
+		return false
+	}
+	if v.Major != 8 {
+		return v.Major > 8
+	}
+	return v.Minor > 0 || v.Patch >= 17
+}
+
+func stream(ctx context.Context, dir, format string, w io.Writer) error {
+	var (
+		tw *tar.Writer
+		xw *xbstream.Writer
+	)
+	switch format {
+	case "tar":
+		tw = tar.NewWriter(w)
+	case "xbstream":
+		xw = xbstream.NewWriter(w)
+	default:
+		return fmt.Errorf("unsupported format %s", format)
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if tw != nil {
+			if !d.IsDir() && !info.Mode().IsRegular() {
+				return nil
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(name)
+			if d.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return copyFile(path, tw)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return xw.WriteFile(filepath.ToSlash(name), f)
+	})
+	if err != nil {
+		return err
+	}
+
+	if tw != nil {
+		return tw.Close()
+	}
+	return nil
+}
+
+func copyFile(path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+var _ api.BackupWriter = Writer{}
@@ -0,0 +1,22 @@
+package clone_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestClone(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Clone Suite")
+}
+
+var _ = BeforeSuite(func() {
+	scriptDir, err := filepath.Abs("../mysqlcli/scripts")
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(os.Setenv("PATH", scriptDir+":"+os.Getenv("PATH"))).To(Succeed())
+})
@@ -0,0 +1,159 @@
+package clone_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/clone"
+)
+
+var _ = Describe("clone.Writer", func() {
+	var (
+		tmpDir string
+		log    bytes.Buffer
+		output bytes.Buffer
+		writer clone.Writer
+	)
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+		log.Reset()
+		output.Reset()
+		writer = clone.Writer{
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       tmpDir,
+			Logger:       lagertest.NewTestLogger("clone"),
+		}
+	})
+
+	It("streams a clone of the data directory as tar", func() {
+		Expect(writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &log}, &output)).To(Succeed())
+
+		files := map[string]string{}
+		tr := tar.NewReader(&output)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			content, err := io.ReadAll(tr)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(content)
+		}
+		Expect(files).To(Equal(map[string]string{
+			"#innodb_redo/":          "",
+			"#innodb_redo/#ib_redo0": "",
+			"ibdata1":                "some-tablespace",
+			"mysql/":                 "",
+			"mysql/user.ibd":         "some-table",
+		}))
+	})
+
+	It("streams a clone of the data directory as xbstream", func() {
+		Expect(writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &log}, &output)).To(Succeed())
+
+		Expect(output.String()).To(HavePrefix("XBSTCK01"))
+		Expect(output.String()).To(ContainSubstring("mysql/user.ibd"))
+		Expect(output.String()).To(ContainSubstring("some-tablespace"))
+	})
+
+	It("records the engine in the metadata of the backup", func() {
+		metadata := map[string]string{}
+		Expect(writer.StreamTo(context.Background(), api.BackupRequest{
+			ID:     "some-id",
+			Format: "tar",
+			Log:    &log,
+			SetMetadata: func(key, value string) {
+				metadata[key] = value
+			},
+		}, &output)).To(Succeed())
+
+		Expect(metadata).To(Equal(map[string]string{
+			"backup_engine":         "clone",
+			"backup_engine_version": "8.0.35-27.1",
+		}))
+		Expect(log.String()).NotTo(ContainSubstring("based on"))
+		Expect(log.String()).To(HaveSuffix("completed OK!\n"))
+	})
+
+	It("removes the clone once it has been streamed", func() {
+		Expect(writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &log}, &output)).To(Succeed())
+
+		Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+	})
+
+	When("the server does not support the CLONE plugin", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("FAKE_MYSQL_VERSION", "5.7.44-log")
+		})
+
+		It("refuses to take the backup", func() {
+			err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &log}, &output)
+			Expect(err).To(MatchError("FATAL: the CLONE plugin needs MySQL 8.0.17 or later, the server is 5.7.44-log"))
+			Expect(output.Len()).To(BeZero())
+		})
+	})
+
+	When("the clone is cancelled", func() {
+		var state string
+
+		BeforeEach(func() {
+			state = GinkgoT().TempDir()
+			GinkgoT().Setenv("FAKE_MYSQL_STATE", state)
+			GinkgoT().Setenv("FAKE_MYSQL_CLONE_HANG", "true")
+			writer.StopTimeout = 2 * time.Second
+		})
+
+		streamAndCancel := func() error {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			go func() {
+				defer GinkgoRecover()
+				Eventually(filepath.Join(state, "cloning")).Should(BeAnExistingFile())
+				cancel(api.ErrCancelled)
+			}()
+			return writer.StreamTo(ctx, api.BackupRequest{ID: "some-id", Format: "tar", Log: &log}, &output)
+		}
+
+		It("kills the clone on the server before removing it", func() {
+			Expect(streamAndCancel()).To(MatchError(api.ErrCancelled))
+
+			Expect(os.ReadFile(filepath.Join(state, "killed"))).To(Equal([]byte("KILL QUERY 42\n")))
+			Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+		})
+
+		It("leaves the clone in place when the server does not stop cloning", func() {
+			GinkgoT().Setenv("FAKE_MYSQL_CLONE_UNKILLABLE", "true")
+
+			Expect(streamAndCancel()).To(MatchError(api.ErrCancelled))
+
+			Expect(filepath.Join(tmpDir, "clone-some-id")).To(BeADirectory())
+			Expect(log.String()).To(ContainSubstring("Stopping the clone on the server failed, leaving " + filepath.Join(tmpDir, "clone-some-id") + " in place"))
+		})
+	})
+
+	When("the clone fails", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv("FAKE_MYSQL_CLONE_ERROR", "ERROR 1227 (42000): Access denied; you need the BACKUP_ADMIN privilege")
+		})
+
+		It("returns the error of the server", func() {
+			err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &log}, &output)
+			Expect(err).To(MatchError(ContainSubstring("FATAL: cloning the data directory failed")))
+			Expect(err).To(MatchError(ContainSubstring("BACKUP_ADMIN privilege")))
+			Expect(log.String()).To(ContainSubstring("BACKUP_ADMIN privilege"))
+			Expect(os.ReadDir(tmpDir)).To(BeEmpty())
+		})
+	})
+})
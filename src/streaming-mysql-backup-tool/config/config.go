@@ -3,14 +3,26 @@ package config
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"flag"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
 
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagerflags"
 	"code.cloudfoundry.org/tlsconfig"
+	"github.com/cloudfoundry/tracing"
 	"github.com/pivotal-cf-experimental/service-config"
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/identity"
 )
 
 type Config struct {
@@ -19,12 +31,423 @@ type Config struct {
 	Credentials Credentials `yaml:"Credentials" validate:"nonzero"`
 	TLS         TLSConfig   `yaml:"TLS"`
 	Logger      lager.Logger
-	XtraBackup  XtraBackup `yaml:"XtraBackup"`
+	XtraBackup  XtraBackup     `yaml:"XtraBackup"`
+	BackupLogs  BackupLogs     `yaml:"BackupLogs"`
+	History     History        `yaml:"History"`
+	FanOut      FanOut         `yaml:"FanOut"`
+	ObjectStore ObjectStore    `yaml:"ObjectStore"`
+	Maintenance Maintenance    `yaml:"Maintenance"`
+	AuthGuard   AuthGuard      `yaml:"AuthGuard"`
+	RateLimit   RateLimit      `yaml:"RateLimit"`
+	Policy      Policy         `yaml:"Policy"`
+	Tracing     tracing.Config `yaml:"Tracing"`
+	Instances   []Instance     `yaml:"Instances"`
+}
+
+var instanceName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Instance is a further mysqld instance on the VM, served under /instances/{name}.
+type Instance struct {
+	Name             string       `yaml:"Name"`
+	DefaultsFile     string       `yaml:"DefaultsFile"`
+	TmpDir           string       `yaml:"TmpDir"`
+	Binary           string       `yaml:"Binary"`
+	Credentials      *Credentials `yaml:"Credentials"`
+	ClientIdentities []string     `yaml:"ClientIdentities"`
+	Bundle           *Bundle      `yaml:"Bundle"`
+	Keyring          *Keyring     `yaml:"Keyring"`
+}
+
+// XtraBackup returns defaults with the overrides of the instance applied.
+func (i Instance) XtraBackup(defaults XtraBackup) XtraBackup {
+	x := defaults
+	x.DefaultsFile = i.DefaultsFile
+	if i.TmpDir != "" {
+		x.TmpDir = i.TmpDir
+	}
+	if i.Binary != "" {
+		x.Binary = i.Binary
+		x.Binaries = nil
+	}
+	if i.Bundle != nil {
+		x.Bundle = *i.Bundle
+	}
+	if i.Keyring != nil {
+		x.Keyring = *i.Keyring
+	}
+	return x
+}
+
+func validateInstances(instances []Instance) error {
+	names := map[string]bool{}
+	for _, i := range instances {
+		if !instanceName.MatchString(i.Name) {
+			return errors.Errorf("invalid Instances name '%s', must only contain letters, digits, '-' and '_'", i.Name)
+		}
+		if names[i.Name] {
+			return errors.Errorf("duplicate Instances name '%s'", i.Name)
+		}
+		names[i.Name] = true
+
+		if i.DefaultsFile == "" {
+			return errors.Errorf("Instances '%s' must have a DefaultsFile", i.Name)
+		}
+		if c := i.Credentials; c != nil && (c.Username == "" || c.Password == "") {
+			return errors.Errorf("Instances '%s' must have both a Username and a Password in its Credentials", i.Name)
+		}
+		if i.Bundle != nil {
+			if err := i.Bundle.validate(); err != nil {
+				return errors.Wrapf(err, "Instances '%s'", i.Name)
+			}
+		}
+		if i.Keyring != nil {
+			if err := i.Keyring.validate(); err != nil {
+				return errors.Wrapf(err, "Instances '%s'", i.Name)
+			}
+		}
+	}
+	return nil
 }
 
+var (
+	releaseSeries     = regexp.MustCompile(`^\d+\.\d+$`)
+	redoLogArchiveDir = regexp.MustCompile(`^[^:;]+:/[^;]*$`)
+)
+
 type XtraBackup struct {
-	DefaultsFile string `yaml:"DefaultsFile"`
-	TmpDir       string `yaml:"TmpDir"`
+	Engine                 string            `yaml:"Engine"`
+	Binaries               map[string]string `yaml:"Binaries"`
+	Binary                 string            `yaml:"Binary"`
+	DefaultsFile           string            `yaml:"DefaultsFile"`
+	TmpDir                 string            `yaml:"TmpDir"`
+	HistoryName            string            `yaml:"HistoryName"`
+	MaxPause               time.Duration     `yaml:"MaxPause"`
+	StallTimeout           time.Duration     `yaml:"StallTimeout"`
+	DiskGuard              DiskGuard         `yaml:"DiskGuard"`
+	SlaveInfo              bool              `yaml:"SlaveInfo"`
+	SafeSlaveBackup        bool              `yaml:"SafeSlaveBackup"`
+	SafeSlaveBackupTimeout time.Duration     `yaml:"SafeSlaveBackupTimeout"`
+	GaleraInfo             bool              `yaml:"GaleraInfo"`
+	RedoLogArchiveDir      string            `yaml:"RedoLogArchiveDir"`
+	Bundle                 Bundle            `yaml:"Bundle"`
+	Keyring                Keyring           `yaml:"Keyring"`
+}
+
+type Keyring struct {
+	FileData        string `yaml:"FileData"`
+	ComponentConfig string `yaml:"ComponentConfig"`
+	TransitionKey   string `yaml:"TransitionKey"`
+	EncryptionKey   string `yaml:"EncryptionKey"`
+}
+
+func (k Keyring) Enabled() bool {
+	return k.FileData != "" || k.ComponentConfig != "" || k.TransitionKey != ""
+}
+
+// File returns FileData, or the keyring file named by ComponentConfig.
+func (k Keyring) File() (string, error) {
+	if k.FileData != "" {
+		return k.FileData, nil
+	}
+
+	contents, err := os.ReadFile(k.ComponentConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "Reading XtraBackup.Keyring.ComponentConfig")
+	}
+	var component struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(contents, &component); err != nil {
+		return "", errors.Wrap(err, "Parsing XtraBackup.Keyring.ComponentConfig")
+	}
+	if component.Path == "" {
+		return "", errors.Errorf("XtraBackup.Keyring.ComponentConfig '%s' does not set the path of a keyring file", k.ComponentConfig)
+	}
+	return component.Path, nil
+}
+
+type Bundle struct {
+	Files     []string `yaml:"Files"`
+	Snapshots []string `yaml:"Snapshots"`
+}
+
+func (b Bundle) Enabled() bool {
+	return len(b.Files) > 0 || len(b.Snapshots) > 0
+}
+
+var defaultXtraBackup = XtraBackup{
+	Engine:   "xtrabackup",
+	MaxPause: 15 * time.Minute,
+}
+
+func (x XtraBackup) validate() error {
+	switch x.Engine {
+	case "xtrabackup", "mariabackup", "clone", "auto":
+	default:
+		return errors.Errorf("invalid XtraBackup.Engine '%s', must be 'xtrabackup', 'mariabackup', 'clone' or 'auto'", x.Engine)
+	}
+
+	for series := range x.Binaries {
+		if !releaseSeries.MatchString(series) {
+			return errors.Errorf("invalid XtraBackup.Binaries series '%s', must be a major and minor version such as '8.0'", series)
+		}
+	}
+	if d := x.RedoLogArchiveDir; d != "" && !redoLogArchiveDir.MatchString(d) {
+		return errors.Errorf("invalid XtraBackup.RedoLogArchiveDir '%s', must be a label and an absolute path such as 'backup:/var/vcap/store/redo-log-archive'", d)
+	}
+	if err := x.Keyring.validate(); err != nil {
+		return err
+	}
+	return x.Bundle.validate()
+}
+
+func (k Keyring) validate() error {
+	if k.FileData != "" && k.ComponentConfig != "" {
+		return errors.New("XtraBackup.Keyring.FileData and XtraBackup.Keyring.ComponentConfig are mutually exclusive")
+	}
+	if k.EncryptionKey != "" && k.FileData == "" && k.ComponentConfig == "" {
+		return errors.New("XtraBackup.Keyring.EncryptionKey requires XtraBackup.Keyring.FileData or XtraBackup.Keyring.ComponentConfig")
+	}
+	if k.EncryptionKey != "" && k.TransitionKey != "" {
+		return errors.New("XtraBackup.Keyring.EncryptionKey and XtraBackup.Keyring.TransitionKey are mutually exclusive")
+	}
+	// The transition key is written into an option file for xtrabackup.
+	if strings.IndexFunc(k.TransitionKey, unicode.IsControl) >= 0 {
+		return errors.New("XtraBackup.Keyring.TransitionKey must not contain control characters")
+	}
+	return nil
+}
+
+func (b Bundle) validate() error {
+	for _, file := range b.Files {
+		if !filepath.IsAbs(file) {
+			return errors.Errorf("invalid XtraBackup.Bundle.Files path '%s', must be absolute", file)
+		}
+	}
+	for _, snapshot := range b.Snapshots {
+		switch snapshot {
+		case "global_variables", "server_version", "wsrep_provider_options":
+		default:
+			return errors.Errorf("invalid XtraBackup.Bundle.Snapshots '%s', must be 'global_variables', 'server_version' or 'wsrep_provider_options'", snapshot)
+		}
+	}
+	return nil
+}
+
+// ReadXtraBackup reads only the XtraBackup options of the config file at path.
+func ReadXtraBackup(path string) (XtraBackup, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return XtraBackup{}, err
+	}
+
+	config := struct {
+		XtraBackup XtraBackup `yaml:"XtraBackup"`
+	}{XtraBackup: defaultXtraBackup}
+	if err := yaml.Unmarshal(contents, &config); err != nil {
+		return XtraBackup{}, errors.Wrap(err, "Unmarshaling config")
+	}
+
+	return config.XtraBackup, config.XtraBackup.validate()
+}
+
+type DiskGuard struct {
+	MinFreeBytes       uint64        `yaml:"MinFreeBytes"`
+	MinFreePercent     float64       `yaml:"MinFreePercent"`
+	RedoBytesPerSecond uint64        `yaml:"RedoBytesPerSecond"`
+	ExpectedDuration   time.Duration `yaml:"ExpectedDuration"`
+	CheckInterval      time.Duration `yaml:"CheckInterval"`
+}
+
+func (d DiskGuard) Enabled() bool {
+	return d.MinFreeBytes > 0 || d.MinFreePercent > 0
+}
+
+type BackupLogs struct {
+	Directory string        `yaml:"Directory"`
+	MaxCount  int           `yaml:"MaxCount"`
+	MaxAge    time.Duration `yaml:"MaxAge"`
+}
+
+type History struct {
+	File       string `yaml:"File"`
+	MaxRecords int    `yaml:"MaxRecords"`
+}
+
+type FanOut struct {
+	JoinWindow         time.Duration `yaml:"JoinWindow"`
+	MaxBufferBytes     int           `yaml:"MaxBufferBytes"`
+	SlowConsumerPolicy string        `yaml:"SlowConsumerPolicy"`
+	SpillDir           string        `yaml:"SpillDir"`
+}
+
+type Maintenance struct {
+	SentinelFile     string        `yaml:"SentinelFile"`
+	PollInterval     time.Duration `yaml:"PollInterval"`
+	InFlight         string        `yaml:"InFlight"`
+	AdminCredentials *Credentials  `yaml:"AdminCredentials"`
+	AdminIdentities  []string      `yaml:"AdminIdentities"`
+}
+
+func (m Maintenance) validate() error {
+	switch m.InFlight {
+	case "finish", "cancel":
+	default:
+		return errors.Errorf("invalid Maintenance.InFlight '%s', must be 'finish' or 'cancel'", m.InFlight)
+	}
+	if m.PollInterval <= 0 {
+		return errors.New("Maintenance.PollInterval must be positive")
+	}
+	if c := m.AdminCredentials; c != nil && (c.Username == "" || c.Password == "") {
+		return errors.New("Maintenance.AdminCredentials must have both a Username and a Password")
+	}
+	return nil
+}
+
+type AuthGuard struct {
+	MaxFailures int           `yaml:"MaxFailures"`
+	BaseDelay   time.Duration `yaml:"BaseDelay"`
+	MaxDelay    time.Duration `yaml:"MaxDelay"`
+	Lockout     time.Duration `yaml:"Lockout"`
+}
+
+func (g AuthGuard) Enabled() bool {
+	return g.MaxFailures > 0
+}
+
+func (g AuthGuard) validate() error {
+	switch {
+	case g.MaxFailures < 0:
+		return errors.New("AuthGuard.MaxFailures must not be negative")
+	case !g.Enabled():
+		return nil
+	case g.BaseDelay <= 0 || g.MaxDelay <= 0 || g.Lockout <= 0:
+		return errors.New("AuthGuard.BaseDelay, AuthGuard.MaxDelay and AuthGuard.Lockout must be positive")
+	case g.BaseDelay > g.MaxDelay:
+		return errors.Errorf("AuthGuard.BaseDelay '%s' must not exceed AuthGuard.MaxDelay '%s'", g.BaseDelay, g.MaxDelay)
+	}
+	return nil
+}
+
+type RateLimit struct {
+	Requests int           `yaml:"Requests"`
+	Interval time.Duration `yaml:"Interval"`
+}
+
+func (r RateLimit) Enabled() bool {
+	return r.Requests > 0
+}
+
+func (r RateLimit) validate() error {
+	switch {
+	case r.Requests < 0:
+		return errors.New("RateLimit.Requests must not be negative")
+	case r.Enabled() && r.Interval <= 0:
+		return errors.Errorf("invalid RateLimit.Interval '%s', must be positive", r.Interval)
+	}
+	return nil
+}
+
+type Policy struct {
+	Rules []PolicyRule `yaml:"Rules"`
+}
+
+type PolicyRule struct {
+	Identities []string `yaml:"Identities"`
+	Networks   []string `yaml:"Networks"`
+	Operations []string `yaml:"Operations"`
+	Formats    []string `yaml:"Formats"`
+}
+
+func (p Policy) Enabled() bool {
+	return len(p.Rules) > 0
+}
+
+func (p Policy) validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Operations) == 0 {
+			return errors.Errorf("Policy.Rules[%d] must grant at least one of the Operations", i)
+		}
+		for _, operation := range rule.Operations {
+			switch operation {
+			case "backup", "status", "cancel":
+			case "restore":
+				return errors.Errorf("Policy.Rules[%d].Operations 'restore' is not supported, the tool does not restore backups", i)
+			default:
+				return errors.Errorf("invalid Policy.Rules[%d].Operations '%s', must be 'backup', 'status' or 'cancel'", i, operation)
+			}
+		}
+		for _, format := range rule.Formats {
+			switch format {
+			case "xbstream", "tar":
+			default:
+				return errors.Errorf("invalid Policy.Rules[%d].Formats '%s', must be 'xbstream' or 'tar'", i, format)
+			}
+		}
+		for _, network := range rule.Networks {
+			if _, _, err := net.ParseCIDR(network); err != nil {
+				return errors.Errorf("invalid Policy.Rules[%d].Networks '%s', must be a CIDR range", i, network)
+			}
+		}
+	}
+	return nil
+}
+
+// IPNets returns the parsed Networks of the rule.
+func (r PolicyRule) IPNets() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, network := range r.Networks {
+		if _, ipNet, err := net.ParseCIDR(network); err == nil {
+			networks = append(networks, ipNet)
+		}
+	}
+	return networks
+}
+
+type ObjectStore struct {
+	Endpoint        string `yaml:"Endpoint"`
+	Region          string `yaml:"Region"`
+	Bucket          string `yaml:"Bucket"`
+	Prefix          string `yaml:"Prefix"`
+	AccessKeyID     string `yaml:"AccessKeyID"`
+	SecretAccessKey string `yaml:"SecretAccessKey"`
+	CA              string `yaml:"CA"`
+	ChunkSize       int    `yaml:"ChunkSize"`
+	EncryptionKey   string `yaml:"EncryptionKey"`
+}
+
+func (o ObjectStore) Enabled() bool {
+	return o.Endpoint != ""
+}
+
+func (o ObjectStore) validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.Bucket == "" {
+		return errors.New("ObjectStore.Bucket must be set when ObjectStore.Endpoint is set")
+	}
+	if o.EncryptionKey == "" {
+		return errors.New("ObjectStore.EncryptionKey must be set when ObjectStore.Endpoint is set")
+	}
+	return nil
+}
+
+// HTTPClient returns a client for talking to the Endpoint.
+func (o ObjectStore) HTTPClient() (*http.Client, error) {
+	if o.CA == "" {
+		return &http.Client{}, nil
+	}
+
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM([]byte(o.CA)); !ok {
+		return nil, errors.New("unable to load object store CA certificate")
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: certPool},
+		},
+	}, nil
 }
 
 type Credentials struct {
@@ -33,17 +456,47 @@ type Credentials struct {
 }
 
 type TLSConfig struct {
-	EnableMutualTLS          bool        `yaml:"EnableMutualTLS"`
-	RequiredClientIdentities []string    `yaml:"RequiredClientIdentities"`
-	ServerCert               string      `yaml:"ServerCert" validate:"nonzero"`
-	ServerKey                string      `yaml:"ServerKey" validate:"nonzero"`
-	ClientCA                 string      `yaml:"ClientCA" validate:"nonzero"`
-	Config                   *tls.Config `yaml:"-"`
+	EnableMutualTLS          bool                                  `yaml:"EnableMutualTLS"`
+	RequiredClientIdentities []string                              `yaml:"RequiredClientIdentities"`
+	ServerCert               string                                `yaml:"ServerCert" validate:"nonzero"`
+	ServerKey                string                                `yaml:"ServerKey" validate:"nonzero"`
+	ClientCA                 string                                `yaml:"ClientCA" validate:"nonzero"`
+	Revocation               Revocation                            `yaml:"Revocation"`
+	Config                   *tls.Config                           `yaml:"-"`
+	CheckRevocation          func(chain []*x509.Certificate) error `yaml:"-"`
+}
+
+type Revocation struct {
+	CRLFile           string        `yaml:"CRLFile"`
+	CRLReloadInterval time.Duration `yaml:"CRLReloadInterval"`
+	OCSP              bool          `yaml:"OCSP"`
+	OCSPResponder     string        `yaml:"OCSPResponder"`
+	OCSPTimeout       time.Duration `yaml:"OCSPTimeout"`
+	FailurePolicy     string        `yaml:"FailurePolicy"`
+}
+
+func (r Revocation) Enabled() bool {
+	return r.CRLFile != "" || r.OCSP
+}
+
+func (r Revocation) validate() error {
+	switch r.FailurePolicy {
+	case "closed", "open":
+	default:
+		return errors.Errorf("invalid TLS.Revocation.FailurePolicy '%s', must be 'closed' or 'open'", r.FailurePolicy)
+	}
+	if r.CRLFile != "" && r.CRLReloadInterval <= 0 {
+		return errors.New("TLS.Revocation.CRLReloadInterval must be positive")
+	}
+	if r.OCSPResponder != "" && !r.OCSP {
+		return errors.New("TLS.Revocation.OCSPResponder requires TLS.Revocation.OCSP")
+	}
+	return nil
 }
 
 type ClientCertificateVerifierFunc func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
 
-func (t *TLSConfig) unmarshalTLSConfig() error {
+func (t *TLSConfig) unmarshalTLSConfig(logger lager.Logger) error {
 	serverCert, err := tls.X509KeyPair([]byte(t.ServerCert), []byte(t.ServerKey))
 	if err != nil {
 		return errors.Wrapf(err, `failed to load server certificate or private key`)
@@ -70,16 +523,20 @@ func (t *TLSConfig) unmarshalTLSConfig() error {
 		)
 
 		verifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-			for _, name := range t.RequiredClientIdentities {
-				opts := x509.VerifyOptions{
-					Roots:         clientCAPool,
-					CurrentTime:   time.Now(),
-					Intermediates: x509.NewCertPool(),
-					KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-					DNSName:       name,
-				}
-				if _, err := verifiedChains[0][0].Verify(opts); err == nil {
-					return nil
+			cert := verifiedChains[0][0]
+			opts := x509.VerifyOptions{
+				Roots:         clientCAPool,
+				CurrentTime:   time.Now(),
+				Intermediates: x509.NewCertPool(),
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}
+			if _, err := cert.Verify(opts); err == nil {
+				if name, ok := identity.Match(cert, t.RequiredClientIdentities); ok {
+					logger.Info("client certificate matched identity", lager.Data{
+						"identity": name,
+						"client":   identity.Name(cert),
+					})
+					return t.checkRevocation(verifiedChains[0])
 				}
 			}
 
@@ -100,6 +557,13 @@ func (t *TLSConfig) unmarshalTLSConfig() error {
 	return nil
 }
 
+func (t *TLSConfig) checkRevocation(chain []*x509.Certificate) error {
+	if t.CheckRevocation == nil {
+		return nil
+	}
+	return t.CheckRevocation(chain)
+}
+
 func NewConfig(osArgs []string) (*Config, error) {
 	var (
 		rootConfig Config
@@ -115,6 +579,34 @@ func NewConfig(osArgs []string) (*Config, error) {
 
 	serviceConfig.AddDefaults(Config{
 		BindAddress: "localhost:8081",
+		XtraBackup:  defaultXtraBackup,
+		TLS: TLSConfig{
+			Revocation: Revocation{
+				CRLReloadInterval: 5 * time.Minute,
+				OCSPTimeout:       5 * time.Second,
+				FailurePolicy:     "closed",
+			},
+		},
+		FanOut: FanOut{
+			MaxBufferBytes:     64 * 1024 * 1024,
+			SlowConsumerPolicy: "drop",
+		},
+		ObjectStore: ObjectStore{
+			Region:    "us-east-1",
+			ChunkSize: 16 * 1024 * 1024,
+		},
+		Maintenance: Maintenance{
+			PollInterval: 5 * time.Second,
+			InFlight:     "finish",
+		},
+		AuthGuard: AuthGuard{
+			BaseDelay: time.Second,
+			MaxDelay:  time.Minute,
+			Lockout:   15 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			Interval: time.Minute,
+		},
 	})
 
 	serviceConfig.AddFlags(flags)
@@ -126,7 +618,49 @@ func NewConfig(osArgs []string) (*Config, error) {
 		return &rootConfig, err
 	}
 
-	if err := rootConfig.TLS.unmarshalTLSConfig(); err != nil {
+	if err := rootConfig.TLS.unmarshalTLSConfig(rootConfig.Logger.Session("tls")); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.TLS.Revocation.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.XtraBackup.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	switch rootConfig.FanOut.SlowConsumerPolicy {
+	case "drop", "spill":
+	default:
+		return &rootConfig, errors.Errorf("invalid FanOut.SlowConsumerPolicy '%s', must be 'drop' or 'spill'", rootConfig.FanOut.SlowConsumerPolicy)
+	}
+
+	if err := rootConfig.ObjectStore.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.Maintenance.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.AuthGuard.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.RateLimit.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.Policy.validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := rootConfig.Tracing.Validate(); err != nil {
+		return &rootConfig, err
+	}
+
+	if err := validateInstances(rootConfig.Instances); err != nil {
 		return &rootConfig, err
 	}
 
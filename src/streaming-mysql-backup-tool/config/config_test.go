@@ -3,8 +3,13 @@ package config_test
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/tlsconfig/certtest"
+	"github.com/cloudfoundry/tracing"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -19,10 +24,39 @@ var _ = Describe("Config", func() {
 		osArgs          []string
 		serverCert      string
 		serverKey       string
+
+		backupEngine       string
+		binarySeries       string
+		slowConsumerPolicy string
+		objectStoreBucket  string
+		instanceName       string
+		bundleSnapshot     string
+		keyringTransition  string
+		redoLogArchiveDir  string
+		maintenanceFlight  string
+		authGuardBase      string
+		policyNetwork      string
+		policyOperation    string
+		tracingExporter    string
+		revocationPolicy   string
 	)
 
 	BeforeEach(func() {
 		enableMutualTLS = false
+		backupEngine = "auto"
+		binarySeries = "8.0"
+		slowConsumerPolicy = "spill"
+		objectStoreBucket = "backups"
+		instanceName = "mysql-2"
+		bundleSnapshot = "global_variables"
+		keyringTransition = ""
+		redoLogArchiveDir = "backup:/var/vcap/store/redo-log-archive"
+		maintenanceFlight = "cancel"
+		authGuardBase = "2s"
+		policyNetwork = "10.0.0.0/24"
+		policyOperation = "cancel"
+		tracingExporter = "otlp"
+		revocationPolicy = "open"
 
 		// Create certificates
 		clientAuthority, err := certtest.BuildCA("clientCA")
@@ -52,23 +86,140 @@ var _ = Describe("Config", func() {
 					"Password": "fake_password",
 				},
 				"XtraBackup": {
+				  "Engine": %q,
+				  "Binaries": {
+				    "5.7": "/var/vcap/packages/xtrabackup/bin/xtrabackup",
+				    %q: "/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup",
+				  },
 				  "DefaultsFile": "/etc/my.cnf",
 				  "TmpDir": "/tmp",
+				  "HistoryName": "nightly",
+				  "StallTimeout": "30m",
+				  "SlaveInfo": true,
+				  "SafeSlaveBackup": true,
+				  "SafeSlaveBackupTimeout": "5m",
+				  "GaleraInfo": true,
+				  "RedoLogArchiveDir": %q,
+				  "DiskGuard": {
+				    "MinFreeBytes": 10737418240,
+				    "MinFreePercent": 10,
+				    "RedoBytesPerSecond": 1048576,
+				    "ExpectedDuration": "1h",
+				    "CheckInterval": "10s",
+				  },
+				  "Bundle": {
+				    "Files": ["/var/vcap/jobs/pxc-mysql/config/my.cnf", "/var/vcap/store/pxc-mysql/grastate.dat"],
+				    "Snapshots": [%q, "server_version", "wsrep_provider_options"],
+				  },
+				  "Keyring": {
+				    "ComponentConfig": "/var/vcap/jobs/pxc-mysql/config/component_keyring_file.cnf",
+				    "TransitionKey": %q,
+				    "EncryptionKey": "some-keyring-key",
+				  },
+				},
+				"BackupLogs": {
+				  "Directory": "/var/vcap/data/backup-logs",
+				  "MaxCount": 5,
+				  "MaxAge": "24h",
+				},
+				"History": {
+				  "File": "/var/vcap/store/history.jsonl",
+				  "MaxRecords": 100,
+				},
+				"FanOut": {
+				  "JoinWindow": "30s",
+				  "SlowConsumerPolicy": %q,
+				  "SpillDir": "/tmp",
+				},
+				"ObjectStore": {
+				  "Endpoint": "https://minio.example.com:9000",
+				  "Bucket": %q,
+				  "Prefix": "mysql",
+				  "AccessKeyID": "some-access-key",
+				  "SecretAccessKey": "some-secret-key",
+				  "EncryptionKey": "some-encryption-key",
+				},
+				"Maintenance": {
+				  "SentinelFile": "/var/vcap/data/streaming-mysql-backup-tool/maintenance",
+				  "InFlight": %q,
+				  "AdminCredentials": {
+				    "Username": "admin_username",
+				    "Password": "admin_password",
+				  },
+				  "AdminIdentities": ["mysql-backup-admin"],
 				},
+				"AuthGuard": {
+				  "MaxFailures": 5,
+				  "BaseDelay": %q,
+				  "MaxDelay": "30s",
+				  "Lockout": "1h",
+				},
+				"RateLimit": {
+				  "Requests": 10,
+				},
+				"Policy": {
+				  "Rules": [
+				    {
+				      "Identities": ["backup-client"],
+				      "Networks": [%q],
+				      "Operations": ["backup", "status"],
+				      "Formats": ["xbstream"],
+				    },
+				    {
+				      "Identities": ["operator"],
+				      "Operations": ["status", %q],
+				    },
+				  ],
+				},
+				"Tracing": {
+				  "Exporter": %q,
+				  "Endpoint": "http://otel-collector.service.internal:4318/v1/traces",
+				},
+				"Instances": [
+				  {
+				    "Name": %q,
+				    "DefaultsFile": "/etc/mysql-2.cnf",
+				    "Binary": "/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup",
+				    "Credentials": {
+				      "Username": "instance_username",
+				      "Password": "instance_password",
+				    },
+				    "ClientIdentities": ["mysql-2-backup-client"],
+				  },
+				],
 				"TLS":{
 					"ServerCert": %q,
 					"ServerKey": %q,
 					"ClientCA": %q,
-					"EnableMutualTLS": %t
+					"EnableMutualTLS": %t,
+					"Revocation": {
+					  "CRLFile": "/var/vcap/jobs/streaming-mysql-backup-tool/config/client.crl",
+					  "OCSP": true,
+					  "FailurePolicy": %q,
+					},
 				},
 			}`
 
 		configuration := fmt.Sprintf(
 			configurationTemplate,
+			backupEngine,
+			binarySeries,
+			redoLogArchiveDir,
+			bundleSnapshot,
+			keyringTransition,
+			slowConsumerPolicy,
+			objectStoreBucket,
+			maintenanceFlight,
+			authGuardBase,
+			policyNetwork,
+			policyOperation,
+			tracingExporter,
+			instanceName,
 			serverCert,
 			serverKey,
 			clientCA,
 			enableMutualTLS,
+			revocationPolicy,
 		)
 
 		osArgs = []string{
@@ -81,8 +232,357 @@ var _ = Describe("Config", func() {
 		rootConfig, err := config.NewConfig(osArgs)
 		Expect(err).NotTo(HaveOccurred())
 
+		Expect(rootConfig.XtraBackup.Engine).To(Equal("auto"))
+		Expect(rootConfig.XtraBackup.Binaries).To(Equal(map[string]string{
+			"5.7": "/var/vcap/packages/xtrabackup/bin/xtrabackup",
+			"8.0": "/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup",
+		}))
 		Expect(rootConfig.XtraBackup.DefaultsFile).To(Equal("/etc/my.cnf"))
 		Expect(rootConfig.XtraBackup.TmpDir).To(Equal("/tmp"))
+		Expect(rootConfig.XtraBackup.HistoryName).To(Equal("nightly"))
+		Expect(rootConfig.XtraBackup.MaxPause).To(Equal(15 * time.Minute))
+		Expect(rootConfig.XtraBackup.StallTimeout).To(Equal(30 * time.Minute))
+		Expect(rootConfig.XtraBackup.SlaveInfo).To(BeTrue())
+		Expect(rootConfig.XtraBackup.SafeSlaveBackup).To(BeTrue())
+		Expect(rootConfig.XtraBackup.SafeSlaveBackupTimeout).To(Equal(5 * time.Minute))
+		Expect(rootConfig.XtraBackup.GaleraInfo).To(BeTrue())
+		Expect(rootConfig.XtraBackup.RedoLogArchiveDir).To(Equal("backup:/var/vcap/store/redo-log-archive"))
+	})
+
+	Context("When the redo log archive dir has no label", func() {
+		BeforeEach(func() {
+			redoLogArchiveDir = "/var/vcap/store/redo-log-archive"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid XtraBackup.RedoLogArchiveDir '/var/vcap/store/redo-log-archive', must be a label and an absolute path such as 'backup:/var/vcap/store/redo-log-archive'"))
+		})
+	})
+
+	Context("When the XtraBackup engine is invalid", func() {
+		BeforeEach(func() {
+			backupEngine = "mysqldump"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid XtraBackup.Engine 'mysqldump', must be 'xtrabackup', 'mariabackup', 'clone' or 'auto'"))
+		})
+	})
+
+	Context("When an XtraBackup binary is configured for an invalid series", func() {
+		BeforeEach(func() {
+			binarySeries = "8"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid XtraBackup.Binaries series '8', must be a major and minor version such as '8.0'"))
+		})
+	})
+
+	It("can load Bundle config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.XtraBackup.Bundle).To(Equal(config.Bundle{
+			Files:     []string{"/var/vcap/jobs/pxc-mysql/config/my.cnf", "/var/vcap/store/pxc-mysql/grastate.dat"},
+			Snapshots: []string{"global_variables", "server_version", "wsrep_provider_options"},
+		}))
+		Expect(rootConfig.XtraBackup.Bundle.Enabled()).To(BeTrue())
+
+		xtraBackup := rootConfig.Instances[0].XtraBackup(rootConfig.XtraBackup)
+		Expect(xtraBackup.Bundle).To(Equal(rootConfig.XtraBackup.Bundle))
+	})
+
+	Context("When a Bundle snapshot is invalid", func() {
+		BeforeEach(func() {
+			bundleSnapshot = "processlist"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid XtraBackup.Bundle.Snapshots 'processlist', must be 'global_variables', 'server_version' or 'wsrep_provider_options'"))
+		})
+	})
+
+	It("can load Keyring config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.XtraBackup.Keyring).To(Equal(config.Keyring{
+			ComponentConfig: "/var/vcap/jobs/pxc-mysql/config/component_keyring_file.cnf",
+			EncryptionKey:   "some-keyring-key",
+		}))
+		Expect(rootConfig.XtraBackup.Keyring.Enabled()).To(BeTrue())
+
+		xtraBackup := rootConfig.Instances[0].XtraBackup(rootConfig.XtraBackup)
+		Expect(xtraBackup.Keyring).To(Equal(rootConfig.XtraBackup.Keyring))
+	})
+
+	Context("When the Keyring has both a TransitionKey and an EncryptionKey", func() {
+		BeforeEach(func() {
+			keyringTransition = "some-transition-key"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("XtraBackup.Keyring.EncryptionKey and XtraBackup.Keyring.TransitionKey are mutually exclusive"))
+		})
+	})
+
+	Describe("Keyring.File", func() {
+		It("is the FileData of a keyring plugin", func() {
+			Expect(config.Keyring{FileData: "/var/vcap/store/mysql-keyring/keyring"}.File()).To(Equal("/var/vcap/store/mysql-keyring/keyring"))
+		})
+
+		It("is the path the ComponentConfig of a keyring component points at", func() {
+			componentConfig := filepath.Join(GinkgoT().TempDir(), "component_keyring_file.cnf")
+			Expect(os.WriteFile(componentConfig, []byte(`{"path": "/var/vcap/store/mysql-keyring/component_keyring_file", "read_only": false}`), 0600)).To(Succeed())
+
+			Expect(config.Keyring{ComponentConfig: componentConfig}.File()).To(Equal("/var/vcap/store/mysql-keyring/component_keyring_file"))
+		})
+
+		It("fails when the ComponentConfig does not point at a keyring file", func() {
+			componentConfig := filepath.Join(GinkgoT().TempDir(), "component_keyring_kmip.cnf")
+			Expect(os.WriteFile(componentConfig, []byte(`{"server_addr": "kmip.example.com"}`), 0600)).To(Succeed())
+
+			_, err := config.Keyring{ComponentConfig: componentConfig}.File()
+			Expect(err).To(MatchError(ContainSubstring("does not set the path of a keyring file")))
+		})
+	})
+
+	It("can load DiskGuard config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.XtraBackup.DiskGuard).To(Equal(config.DiskGuard{
+			MinFreeBytes:       10737418240,
+			MinFreePercent:     10,
+			RedoBytesPerSecond: 1048576,
+			ExpectedDuration:   time.Hour,
+			CheckInterval:      10 * time.Second,
+		}))
+		Expect(rootConfig.XtraBackup.DiskGuard.Enabled()).To(BeTrue())
+	})
+
+	It("can load BackupLogs config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.BackupLogs.Directory).To(Equal("/var/vcap/data/backup-logs"))
+		Expect(rootConfig.BackupLogs.MaxCount).To(Equal(5))
+		Expect(rootConfig.BackupLogs.MaxAge).To(Equal(24 * time.Hour))
+	})
+
+	It("can load History config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.History.File).To(Equal("/var/vcap/store/history.jsonl"))
+		Expect(rootConfig.History.MaxRecords).To(Equal(100))
+	})
+
+	It("can load FanOut config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.FanOut).To(Equal(config.FanOut{
+			JoinWindow:         30 * time.Second,
+			MaxBufferBytes:     64 * 1024 * 1024,
+			SlowConsumerPolicy: "spill",
+			SpillDir:           "/tmp",
+		}))
+	})
+
+	Context("When the FanOut slow consumer policy is invalid", func() {
+		BeforeEach(func() {
+			slowConsumerPolicy = "wait"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid FanOut.SlowConsumerPolicy 'wait', must be 'drop' or 'spill'"))
+		})
+	})
+
+	It("can load ObjectStore config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.ObjectStore).To(Equal(config.ObjectStore{
+			Endpoint:        "https://minio.example.com:9000",
+			Region:          "us-east-1",
+			Bucket:          "backups",
+			Prefix:          "mysql",
+			AccessKeyID:     "some-access-key",
+			SecretAccessKey: "some-secret-key",
+			ChunkSize:       16 * 1024 * 1024,
+			EncryptionKey:   "some-encryption-key",
+		}))
+		Expect(rootConfig.ObjectStore.Enabled()).To(BeTrue())
+	})
+
+	It("can load Maintenance config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Maintenance).To(Equal(config.Maintenance{
+			SentinelFile:     "/var/vcap/data/streaming-mysql-backup-tool/maintenance",
+			PollInterval:     5 * time.Second,
+			InFlight:         "cancel",
+			AdminCredentials: &config.Credentials{Username: "admin_username", Password: "admin_password"},
+			AdminIdentities:  []string{"mysql-backup-admin"},
+		}))
+	})
+
+	Context("When the Maintenance in-flight policy is invalid", func() {
+		BeforeEach(func() {
+			maintenanceFlight = "pause"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid Maintenance.InFlight 'pause', must be 'finish' or 'cancel'"))
+		})
+	})
+
+	It("can load AuthGuard and RateLimit config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.AuthGuard).To(Equal(config.AuthGuard{
+			MaxFailures: 5,
+			BaseDelay:   2 * time.Second,
+			MaxDelay:    30 * time.Second,
+			Lockout:     time.Hour,
+		}))
+		Expect(rootConfig.RateLimit).To(Equal(config.RateLimit{
+			Requests: 10,
+			Interval: time.Minute,
+		}))
+	})
+
+	Context("When the AuthGuard backs off for longer than its maximum", func() {
+		BeforeEach(func() {
+			authGuardBase = "1m"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("AuthGuard.BaseDelay '1m0s' must not exceed AuthGuard.MaxDelay '30s'"))
+		})
+	})
+
+	It("can load Policy config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Policy.Rules).To(Equal([]config.PolicyRule{
+			{
+				Identities: []string{"backup-client"},
+				Networks:   []string{"10.0.0.0/24"},
+				Operations: []string{"backup", "status"},
+				Formats:    []string{"xbstream"},
+			},
+			{
+				Identities: []string{"operator"},
+				Operations: []string{"status", "cancel"},
+			},
+		}))
+		Expect(rootConfig.Policy.Rules[0].IPNets()).To(ConsistOf(&net.IPNet{
+			IP:   net.IPv4(10, 0, 0, 0).To4(),
+			Mask: net.CIDRMask(24, 32),
+		}))
+	})
+
+	Context("When a Policy network is not a CIDR range", func() {
+		BeforeEach(func() {
+			policyNetwork = "10.0.0.1"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid Policy.Rules[0].Networks '10.0.0.1', must be a CIDR range"))
+		})
+	})
+
+	Context("When a Policy grants restoring backups", func() {
+		BeforeEach(func() {
+			policyOperation = "restore"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("Policy.Rules[1].Operations 'restore' is not supported, the tool does not restore backups"))
+		})
+	})
+
+	It("can load Tracing config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Tracing).To(Equal(tracing.Config{
+			Exporter: "otlp",
+			Endpoint: "http://otel-collector.service.internal:4318/v1/traces",
+		}))
+	})
+
+	Context("When the Tracing exporter is unknown", func() {
+		BeforeEach(func() {
+			tracingExporter = "jaeger"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid Tracing.Exporter 'jaeger', must be 'otlp', 'file' or empty"))
+		})
+	})
+
+	Context("When the ObjectStore has no bucket", func() {
+		BeforeEach(func() {
+			objectStoreBucket = ""
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("ObjectStore.Bucket must be set when ObjectStore.Endpoint is set"))
+		})
+	})
+
+	It("can load Instances config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.Instances).To(Equal([]config.Instance{{
+			Name:         "mysql-2",
+			DefaultsFile: "/etc/mysql-2.cnf",
+			Binary:       "/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup",
+			Credentials: &config.Credentials{
+				Username: "instance_username",
+				Password: "instance_password",
+			},
+			ClientIdentities: []string{"mysql-2-backup-client"},
+		}}))
+
+		xtraBackup := rootConfig.Instances[0].XtraBackup(rootConfig.XtraBackup)
+		Expect(xtraBackup.DefaultsFile).To(Equal("/etc/mysql-2.cnf"))
+		Expect(xtraBackup.TmpDir).To(Equal("/tmp"))
+		Expect(xtraBackup.Binary).To(Equal("/var/vcap/packages/xtrabackup-8.0/bin/xtrabackup"))
+		Expect(xtraBackup.Binaries).To(BeNil())
+		Expect(xtraBackup.StallTimeout).To(Equal(30 * time.Minute))
+	})
+
+	Context("When an instance name is invalid", func() {
+		BeforeEach(func() {
+			instanceName = "../mysql"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid Instances name '../mysql', must only contain letters, digits, '-' and '_'"))
+		})
 	})
 
 	It("can load a BindAddress option", func() {
@@ -92,6 +592,31 @@ var _ = Describe("Config", func() {
 		Expect(rootConfig.BindAddress).To(Equal(":1234"))
 	})
 
+	It("can load TLS Revocation config options", func() {
+		rootConfig, err := config.NewConfig(osArgs)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rootConfig.TLS.Revocation).To(Equal(config.Revocation{
+			CRLFile:           "/var/vcap/jobs/streaming-mysql-backup-tool/config/client.crl",
+			CRLReloadInterval: 5 * time.Minute,
+			OCSP:              true,
+			OCSPTimeout:       5 * time.Second,
+			FailurePolicy:     "open",
+		}))
+		Expect(rootConfig.TLS.Revocation.Enabled()).To(BeTrue())
+	})
+
+	Context("When the TLS Revocation failure policy is invalid", func() {
+		BeforeEach(func() {
+			revocationPolicy = "sometimes"
+		})
+
+		It("Fails to start with error", func() {
+			_, err := config.NewConfig(osArgs)
+			Expect(err).To(MatchError("invalid TLS.Revocation.FailurePolicy 'sometimes', must be 'closed' or 'open'"))
+		})
+	})
+
 	Context("When TLS Server credentials are misconfigured", func() {
 		Context("When server key is invalid", func() {
 			BeforeEach(func() {
@@ -175,3 +700,46 @@ var _ = Describe("Config", func() {
 
 	})
 })
+
+var _ = Describe("ReadXtraBackup", func() {
+	It("reads only the XtraBackup options, with defaults", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yml")
+		Expect(os.WriteFile(path, []byte(`
+XtraBackup:
+  Engine: mariabackup
+  DefaultsFile: /etc/my.cnf
+  TmpDir: /tmp
+  StallTimeout: 30m
+`), 0600)).To(Succeed())
+
+		xtraBackup, err := config.ReadXtraBackup(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(xtraBackup.Engine).To(Equal("mariabackup"))
+		Expect(xtraBackup.DefaultsFile).To(Equal("/etc/my.cnf"))
+		Expect(xtraBackup.TmpDir).To(Equal("/tmp"))
+		Expect(xtraBackup.StallTimeout).To(Equal(30 * time.Minute))
+		Expect(xtraBackup.MaxPause).To(Equal(15 * time.Minute))
+	})
+
+	It("validates the options", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yml")
+		Expect(os.WriteFile(path, []byte("XtraBackup:\n  Engine: mysqldump\n"), 0600)).To(Succeed())
+
+		_, err := config.ReadXtraBackup(path)
+		Expect(err).To(MatchError("invalid XtraBackup.Engine 'mysqldump', must be 'xtrabackup', 'mariabackup', 'clone' or 'auto'"))
+	})
+
+	It("rejects a transition key that would add lines to the option file of xtrabackup", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yml")
+		Expect(os.WriteFile(path, []byte(`
+XtraBackup:
+  DefaultsFile: /etc/my.cnf
+  TmpDir: /tmp
+  Keyring:
+    TransitionKey: "some-key\nkeyring-file-data=/tmp/keyring"
+`), 0600)).To(Succeed())
+
+		_, err := config.ReadXtraBackup(path)
+		Expect(err).To(MatchError("XtraBackup.Keyring.TransitionKey must not contain control characters"))
+	})
+})
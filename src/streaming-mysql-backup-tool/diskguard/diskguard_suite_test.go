@@ -0,0 +1,13 @@
+package diskguard_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiskGuard(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Disk Guard Suite")
+}
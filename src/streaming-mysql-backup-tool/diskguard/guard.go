@@ -0,0 +1,156 @@
+package diskguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+var ErrDiskFull = errors.New("DISK_FULL")
+
+const defaultCheckInterval = 5 * time.Second
+
+// Project until the next check, and as long again for the backup to stop.
+const projectedIntervals = 2
+
+type Usage struct {
+	Free  uint64
+	Total uint64
+}
+
+type Guard struct {
+	Path               string
+	MinFreeBytes       uint64
+	MinFreePercent     float64
+	RedoBytesPerSecond uint64
+	ExpectedDuration   time.Duration
+	CheckInterval      time.Duration
+	Logger             lager.Logger
+
+	// StatFS reports the disk usage of a path. It defaults to statfs(2).
+	StatFS func(path string) (Usage, error)
+}
+
+func (g Guard) Check() error {
+	usage, err := g.usage()
+	if err != nil {
+		return err
+	}
+
+	floor := g.floor(usage)
+	redo := g.RedoBytesPerSecond * uint64(g.ExpectedDuration/time.Second)
+	required := floor + redo
+
+	g.Logger.Info("checked free disk space", lager.Data{
+		"path":           g.Path,
+		"free_bytes":     usage.Free,
+		"floor_bytes":    floor,
+		"redo_estimate":  redo,
+		"required_bytes": required,
+	})
+
+	if usage.Free < required {
+		return fmt.Errorf("%w: %d bytes free on %s, %d bytes required (floor %d + estimated redo log %d)", ErrDiskFull, usage.Free, g.Path, required, floor, redo)
+	}
+
+	return nil
+}
+
+func (g Guard) Watch(ctx context.Context, cancel context.CancelCauseFunc) {
+	interval := g.CheckInterval
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		last   Usage
+		lastAt time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			usage, err := g.usage()
+			if err != nil {
+				g.Logger.Error("checking free disk space failed", err, lager.Data{"path": g.Path})
+				continue
+			}
+
+			if err := g.checkFloor(usage); err != nil {
+				cancel(err)
+				return
+			}
+			if !lastAt.IsZero() {
+				if err := g.checkProjection(usage, last, now.Sub(lastAt), interval); err != nil {
+					cancel(err)
+					return
+				}
+			}
+			last, lastAt = usage, now
+		}
+	}
+}
+
+func (g Guard) CheckFloor() error {
+	usage, err := g.usage()
+	if err != nil {
+		return err
+	}
+	return g.checkFloor(usage)
+}
+
+func (g Guard) checkFloor(usage Usage) error {
+	if floor := g.floor(usage); usage.Free < floor {
+		return fmt.Errorf("%w: %d bytes free on %s, below the floor of %d bytes", ErrDiskFull, usage.Free, g.Path, floor)
+	}
+	return nil
+}
+
+func (g Guard) checkProjection(usage, last Usage, elapsed, interval time.Duration) error {
+	if usage.Free >= last.Free || elapsed <= 0 {
+		return nil
+	}
+
+	rate := float64(last.Free-usage.Free) / elapsed.Seconds()
+	projected := rate * (projectedIntervals * interval).Seconds()
+	floor := g.floor(usage)
+	if float64(usage.Free-floor) < projected {
+		return fmt.Errorf("%w: %d bytes free on %s, falling by %.0f bytes per second, would drop below the floor of %d bytes before the backup could be stopped", ErrDiskFull, usage.Free, g.Path, rate, floor)
+	}
+	return nil
+}
+
+func (g Guard) floor(usage Usage) uint64 {
+	floor := g.MinFreeBytes
+	if byPercent := uint64(float64(usage.Total) * g.MinFreePercent / 100); byPercent > floor {
+		floor = byPercent
+	}
+	return floor
+}
+
+func (g Guard) usage() (Usage, error) {
+	if g.StatFS != nil {
+		return g.StatFS(g.Path)
+	}
+	return statFS(g.Path)
+}
+
+func statFS(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, err
+	}
+
+	return Usage{
+		Free:  st.Bavail * uint64(st.Bsize),
+		Total: st.Blocks * uint64(st.Bsize),
+	}, nil
+}
@@ -0,0 +1,144 @@
+package diskguard_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+)
+
+var _ = Describe("Guard", func() {
+	var (
+		mu    sync.Mutex
+		usage diskguard.Usage
+		guard diskguard.Guard
+	)
+
+	setUsage := func(u diskguard.Usage) {
+		mu.Lock()
+		defer mu.Unlock()
+		usage = u
+	}
+
+	BeforeEach(func() {
+		setUsage(diskguard.Usage{Free: 100, Total: 1000})
+		guard = diskguard.Guard{
+			Path:          "/var/vcap/store/xtrabackup_tmp",
+			CheckInterval: 10 * time.Millisecond,
+			Logger:        lagertest.NewTestLogger("diskguard"),
+			StatFS: func(string) (diskguard.Usage, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return usage, nil
+			},
+		}
+	})
+
+	Describe("Check", func() {
+		It("succeeds when there is enough free space", func() {
+			guard.MinFreeBytes = 50
+			Expect(guard.Check()).To(Succeed())
+		})
+
+		It("fails when the free space is below MinFreeBytes", func() {
+			guard.MinFreeBytes = 150
+
+			err := guard.Check()
+			Expect(err).To(MatchError(diskguard.ErrDiskFull))
+			Expect(err).To(MatchError("DISK_FULL: 100 bytes free on /var/vcap/store/xtrabackup_tmp, 150 bytes required (floor 150 + estimated redo log 0)"))
+		})
+
+		It("fails when the free space is below MinFreePercent", func() {
+			guard.MinFreeBytes = 50
+			guard.MinFreePercent = 15
+
+			Expect(guard.Check()).To(MatchError(ContainSubstring("150 bytes required (floor 150")))
+		})
+
+		It("reserves room for the redo log written during the backup", func() {
+			guard.MinFreeBytes = 50
+			guard.RedoBytesPerSecond = 1
+			guard.ExpectedDuration = time.Minute
+
+			Expect(guard.Check()).To(MatchError(ContainSubstring("110 bytes required (floor 50 + estimated redo log 60)")))
+		})
+
+		It("returns errors from reading the disk usage", func() {
+			guard.StatFS = func(string) (diskguard.Usage, error) {
+				return diskguard.Usage{}, errors.New("no such file or directory")
+			}
+
+			err := guard.Check()
+			Expect(err).To(MatchError("no such file or directory"))
+			Expect(err).NotTo(MatchError(diskguard.ErrDiskFull))
+		})
+
+		It("reads the usage of the real disk by default", func() {
+			guard.StatFS = nil
+			guard.Path = os.TempDir()
+
+			Expect(guard.Check()).To(Succeed())
+		})
+	})
+
+	Describe("Watch", func() {
+		It("cancels the backup once the free space drops below the floor", func() {
+			guard.MinFreeBytes = 50
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			go guard.Watch(ctx, cancel)
+			Consistently(ctx.Done(), "50ms").ShouldNot(BeClosed())
+
+			setUsage(diskguard.Usage{Free: 40, Total: 1000})
+			Eventually(ctx.Done()).Should(BeClosed())
+			Expect(context.Cause(ctx)).To(MatchError(diskguard.ErrDiskFull))
+			Expect(context.Cause(ctx)).To(MatchError("DISK_FULL: 40 bytes free on /var/vcap/store/xtrabackup_tmp, below the floor of 50 bytes"))
+		})
+
+		It("cancels the backup when the free space falls too fast to stop it above the floor", func() {
+			guard.MinFreeBytes = 50
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			go guard.Watch(ctx, cancel)
+			Consistently(ctx.Done(), "50ms").ShouldNot(BeClosed())
+
+			setUsage(diskguard.Usage{Free: 55, Total: 1000})
+			Eventually(ctx.Done()).Should(BeClosed())
+			Expect(context.Cause(ctx)).To(MatchError(diskguard.ErrDiskFull))
+			Expect(context.Cause(ctx)).To(MatchError(MatchRegexp(`^DISK_FULL: 55 bytes free on /var/vcap/store/xtrabackup_tmp, falling by \d+ bytes per second, would drop below the floor of 50 bytes before the backup could be stopped$`)))
+		})
+
+		It("keeps the backup running while the free space stays above the floor", func() {
+			guard.MinFreeBytes = 50
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+
+			go guard.Watch(ctx, cancel)
+			Consistently(ctx.Done(), "50ms").ShouldNot(BeClosed())
+
+			setUsage(diskguard.Usage{Free: 95, Total: 1000})
+			Consistently(ctx.Done(), "50ms").ShouldNot(BeClosed())
+		})
+
+		It("returns once the backup is done", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				guard.Watch(ctx, cancel)
+			}()
+
+			cancel(nil)
+			Eventually(done).Should(BeClosed())
+		})
+	})
+})
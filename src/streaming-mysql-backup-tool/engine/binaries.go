@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+// Binaries picks the xtrabackup binary for the release series of the server.
+type Binaries struct {
+	Writer        xtrabackup.Writer
+	Binaries      map[string]string
+	ServerVersion func(ctx context.Context) (serverversion.Version, error)
+	Logger        lager.Logger
+}
+
+func (b Binaries) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	logger := b.Logger.WithData(lager.Data{"backup_id": req.ID})
+
+	version, err := b.ServerVersion(ctx)
+	if err != nil {
+		logger.Error("detecting the server version failed", err)
+		return fmt.Errorf("FATAL: selecting the xtrabackup binary failed: %w", err)
+	}
+
+	binary, err := b.binaryFor(version)
+	if err != nil {
+		logger.Error("selecting the xtrabackup binary failed", err)
+		return err
+	}
+
+	logger.Info("selected xtrabackup binary", lager.Data{
+		"binary":         binary,
+		"server_version": version.Raw,
+	})
+
+	x := b.Writer
+	x.Binary = binary
+	return x.StreamTo(ctx, req, w)
+}
+
+func (b Binaries) binaryFor(version serverversion.Version) (string, error) {
+	series := fmt.Sprintf("%d.%d", version.Major, version.Minor)
+
+	binary, ok := b.Binaries[series]
+	if !ok {
+		return "", fmt.Errorf("FATAL: no xtrabackup binary is compatible with MySQL %s, binaries are configured for %s", version, b.series())
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		return "", fmt.Errorf("FATAL: the xtrabackup binary for MySQL %s is not available: %w", series, err)
+	}
+	return binary, nil
+}
+
+func (b Binaries) series() string {
+	series := make([]string, 0, len(b.Binaries))
+	for s := range b.Binaries {
+		series = append(series, s)
+	}
+	sort.Strings(series)
+	return strings.Join(series, ", ")
+}
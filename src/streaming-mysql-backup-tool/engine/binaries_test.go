@@ -0,0 +1,85 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/engine"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+var _ = Describe("Binaries", func() {
+	var (
+		logger   *lagertest.TestLogger
+		binDir   string
+		version  string
+		binaries engine.Binaries
+		output   *gbytes.Buffer
+		log      *gbytes.Buffer
+	)
+
+	writeBinary := func(name, script string) string {
+		path := filepath.Join(binDir, name)
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("engine")
+		binDir = GinkgoT().TempDir()
+		output = gbytes.NewBuffer()
+		log = gbytes.NewBuffer()
+
+		binaries = engine.Binaries{
+			Writer: xtrabackup.Writer{
+				DefaultsFile: "/etc/my.cnf",
+				TmpDir:       binDir,
+				Logger:       logger,
+			},
+			Binaries: map[string]string{
+				"5.7": writeBinary("xtrabackup-2.4", "echo xtrabackup 2.4"),
+				"8.0": writeBinary("xtrabackup-8.0", "echo xtrabackup 8.0"),
+			},
+			ServerVersion: func(context.Context) (serverversion.Version, error) {
+				return serverversion.Parse(version)
+			},
+			Logger: logger,
+		}
+	})
+
+	It("runs the binary configured for the series of the server", func() {
+		version = "8.0.35-27"
+
+		Expect(binaries.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: log}, output)).To(Succeed())
+		Expect(output).To(gbytes.Say("xtrabackup 8.0"))
+		Expect(logger).To(gbytes.Say(`"binary":".*/xtrabackup-8.0"`))
+
+		version = "5.7.44-log"
+
+		Expect(binaries.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: log}, output)).To(Succeed())
+		Expect(output).To(gbytes.Say("xtrabackup 2.4"))
+	})
+
+	It("refuses servers no binary is configured for", func() {
+		version = "8.4.0"
+
+		err := binaries.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: log}, output)
+		Expect(err).To(MatchError("FATAL: no xtrabackup binary is compatible with MySQL 8.4.0, binaries are configured for 5.7, 8.0"))
+	})
+
+	It("refuses servers whose binary is missing", func() {
+		version = "8.0.35"
+		binaries.Binaries["8.0"] = filepath.Join(binDir, "missing")
+
+		err := binaries.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: log}, output)
+		Expect(err).To(MatchError(HavePrefix("FATAL: the xtrabackup binary for MySQL 8.0 is not available")))
+	})
+})
@@ -0,0 +1,52 @@
+// Package engine picks the tool a backup is taken with.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+)
+
+const (
+	XtraBackup  = "xtrabackup"
+	MariaBackup = "mariabackup"
+	// Clone takes backups with the CLONE plugin of the server.
+	Clone = "clone"
+	// Auto picks mariabackup for MariaDB servers and xtrabackup otherwise.
+	Auto = "auto"
+)
+
+// Selector picks mariabackup or xtrabackup from the server version before every backup.
+type Selector struct {
+	XtraBackup    api.BackupWriter
+	MariaBackup   api.BackupWriter
+	ServerVersion func(ctx context.Context) (serverversion.Version, error)
+	Logger        lager.Logger
+}
+
+func (s Selector) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	logger := s.Logger.WithData(lager.Data{"backup_id": req.ID})
+
+	version, err := s.ServerVersion(ctx)
+	if err != nil {
+		logger.Error("detecting the server version failed", err)
+		return fmt.Errorf("FATAL: detecting the backup engine failed: %w", err)
+	}
+
+	writer, name := s.XtraBackup, XtraBackup
+	if version.MariaDB() {
+		writer, name = s.MariaBackup, MariaBackup
+	}
+
+	logger.Info("selected backup engine", lager.Data{
+		"engine":         name,
+		"server_version": version.Raw,
+	})
+
+	return writer.StreamTo(ctx, req, w)
+}
@@ -0,0 +1,75 @@
+package engine_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/engine"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+)
+
+type namedWriter string
+
+func (n namedWriter) StreamTo(_ context.Context, _ api.BackupRequest, w io.Writer) error {
+	_, err := io.WriteString(w, string(n))
+	return err
+}
+
+var _ = Describe("Selector", func() {
+	var (
+		logger     *lagertest.TestLogger
+		version    string
+		versionErr error
+		selector   engine.Selector
+		output     bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("engine")
+		versionErr = nil
+		output.Reset()
+		selector = engine.Selector{
+			XtraBackup:  namedWriter("xtrabackup"),
+			MariaBackup: namedWriter("mariabackup"),
+			ServerVersion: func(context.Context) (serverversion.Version, error) {
+				if versionErr != nil {
+					return serverversion.Version{}, versionErr
+				}
+				return serverversion.Parse(version)
+			},
+			Logger: logger,
+		}
+	})
+
+	It("takes backups of MySQL with xtrabackup", func() {
+		version = "8.0.35-27"
+
+		Expect(selector.StreamTo(context.Background(), api.BackupRequest{ID: "some-id"}, &output)).To(Succeed())
+		Expect(output.String()).To(Equal("xtrabackup"))
+		Expect(logger.Buffer()).To(gbytes.Say(`"engine":"xtrabackup"`))
+	})
+
+	It("takes backups of MariaDB with mariabackup", func() {
+		version = "10.6.16-MariaDB-log"
+
+		Expect(selector.StreamTo(context.Background(), api.BackupRequest{ID: "some-id"}, &output)).To(Succeed())
+		Expect(output.String()).To(Equal("mariabackup"))
+		Expect(logger.Buffer()).To(gbytes.Say(`"engine":"mariabackup"`))
+	})
+
+	It("fails the backup when the server version cannot be detected", func() {
+		versionErr = errors.New("connection refused")
+
+		err := selector.StreamTo(context.Background(), api.BackupRequest{ID: "some-id"}, &output)
+		Expect(err).To(MatchError("FATAL: detecting the backup engine failed: connection refused"))
+		Expect(output.Len()).To(BeZero())
+	})
+})
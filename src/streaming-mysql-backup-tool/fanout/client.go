@@ -0,0 +1,194 @@
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+)
+
+const (
+	spillReadSize   = 64 * 1024
+	spillCheckBytes = 16 * 1024 * 1024
+)
+
+type client struct {
+	req       api.BackupRequest
+	maxBuffer int
+	policy    Policy
+	spillDir  string
+	guard     *diskguard.Guard
+	drained   func()
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	chunks   [][]byte
+	buffered int
+
+	spill     *os.File
+	spilled   int64
+	spillRead int64
+	checked   int64
+
+	done    bool
+	err     error
+	dropped error
+}
+
+func newClient(req api.BackupRequest, maxBuffer int, policy Policy, spillDir string, guard *diskguard.Guard) *client {
+	c := &client{
+		req:       req,
+		maxBuffer: maxBuffer,
+		policy:    policy,
+		spillDir:  spillDir,
+		guard:     guard,
+		drained:   func() {},
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// A client that has caught up always has room.
+func (c *client) hasRoom(n int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dropped != nil || c.done {
+		return false
+	}
+	pending := int64(c.buffered) + c.spilled - c.spillRead
+	return pending == 0 || pending+int64(n) <= int64(c.maxBuffer)
+}
+
+// offer buffers p for the client without waiting for it.
+func (c *client) offer(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.cond.Broadcast()
+
+	if c.dropped != nil || c.done {
+		return
+	}
+
+	if c.spill == nil && (c.buffered == 0 || c.buffered+len(p) <= c.maxBuffer) {
+		c.chunks = append(c.chunks, append([]byte(nil), p...))
+		c.buffered += len(p)
+		return
+	}
+
+	if c.policy != Spill {
+		c.dropped = fmt.Errorf("%w: fell more than %d bytes behind the other clients of the shared backup", ErrSlowConsumer, c.maxBuffer)
+		return
+	}
+
+	if c.guard != nil && (c.spill == nil || c.spilled-c.checked >= spillCheckBytes) {
+		c.checked = c.spilled
+		if err := c.guard.CheckFloor(); err != nil {
+			c.dropped = fmt.Errorf("%w: spilling the shared backup to disk failed: %v", ErrSlowConsumer, err)
+			return
+		}
+	}
+
+	if c.spill == nil {
+		f, err := os.CreateTemp(c.spillDir, "backup-spill-")
+		if err != nil {
+			c.dropped = fmt.Errorf("%w: spilling the shared backup to disk failed: %v", ErrSlowConsumer, err)
+			return
+		}
+		c.spill = f
+	}
+
+	n, err := c.spill.WriteAt(p, c.spilled)
+	c.spilled += int64(n)
+	if err != nil {
+		c.dropped = fmt.Errorf("%w: spilling the shared backup to disk failed: %v", ErrSlowConsumer, err)
+	}
+}
+
+func (c *client) finish(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done, c.err = true, err
+	c.cond.Broadcast()
+}
+
+func (c *client) copyTo(ctx context.Context, w io.Writer) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.cond.Broadcast()
+	})
+	defer stop()
+
+	buf := make([]byte, spillReadSize)
+	for {
+		c.mu.Lock()
+		for len(c.chunks) == 0 && c.spillRead == c.spilled && !c.done && c.dropped == nil && ctx.Err() == nil {
+			c.cond.Wait()
+		}
+
+		var p []byte
+		switch {
+		case c.dropped != nil:
+			c.mu.Unlock()
+			return c.dropped
+		case ctx.Err() != nil:
+			c.mu.Unlock()
+			return context.Cause(ctx)
+		case len(c.chunks) > 0:
+			p = c.chunks[0]
+			c.chunks[0] = nil
+			c.chunks = c.chunks[1:]
+			c.buffered -= len(p)
+			c.mu.Unlock()
+			c.drained()
+		case c.spillRead < c.spilled:
+			spill, offset := c.spill, c.spillRead
+			n := min(int64(len(buf)), c.spilled-c.spillRead)
+			c.mu.Unlock()
+
+			if _, err := spill.ReadAt(buf[:n], offset); err != nil {
+				return fmt.Errorf("reading the spilled backup failed: %w", err)
+			}
+			p = buf[:n]
+
+			c.mu.Lock()
+			c.spillRead += n
+			if c.spillRead == c.spilled {
+				c.removeSpill()
+			}
+			c.mu.Unlock()
+			c.drained()
+		default:
+			c.mu.Unlock()
+			return c.err
+		}
+
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+}
+
+// discard releases the buffers of a client that has left its run.
+func (c *client) discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.chunks, c.buffered = nil, 0
+	c.removeSpill()
+}
+
+func (c *client) removeSpill() {
+	if c.spill == nil {
+		return
+	}
+	_ = c.spill.Close()
+	_ = os.Remove(c.spill.Name())
+	c.spill, c.spilled, c.spillRead, c.checked = nil, 0, 0, 0
+}
@@ -0,0 +1,13 @@
+package fanout_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFanOut(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fan Out Suite")
+}
@@ -0,0 +1,226 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+)
+
+type Policy string
+
+const (
+	// Drop fails the backup of the slow client with ErrSlowConsumer.
+	Drop  Policy = "drop"
+	Spill Policy = "spill"
+)
+
+var ErrSlowConsumer = errors.New("SLOW_CONSUMER")
+
+var errNoClients = errors.New("every client of the shared backup has gone away")
+
+// Writer serves one backup to every compatible request arriving within JoinWindow.
+type Writer struct {
+	BackupWriter   api.BackupWriter
+	JoinWindow     time.Duration
+	MaxBufferBytes int
+	SlowConsumer   Policy
+	SpillDir       string
+	DiskGuard      *diskguard.Guard
+	Logger         lager.Logger
+
+	mu   sync.Mutex
+	open map[string]*run
+}
+
+func (f *Writer) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	r, c := f.join(req)
+	defer c.discard()
+	defer r.leave(c)
+
+	return c.copyTo(ctx, w)
+}
+
+func (f *Writer) join(req api.BackupRequest) (*run, *client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := req.Format + "\x00" + req.HistoryName
+	r, ok := f.open[key]
+	if !ok {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		r = &run{req: req, ctx: ctx, cancel: cancel}
+		r.cond = sync.NewCond(&r.mu)
+		if f.open == nil {
+			f.open = map[string]*run{}
+		}
+		f.open[key] = r
+		go f.start(key, r)
+	} else {
+		f.Logger.Info("joining shared backup", lager.Data{
+			"backup_id":        req.ID,
+			"shared_backup_id": r.req.ID,
+		})
+	}
+
+	c := newClient(req, f.MaxBufferBytes, f.SlowConsumer, f.SpillDir, f.DiskGuard)
+	c.drained = r.wake
+	r.mu.Lock()
+	r.clients = append(r.clients, c)
+	r.mu.Unlock()
+
+	return r, c
+}
+
+func (f *Writer) start(key string, r *run) {
+	time.Sleep(f.JoinWindow)
+
+	f.mu.Lock()
+	delete(f.open, key)
+	f.mu.Unlock()
+
+	r.mu.Lock()
+	ids := make([]string, 0, len(r.clients))
+	for _, c := range r.clients {
+		ids = append(ids, c.req.ID)
+	}
+	r.running = len(ids) > 0
+	r.mu.Unlock()
+
+	if len(ids) == 0 {
+		r.cancel(errNoClients)
+		return
+	}
+
+	stop := context.AfterFunc(r.ctx, r.wake)
+	defer stop()
+
+	f.Logger.Info("starting shared backup", lager.Data{
+		"backup_id": r.req.ID,
+		"clients":   ids,
+	})
+
+	err := f.BackupWriter.StreamTo(r.ctx, api.BackupRequest{
+		ID:          r.req.ID,
+		Format:      r.req.Format,
+		HistoryName: r.req.HistoryName,
+		Log:         logWriter{r},
+		Started:     r.started,
+		SetMetadata: r.setMetadata,
+	}, streamWriter{r})
+	r.finish(err)
+}
+
+// run is a single backup shared by the clients that joined it.
+type run struct {
+	req    api.BackupRequest
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	clients []*client
+	running bool
+}
+
+// wake lets a write waiting for a client to make room check again.
+func (r *run) wake() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cond.Broadcast()
+}
+
+func (r *run) leave(c *client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.clients {
+		if r.clients[i] == c {
+			r.clients = append(r.clients[:i], r.clients[i+1:]...)
+			break
+		}
+	}
+	if r.running && len(r.clients) == 0 {
+		r.cancel(errNoClients)
+	}
+	r.cond.Broadcast()
+}
+
+func (r *run) started(p api.Process) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.clients {
+		if c.req.Started != nil {
+			c.req.Started(p)
+		}
+	}
+}
+
+func (r *run) setMetadata(key, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.clients {
+		c.req.AddMetadata(key, value)
+	}
+}
+
+func (r *run) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running = false
+	for _, c := range r.clients {
+		c.finish(err)
+	}
+	r.cancel(nil)
+}
+
+// streamWriter never gets ahead of the fastest client.
+type streamWriter struct{ r *run }
+
+func (s streamWriter) Write(p []byte) (int, error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+
+	for {
+		if len(s.r.clients) == 0 {
+			return 0, errNoClients
+		}
+		if s.r.ctx.Err() != nil {
+			return 0, context.Cause(s.r.ctx)
+		}
+		if slices.ContainsFunc(s.r.clients, func(c *client) bool { return c.hasRoom(len(p)) }) {
+			break
+		}
+		s.r.cond.Wait()
+	}
+
+	for _, c := range s.r.clients {
+		c.offer(p)
+	}
+	return len(p), nil
+}
+
+// A client that left is not written to, as its log may have been closed.
+type logWriter struct{ r *run }
+
+func (l logWriter) Write(p []byte) (int, error) {
+	l.r.mu.Lock()
+	defer l.r.mu.Unlock()
+
+	for _, c := range l.r.clients {
+		if c.req.Log != nil {
+			_, _ = c.req.Log.Write(p)
+		}
+	}
+	return len(p), nil
+}
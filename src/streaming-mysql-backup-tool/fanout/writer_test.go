@@ -0,0 +1,286 @@
+package fanout_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/fanout"
+)
+
+var _ = Describe("fanout.Writer", func() {
+	var (
+		backupWriter *stubBackupWriter
+		writer       *fanout.Writer
+		spillDir     string
+	)
+
+	BeforeEach(func() {
+		backupWriter = &stubBackupWriter{chunks: 8, chunkSize: 1024}
+		spillDir = GinkgoT().TempDir()
+		writer = &fanout.Writer{
+			BackupWriter:   backupWriter,
+			JoinWindow:     100 * time.Millisecond,
+			MaxBufferBytes: 1024 * 1024,
+			SlowConsumer:   fanout.Drop,
+			SpillDir:       spillDir,
+			Logger:         lagertest.NewTestLogger("fanout"),
+		}
+	})
+
+	type result struct {
+		backup bytes.Buffer
+		log    bytes.Buffer
+		err    error
+	}
+
+	streamTo := func(ctx context.Context, id, format string, w io.Writer) *result {
+		res := &result{}
+		if w == nil {
+			w = &res.backup
+		}
+		res.err = writer.StreamTo(ctx, api.BackupRequest{ID: id, Format: format, Log: &res.log}, w)
+		return res
+	}
+
+	concurrently := func(fns ...func()) {
+		var wg sync.WaitGroup
+		for _, fn := range fns {
+			wg.Add(1)
+			go func(fn func()) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				fn()
+			}(fn)
+		}
+		wg.Wait()
+	}
+
+	It("serves requests that arrive within the join window from a single backup", func() {
+		var first, second *result
+		concurrently(
+			func() { first = streamTo(context.Background(), "first", "xbstream", nil) },
+			func() {
+				time.Sleep(20 * time.Millisecond)
+				second = streamTo(context.Background(), "second", "xbstream", nil)
+			},
+		)
+
+		Expect(backupWriter.calls.Load()).To(BeEquivalentTo(1))
+		Expect(backupWriter.idArg).To(Equal("first"))
+		for _, res := range []*result{first, second} {
+			Expect(res.err).NotTo(HaveOccurred())
+			Expect(res.backup.Bytes()).To(Equal(backupWriter.content()))
+			Expect(res.log.String()).To(Equal("xtrabackup output\n"))
+		}
+	})
+
+	It("takes separate backups for incompatible requests", func() {
+		var tar, xbstream *result
+		concurrently(
+			func() { tar = streamTo(context.Background(), "first", "tar", nil) },
+			func() { xbstream = streamTo(context.Background(), "second", "xbstream", nil) },
+		)
+
+		Expect(backupWriter.calls.Load()).To(BeEquivalentTo(2))
+		Expect(tar.err).NotTo(HaveOccurred())
+		Expect(xbstream.err).NotTo(HaveOccurred())
+	})
+
+	It("takes a new backup for requests after the join window", func() {
+		Expect(streamTo(context.Background(), "first", "xbstream", nil).err).NotTo(HaveOccurred())
+		Expect(streamTo(context.Background(), "second", "xbstream", nil).err).NotTo(HaveOccurred())
+
+		Expect(backupWriter.calls.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("reports the error of the backup to every client", func() {
+		backupWriter.err = errors.New("FATAL: some xtrabackup failure")
+
+		var first, second *result
+		concurrently(
+			func() { first = streamTo(context.Background(), "first", "xbstream", nil) },
+			func() { second = streamTo(context.Background(), "second", "xbstream", nil) },
+		)
+
+		Expect(first.err).To(MatchError("FATAL: some xtrabackup failure"))
+		Expect(second.err).To(MatchError("FATAL: some xtrabackup failure"))
+	})
+
+	When("a client leaves", func() {
+		It("keeps streaming the backup to the other clients", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			backupWriter.beforeWrite = func() { cancel(api.ErrCancelled) }
+
+			var leaving, staying *result
+			concurrently(
+				func() { leaving = streamTo(ctx, "leaving", "xbstream", nil) },
+				func() { staying = streamTo(context.Background(), "staying", "xbstream", nil) },
+			)
+
+			Expect(leaving.err).To(MatchError(api.ErrCancelled))
+			Expect(staying.err).NotTo(HaveOccurred())
+			Expect(staying.backup.Bytes()).To(Equal(backupWriter.content()))
+		})
+
+		It("stops the backup once every client has left", func() {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			backupWriter.blockUntilDone = true
+			backupWriter.stopped = make(chan struct{})
+			backupWriter.beforeWrite = func() { cancel(api.ErrCancelled) }
+
+			res := streamTo(ctx, "leaving", "xbstream", nil)
+			Expect(res.err).To(MatchError(api.ErrCancelled))
+			Eventually(backupWriter.stopped).Should(BeClosed())
+		})
+	})
+
+	When("a client falls behind", func() {
+		var slow *blockingWriter
+
+		BeforeEach(func() {
+			slow = &blockingWriter{unblock: make(chan struct{})}
+			writer.MaxBufferBytes = 2048
+			backupWriter.delay = 10 * time.Millisecond
+		})
+
+		streamToSlowAndFast := func() (slowResult, fastResult *result) {
+			concurrently(
+				func() { slowResult = streamTo(context.Background(), "slow", "xbstream", slow) },
+				func() {
+					fastResult = streamTo(context.Background(), "fast", "xbstream", nil)
+					close(slow.unblock)
+				},
+			)
+			return slowResult, fastResult
+		}
+
+		It("drops the slow client without holding up the others", func() {
+			slowResult, fastResult := streamToSlowAndFast()
+
+			Expect(fastResult.err).NotTo(HaveOccurred())
+			Expect(fastResult.backup.Bytes()).To(Equal(backupWriter.content()))
+			Expect(slowResult.err).To(MatchError(fanout.ErrSlowConsumer))
+			Expect(slowResult.err).To(MatchError("SLOW_CONSUMER: fell more than 2048 bytes behind the other clients of the shared backup"))
+		})
+
+		It("slows the backup down for a client on its own", func() {
+			go func() {
+				time.Sleep(200 * time.Millisecond)
+				close(slow.unblock)
+			}()
+
+			res := streamTo(context.Background(), "slow", "xbstream", slow)
+
+			Expect(res.err).NotTo(HaveOccurred())
+			Expect(slow.buf.Bytes()).To(Equal(backupWriter.content()))
+		})
+
+		When("the slow consumer policy is to spill", func() {
+			BeforeEach(func() {
+				writer.SlowConsumer = fanout.Spill
+			})
+
+			It("buffers the rest of the backup on disk", func() {
+				slowResult, fastResult := streamToSlowAndFast()
+
+				Expect(fastResult.err).NotTo(HaveOccurred())
+				Expect(slowResult.err).NotTo(HaveOccurred())
+				Expect(slow.buf.Bytes()).To(Equal(backupWriter.content()))
+
+				entries, err := os.ReadDir(spillDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(BeEmpty())
+			})
+
+			It("drops the slow client rather than spill below the free space floor", func() {
+				writer.DiskGuard = &diskguard.Guard{
+					Path:         spillDir,
+					MinFreeBytes: 4096,
+					Logger:       lagertest.NewTestLogger("disk-guard"),
+					StatFS: func(string) (diskguard.Usage, error) {
+						return diskguard.Usage{Free: 1024, Total: 1024 * 1024}, nil
+					},
+				}
+
+				slowResult, fastResult := streamToSlowAndFast()
+
+				Expect(fastResult.err).NotTo(HaveOccurred())
+				Expect(slowResult.err).To(MatchError(fanout.ErrSlowConsumer))
+				Expect(slowResult.err).To(MatchError(ContainSubstring("DISK_FULL: 1024 bytes free on " + spillDir)))
+			})
+		})
+	})
+})
+
+type stubBackupWriter struct {
+	chunks      int
+	chunkSize   int
+	delay       time.Duration
+	err         error
+	beforeWrite func()
+	// blockUntilDone closes stopped once the context is done.
+	blockUntilDone bool
+	stopped        chan struct{}
+
+	calls atomic.Int32
+	mu    sync.Mutex
+	idArg string
+}
+
+func (s *stubBackupWriter) content() []byte {
+	var b []byte
+	for i := 0; i < s.chunks; i++ {
+		b = append(b, bytes.Repeat([]byte{byte('a' + i)}, s.chunkSize)...)
+	}
+	return b
+}
+
+func (s *stubBackupWriter) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	s.calls.Add(1)
+	s.mu.Lock()
+	s.idArg = req.ID
+	s.mu.Unlock()
+
+	_, _ = io.WriteString(req.Log, "xtrabackup output\n")
+	if s.beforeWrite != nil {
+		s.beforeWrite()
+	}
+
+	content := s.content()
+	for i := 0; i < len(content); i += s.chunkSize {
+		time.Sleep(s.delay)
+		if _, err := w.Write(content[i : i+s.chunkSize]); err != nil {
+			return err
+		}
+	}
+
+	if s.blockUntilDone {
+		defer close(s.stopped)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.err
+}
+
+// blockingWriter blocks its first write until unblock is closed.
+type blockingWriter struct {
+	unblock chan struct{}
+	buf     bytes.Buffer
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.unblock
+	return b.buf.Write(p)
+}
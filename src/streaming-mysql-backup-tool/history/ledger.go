@@ -0,0 +1,180 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("backup not found in history")
+
+type Outcome string
+
+const (
+	Succeeded Outcome = "SUCCEEDED"
+	Failed    Outcome = "FAILED"
+	Cancelled Outcome = "CANCELLED"
+)
+
+// Record describes a single backup served by the tool.
+type Record struct {
+	ID         string            `json:"id"`
+	Requester  string            `json:"requester,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Bytes      int64             `json:"bytes"`
+	FromLSN    uint64            `json:"from_lsn,omitempty"`
+	ToLSN      uint64            `json:"to_lsn,omitempty"`
+	Outcome    Outcome           `json:"outcome"`
+	Error      string            `json:"error,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// Ledger is an append-only history of backups, trimmed to MaxRecords.
+type Ledger struct {
+	Path       string
+	MaxRecords int
+
+	mu sync.Mutex
+}
+
+func (l *Ledger) Append(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return l.compact()
+}
+
+func (l *Ledger) List(limit int) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+func (l *Ledger) Get(id string) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records, err := l.read()
+	if err != nil {
+		return Record{}, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].ID == id {
+			return records[i], nil
+		}
+	}
+
+	return Record{}, ErrNotFound
+}
+
+// Lines left truncated by a crash are skipped.
+func (l *Ledger) read() ([]Record, error) {
+	f, err := os.Open(l.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		records = append(records, r)
+	}
+
+	return records, scanner.Err()
+}
+
+func (l *Ledger) compact() error {
+	if l.MaxRecords <= 0 {
+		return nil
+	}
+
+	records, err := l.read()
+	if err != nil {
+		return err
+	}
+	if len(records) < 2*l.MaxRecords {
+		return nil
+	}
+	records = records[len(records)-l.MaxRecords:]
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.Path), filepath.Base(l.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := json.NewEncoder(tmp)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.Path)
+}
@@ -0,0 +1,97 @@
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+)
+
+var _ = Describe("Ledger", func() {
+	var (
+		dir    string
+		ledger *history.Ledger
+		start  time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "history")
+		Expect(err).NotTo(HaveOccurred())
+
+		ledger = &history.Ledger{Path: filepath.Join(dir, "state", "history.jsonl")}
+		start = time.Date(2024, 4, 22, 18, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	record := func(id string, offset time.Duration) history.Record {
+		return history.Record{
+			ID:         id,
+			Requester:  "admin",
+			Options:    map[string]string{"format": "xbstream"},
+			StartedAt:  start.Add(offset),
+			FinishedAt: start.Add(offset + time.Minute),
+			Bytes:      1024,
+			FromLSN:    100,
+			ToLSN:      200,
+			Outcome:    history.Succeeded,
+		}
+	}
+
+	It("returns an empty history before any backup was recorded", func() {
+		Expect(ledger.List(0)).To(BeEmpty())
+	})
+
+	It("lists recorded backups, most recent first", func() {
+		Expect(ledger.Append(record("first", 0))).To(Succeed())
+		Expect(ledger.Append(record("second", time.Hour))).To(Succeed())
+
+		records, err := ledger.List(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(Equal([]history.Record{record("second", time.Hour), record("first", 0)}))
+
+		Expect(ledger.List(1)).To(Equal([]history.Record{record("second", time.Hour)}))
+	})
+
+	It("persists the history across instances", func() {
+		Expect(ledger.Append(record("first", 0))).To(Succeed())
+
+		reopened := &history.Ledger{Path: ledger.Path}
+		Expect(reopened.Get("first")).To(Equal(record("first", 0)))
+	})
+
+	It("returns ErrNotFound for unknown backups", func() {
+		_, err := ledger.Get("unknown")
+		Expect(err).To(MatchError(history.ErrNotFound))
+	})
+
+	It("skips records that were only partially written", func() {
+		Expect(ledger.Append(record("first", 0))).To(Succeed())
+		f, err := os.OpenFile(ledger.Path, os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).NotTo(HaveOccurred())
+		_, _ = f.WriteString(`{"id":"trunc`)
+		Expect(f.Close()).To(Succeed())
+
+		Expect(ledger.List(0)).To(HaveLen(1))
+	})
+
+	It("keeps only the most recent MaxRecords once the file grows", func() {
+		ledger.MaxRecords = 2
+		for i, id := range []string{"a", "b", "c", "d"} {
+			Expect(ledger.Append(record(id, time.Duration(i)*time.Hour))).To(Succeed())
+		}
+
+		records, err := ledger.List(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].ID).To(Equal("d"))
+		Expect(records[1].ID).To(Equal("c"))
+	})
+})
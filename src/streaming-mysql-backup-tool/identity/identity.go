@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"crypto/x509"
+	"net/url"
+	"strings"
+)
+
+// Match returns the first of identities cert is valid for.
+func Match(cert *x509.Certificate, identities []string) (string, bool) {
+	for _, identity := range identities {
+		if !strings.Contains(identity, "://") {
+			if cert.VerifyHostname(identity) == nil {
+				return identity, true
+			}
+			continue
+		}
+
+		pattern, prefix := strings.CutSuffix(identity, "/*")
+		want, err := url.Parse(pattern)
+		if err != nil {
+			continue
+		}
+		for _, uri := range cert.URIs {
+			if matchURI(uri, want, prefix) {
+				return identity, true
+			}
+		}
+	}
+	return "", false
+}
+
+func matchURI(uri, want *url.URL, prefix bool) bool {
+	if !strings.EqualFold(uri.Scheme, want.Scheme) || !strings.EqualFold(uri.Host, want.Host) {
+		return false
+	}
+	if uri.RawQuery != "" || uri.Fragment != "" || uri.User != nil {
+		return false
+	}
+	if !prefix {
+		return uri.Path == want.Path
+	}
+	return strings.HasPrefix(uri.Path, strings.TrimSuffix(want.Path, "/")+"/")
+}
+
+func Name(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package identity_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/identity"
+)
+
+var _ = Describe("Match", func() {
+	var cert *x509.Certificate
+
+	BeforeEach(func() {
+		uri, err := url.Parse("spiffe://example.org/ns/backup/sa/client")
+		Expect(err).NotTo(HaveOccurred())
+
+		cert = &x509.Certificate{
+			DNSNames: []string{"backup-client.example.org"},
+			URIs:     []*url.URL{uri},
+		}
+	})
+
+	DescribeTable("matching identities",
+		func(identities []string, matched string) {
+			name, ok := identity.Match(cert, identities)
+			Expect(ok).To(BeTrue())
+			Expect(name).To(Equal(matched))
+		},
+		Entry("a DNS name", []string{"other", "backup-client.example.org"}, "backup-client.example.org"),
+		Entry("an exact URI", []string{"spiffe://example.org/ns/backup/sa/client"}, "spiffe://example.org/ns/backup/sa/client"),
+		Entry("a URI with a differently cased trust domain", []string{"spiffe://Example.org/ns/backup/sa/client"}, "spiffe://Example.org/ns/backup/sa/client"),
+		Entry("a path prefix", []string{"spiffe://example.org/ns/other/*", "spiffe://example.org/ns/backup/*"}, "spiffe://example.org/ns/backup/*"),
+		Entry("a trust domain", []string{"spiffe://example.org/*"}, "spiffe://example.org/*"),
+	)
+
+	DescribeTable("identities that do not match",
+		func(identities []string) {
+			_, ok := identity.Match(cert, identities)
+			Expect(ok).To(BeFalse())
+		},
+		Entry("no identities", nil),
+		Entry("another DNS name", []string{"other.example.org"}),
+		Entry("a URI as a DNS name", []string{"example.org"}),
+		Entry("a URI that only shares a prefix", []string{"spiffe://example.org/ns/backup"}),
+		Entry("a prefix that is not a path segment", []string{"spiffe://example.org/ns/back/*"}),
+		Entry("another trust domain", []string{"spiffe://example.com/*"}),
+		Entry("another scheme", []string{"https://example.org/ns/backup/sa/client"}),
+	)
+})
+
+var _ = Describe("Name", func() {
+	It("prefers the common name, then DNS names, then URIs", func() {
+		uri, err := url.Parse("spiffe://example.org/ns/backup/sa/client")
+		Expect(err).NotTo(HaveOccurred())
+
+		cert := &x509.Certificate{URIs: []*url.URL{uri}}
+		Expect(identity.Name(cert)).To(Equal("spiffe://example.org/ns/backup/sa/client"))
+
+		cert.DNSNames = []string{"backup-client.example.org"}
+		Expect(identity.Name(cert)).To(Equal("backup-client.example.org"))
+
+		cert.Subject = pkix.Name{CommonName: "backup-client"}
+		Expect(identity.Name(cert)).To(Equal("backup-client"))
+	})
+})
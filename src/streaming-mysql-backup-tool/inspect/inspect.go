@@ -0,0 +1,94 @@
+package inspect
+
+import (
+	"archive/tar"
+	"io"
+
+	"github.com/cloudfoundry/xtrabackuplog"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+const maxFileSize = 64 * 1024
+
+// Inspector reads BackupInfo out of the archive written to it. Writes never fail.
+type Inspector struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	info xtrabackuplog.BackupInfo
+}
+
+func New(format string) *Inspector {
+	pr, pw := io.Pipe()
+	i := &Inspector{pw: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(i.done)
+		if format == "tar" {
+			i.readTar(pr)
+		} else {
+			i.readXbstream(pr)
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	return i
+}
+
+func (i *Inspector) Write(p []byte) (int, error) {
+	_, _ = i.pw.Write(p)
+	return len(p), nil
+}
+
+// Close waits for the archive to be inspected and returns what was found.
+func (i *Inspector) Close() xtrabackuplog.BackupInfo {
+	_ = i.pw.Close()
+	<-i.done
+	return i.info
+}
+
+func (i *Inspector) readTar(r io.Reader) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			return
+		}
+		if !xtrabackuplog.IsBackupInfoFile(header.Name) || header.Size > maxFileSize {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return
+		}
+		i.info.Parse(header.Name, content)
+	}
+}
+
+func (i *Inspector) readXbstream(r io.Reader) {
+	files := map[string][]byte{}
+	xr := xbstream.NewReader(r)
+	for {
+		c, err := xr.Next()
+		if err != nil {
+			return
+		}
+		if !xtrabackuplog.IsBackupInfoFile(c.Path) {
+			continue
+		}
+		if c.EOF {
+			i.info.Parse(c.Path, files[c.Path])
+			delete(files, c.Path)
+			continue
+		}
+		content := files[c.Path]
+		if c.SparseMap != nil || c.End() > maxFileSize {
+			continue
+		}
+		if end := int(c.End()); end > len(content) {
+			content = append(content, make([]byte, end-len(content))...)
+		}
+		copy(content[c.Offset:], c.Payload)
+		files[c.Path] = content
+	}
+}
@@ -0,0 +1,68 @@
+package inspect_test
+
+import (
+	"archive/tar"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/xtrabackuplog"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/inspect"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+var files = map[string]string{
+	"ibdata1":                "some-tablespace",
+	"xtrabackup_binlog_info": "binlog.000003\t157\t3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5\n",
+	"xtrabackup_galera_info": "8f3e6cba-6c79-11ee-8c99-0242ac120002:42\n",
+	"xtrabackup_checkpoints": "backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 19006600\nlast_lsn = 19006610\n",
+}
+
+var expected = xtrabackuplog.BackupInfo{
+	BinlogFile:     "binlog.000003",
+	BinlogPosition: "157",
+	GTIDExecuted:   "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5",
+	GaleraUUID:     "8f3e6cba-6c79-11ee-8c99-0242ac120002",
+	GaleraSeqno:    "42",
+	BackupType:     "full-backuped",
+	FromLSN:        "0",
+	ToLSN:          "19006600",
+	LastLSN:        "19006610",
+}
+
+var _ = Describe("Inspector", func() {
+	It("reads the backup info out of xbstream archives", func() {
+		inspector := inspect.New("xbstream")
+		w := xbstream.NewWriterSize(inspector, 8)
+		for name, content := range files {
+			Expect(w.WriteFile(name, strings.NewReader(content))).To(Succeed())
+		}
+
+		Expect(inspector.Close()).To(Equal(expected))
+	})
+
+	It("reads the backup info out of tar archives", func() {
+		inspector := inspect.New("tar")
+		tw := tar.NewWriter(inspector)
+		for name, content := range files {
+			Expect(tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0640, Size: int64(len(content))})).To(Succeed())
+			_, err := io.WriteString(tw, content)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tw.Close()).To(Succeed())
+
+		Expect(inspector.Close()).To(Equal(expected))
+	})
+
+	It("accepts archives it cannot read", func() {
+		inspector := inspect.New("xbstream")
+		n, err := io.WriteString(inspector, strings.Repeat("not an archive", 1000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(14000))
+
+		Expect(inspector.Close()).To(Equal(xtrabackuplog.BackupInfo{}))
+	})
+})
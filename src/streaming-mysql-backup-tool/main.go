@@ -1,19 +1,44 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"syscall"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/backuplog"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/bundle"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/clone"
 	c "github.com/cloudfoundry/streaming-mysql-backup-tool/config"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/engine"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/fanout"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/history"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mariabackup"
 	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/objectstore"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/revocation"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/stream"
 	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
 
 	"code.cloudfoundry.org/lager/v3"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "stream" {
+		os.Exit(runStream(os.Args[2:]))
+	}
+
 	config, err := c.NewConfig(os.Args)
 	logger := config.Logger
 
@@ -23,22 +48,245 @@ func main() {
 		})
 	}
 
+	if r := config.TLS.Revocation; config.TLS.EnableMutualTLS && r.Enabled() {
+		checker := &revocation.Checker{
+			FailOpen: r.FailurePolicy == "open",
+			Logger:   logger.Session("revocation"),
+		}
+		if r.CRLFile != "" {
+			crl := &revocation.CRL{
+				File:   r.CRLFile,
+				Logger: logger.Session("crl"),
+			}
+			if err := crl.Load(); err != nil {
+				logger.Fatal("Failed to load the CRL", err)
+			}
+			go crl.Watch(context.Background(), r.CRLReloadInterval)
+			checker.Sources = append(checker.Sources, crl)
+		}
+		if r.OCSP {
+			checker.Sources = append(checker.Sources, &revocation.OCSP{
+				Responder: r.OCSPResponder,
+				Timeout:   r.OCSPTimeout,
+			})
+		}
+		config.TLS.CheckRevocation = checker.Check
+	}
+
 	mux := http.NewServeMux()
 
-	var backupHandler http.Handler = &api.BackupHandler{
-		BackupWriter: xtrabackup.Writer{
-			DefaultsFile: config.XtraBackup.DefaultsFile,
-			TmpDir:       config.XtraBackup.TmpDir,
-			Logger:       config.Logger,
-		},
-		Logger: logger,
+	newBackupLogs := func(directory string) api.BackupLogStore {
+		if config.BackupLogs.Directory == "" {
+			return nil
+		}
+		return backuplog.Store{
+			Directory: directory,
+			MaxCount:  config.BackupLogs.MaxCount,
+			MaxAge:    config.BackupLogs.MaxAge,
+			Logger:    logger.Session("backup-logs"),
+		}
+	}
+	backupLogs := newBackupLogs(config.BackupLogs.Directory)
+
+	var backupHistory api.BackupHistory
+	if config.History.File != "" {
+		backupHistory = &history.Ledger{
+			Path:       config.History.File,
+			MaxRecords: config.History.MaxRecords,
+		}
+	}
+
+	runningBackups := &api.RunningBackups{
+		MaxPause: config.XtraBackup.MaxPause,
+		Logger:   logger.Session("running-backups"),
+	}
+
+	maintenance := &api.Maintenance{
+		SentinelFile: config.Maintenance.SentinelFile,
+		InFlight:     config.Maintenance.InFlight,
+		Running:      []*api.RunningBackups{runningBackups},
+		Logger:       logger.Session("maintenance"),
+	}
+
+	tracer, err := config.Tracing.NewTracer("streaming-mysql-backup-tool", logger.Session("tracing"))
+	if err != nil {
+		logger.Fatal("Failed to configure tracing", err)
+	}
+
+	var (
+		uploader   api.BackupUploader
+		newUploads = func() *api.Uploads { return nil }
+	)
+	if o := config.ObjectStore; o.Enabled() {
+		httpClient, err := o.HTTPClient()
+		if err != nil {
+			logger.Fatal("Failed to configure the object store", err)
+		}
+		uploader = objectstore.Uploader{
+			Client: objectstore.Client{
+				Endpoint: o.Endpoint,
+				Bucket:   o.Bucket,
+				Signer: objectstore.Signer{
+					AccessKeyID:     o.AccessKeyID,
+					SecretAccessKey: o.SecretAccessKey,
+					Region:          o.Region,
+				},
+				HTTPClient: httpClient,
+			},
+			Prefix:        o.Prefix,
+			ChunkSize:     o.ChunkSize,
+			EncryptionKey: o.EncryptionKey,
+		}
+		newUploads = func() *api.Uploads { return &api.Uploads{} }
+	}
+	uploads := newUploads()
+
+	basicAuth := middleware.BasicAuth
+	if g := config.AuthGuard; g.Enabled() {
+		basicAuth = (&middleware.AuthGuard{
+			MaxFailures: g.MaxFailures,
+			BaseDelay:   g.BaseDelay,
+			MaxDelay:    g.MaxDelay,
+			Lockout:     g.Lockout,
+			Logger:      logger.Session("auth-guard"),
+		}).BasicAuth
+	}
+
+	var rateLimiter *middleware.RateLimiter
+	if r := config.RateLimit; r.Enabled() {
+		rateLimiter = &middleware.RateLimiter{
+			Requests: r.Requests,
+			Interval: r.Interval,
+			Logger:   logger.Session("rate-limit"),
+		}
+	}
+
+	authorize := func(handler http.Handler, operation string) http.Handler {
+		return handler
+	}
+	if config.Policy.Enabled() {
+		policy := &middleware.Policy{Logger: logger.Session("policy")}
+		for _, rule := range config.Policy.Rules {
+			policy.Rules = append(policy.Rules, middleware.Rule{
+				Identities: rule.Identities,
+				Networks:   rule.IPNets(),
+				Operations: rule.Operations,
+				Formats:    rule.Formats,
+			})
+		}
+		authorize = policy.Authorize
+	}
+
+	newBackupHandler := func(instance string, xb c.XtraBackup, backupLogs api.BackupLogStore, uploads *api.Uploads, running *api.RunningBackups, logger lager.Logger) http.Handler {
+		backupWriter, err := newBackupWriter(xb, config.FanOut, logger)
+		if err != nil {
+			logger.Fatal("Failed to configure the backup writer", err)
+		}
+		var handler http.Handler = &api.BackupHandler{
+			BackupWriter: backupWriter,
+			BackupLogs:   backupLogs,
+			History:      backupHistory,
+			HistoryName:  xb.HistoryName,
+			Running:      running,
+			Uploader:     uploader,
+			Uploads:      uploads,
+			Instance:     instance,
+			Maintenance:  maintenance,
+			Tracer:       tracer,
+			Logger:       logger,
+		}
+		if rateLimiter != nil {
+			handler = rateLimiter.Limit(handler)
+		}
+		return authorize(handler, middleware.OperationBackup)
+	}
+
+	newBackupsRouter := func(instance string, backupLogs api.BackupLogStore, uploads *api.Uploads, running *api.RunningBackups, logger lager.Logger) api.BackupsRouter {
+		controlHandler := authorize(&api.ControlHandler{
+			Running: running,
+			Logger:  logger,
+		}, middleware.OperationCancel)
+
+		return api.BackupsRouter{
+			Collection: authorize(&api.HistoryHandler{
+				History:  backupHistory,
+				Instance: instance,
+				Logger:   logger,
+			}, middleware.OperationStatus),
+			Resources: map[string]http.Handler{
+				"log": authorize(&api.BackupLogHandler{
+					BackupLogs: backupLogs,
+					Logger:     logger,
+				}, middleware.OperationStatus),
+				"upload": authorize(&api.UploadHandler{
+					Uploads: uploads,
+				}, middleware.OperationStatus),
+				"cancel": controlHandler,
+				"pause":  controlHandler,
+				"resume": controlHandler,
+			},
+		}
+	}
+
+	backupHandler := newBackupHandler("", config.XtraBackup, backupLogs, uploads, runningBackups, logger)
+	var backupsHandler http.Handler = newBackupsRouter("", backupLogs, uploads, runningBackups, logger)
+
+	instances := map[string]http.Handler{}
+	for _, instance := range config.Instances {
+		instanceLogger := logger.Session("instance-"+instance.Name, lager.Data{"instance": instance.Name})
+		instanceXtraBackup := instance.XtraBackup(config.XtraBackup)
+		instanceLogs := newBackupLogs(filepath.Join(config.BackupLogs.Directory, "instances", instance.Name))
+		instanceUploads := newUploads()
+		instanceRunning := &api.RunningBackups{
+			MaxPause: instanceXtraBackup.MaxPause,
+			Logger:   instanceLogger.Session("running-backups"),
+		}
+		maintenance.Running = append(maintenance.Running, instanceRunning)
+
+		instanceBackups := newBackupsRouter(instance.Name, instanceLogs, instanceUploads, instanceRunning, instanceLogger)
+		instanceMux := http.NewServeMux()
+		instanceMux.Handle("/backup", newBackupHandler(instance.Name, instanceXtraBackup, instanceLogs, instanceUploads, instanceRunning, instanceLogger))
+		instanceMux.Handle("/backups", instanceBackups)
+		instanceMux.Handle("/backups/", instanceBackups)
+
+		credentials := config.Credentials
+		if instance.Credentials != nil {
+			credentials = *instance.Credentials
+		}
+
+		var handler http.Handler = instanceMux
+		if config.TLS.EnableMutualTLS {
+			handler = middleware.RequireClientIdentity(handler, instance.ClientIdentities)
+		} else {
+			handler = basicAuth(handler, credentials.Username, credentials.Password)
+		}
+		instances[instance.Name] = handler
+	}
+	go maintenance.Watch(context.Background(), config.Maintenance.PollInterval)
+
+	var maintenanceHandler http.Handler = &api.MaintenanceHandler{
+		Maintenance: maintenance,
+		Logger:      logger,
 	}
 
 	if !config.TLS.EnableMutualTLS {
-		backupHandler = middleware.BasicAuth(backupHandler, config.Credentials.Username, config.Credentials.Password)
+		backupHandler = basicAuth(backupHandler, config.Credentials.Username, config.Credentials.Password)
+		backupsHandler = basicAuth(backupsHandler, config.Credentials.Username, config.Credentials.Password)
+
+		admin := config.Credentials
+		if config.Maintenance.AdminCredentials != nil {
+			admin = *config.Maintenance.AdminCredentials
+		}
+		maintenanceHandler = basicAuth(maintenanceHandler, admin.Username, admin.Password)
+	} else {
+		maintenanceHandler = middleware.RequireClientIdentity(maintenanceHandler, config.Maintenance.AdminIdentities)
 	}
 
 	mux.Handle("/backup", backupHandler)
+	mux.Handle("/backups", backupsHandler)
+	mux.Handle("/backups/", backupsHandler)
+	mux.Handle("/instances/", api.InstancesRouter{Instances: instances})
+	mux.Handle("/maintenance", maintenanceHandler)
 
 	pidfile, err := os.Create(config.PidFile)
 	if err != nil {
@@ -59,3 +307,154 @@ func main() {
 	err = httpServer.ListenAndServeTLS("", "")
 	logger.Fatal("Streaming backup tool has exited with an error", err)
 }
+
+func newBackupWriter(xb c.XtraBackup, fanOut c.FanOut, logger lager.Logger) (api.BackupWriter, error) {
+	var diskGuard *diskguard.Guard
+	if g := xb.DiskGuard; g.Enabled() {
+		diskGuard = &diskguard.Guard{
+			Path:               xb.TmpDir,
+			MinFreeBytes:       g.MinFreeBytes,
+			MinFreePercent:     g.MinFreePercent,
+			RedoBytesPerSecond: g.RedoBytesPerSecond,
+			ExpectedDuration:   g.ExpectedDuration,
+			CheckInterval:      g.CheckInterval,
+			Logger:             logger.Session("disk-guard"),
+		}
+	}
+
+	xtraBackupWriter := xtrabackup.Writer{
+		Binary:                 xb.Binary,
+		DefaultsFile:           xb.DefaultsFile,
+		TmpDir:                 xb.TmpDir,
+		StallTimeout:           xb.StallTimeout,
+		DiskGuard:              diskGuard,
+		SlaveInfo:              xb.SlaveInfo,
+		SafeSlaveBackup:        xb.SafeSlaveBackup,
+		SafeSlaveBackupTimeout: xb.SafeSlaveBackupTimeout,
+		GaleraInfo:             xb.GaleraInfo,
+		KeyringFileData:        xb.Keyring.FileData,
+		ComponentKeyringConfig: xb.Keyring.ComponentConfig,
+		TransitionKey:          xb.Keyring.TransitionKey,
+		RedoLogArchiveDir:      xb.RedoLogArchiveDir,
+		Logger:                 logger,
+	}
+
+	serverVersion := func(ctx context.Context) (serverversion.Version, error) {
+		return serverversion.Query(ctx, xb.DefaultsFile)
+	}
+
+	var backupWriter api.BackupWriter = xtraBackupWriter
+	if len(xb.Binaries) > 0 {
+		backupWriter = engine.Binaries{
+			Writer:        xtraBackupWriter,
+			Binaries:      xb.Binaries,
+			ServerVersion: serverVersion,
+			Logger:        logger.Session("engine"),
+		}
+	}
+	switch xb.Engine {
+	case engine.MariaBackup:
+		backupWriter = mariabackup.Writer{Writer: xtraBackupWriter}
+	case engine.Clone:
+		backupWriter = clone.Writer{
+			DefaultsFile: xb.DefaultsFile,
+			TmpDir:       xb.TmpDir,
+			DiskGuard:    diskGuard,
+			Logger:       logger.Session("clone"),
+		}
+	case engine.Auto:
+		backupWriter = engine.Selector{
+			XtraBackup:    backupWriter,
+			MariaBackup:   mariabackup.Writer{Writer: xtraBackupWriter},
+			ServerVersion: serverVersion,
+			Logger:        logger.Session("engine"),
+		}
+	}
+	var keyring *bundle.Keyring
+	if k := xb.Keyring; k.EncryptionKey != "" {
+		file, err := k.File()
+		if err != nil {
+			return nil, fmt.Errorf("finding the keyring file failed: %w", err)
+		}
+		keyring = &bundle.Keyring{
+			File:          file,
+			Component:     k.ComponentConfig != "",
+			EncryptionKey: k.EncryptionKey,
+		}
+	}
+	if b := xb.Bundle; b.Enabled() || keyring != nil {
+		backupWriter = bundle.Writer{
+			BackupWriter: backupWriter,
+			DefaultsFile: xb.DefaultsFile,
+			Files:        b.Files,
+			Snapshots:    b.Snapshots,
+			Keyring:      keyring,
+			Logger:       logger.Session("bundle"),
+		}
+	}
+	if fanOut.JoinWindow > 0 {
+		var spillGuard *diskguard.Guard
+		if diskGuard != nil {
+			guard := *diskGuard
+			guard.Path = fanOut.SpillDir
+			spillGuard = &guard
+		}
+		return &fanout.Writer{
+			BackupWriter:   backupWriter,
+			JoinWindow:     fanOut.JoinWindow,
+			MaxBufferBytes: fanOut.MaxBufferBytes,
+			SlowConsumer:   fanout.Policy(fanOut.SlowConsumerPolicy),
+			SpillDir:       fanOut.SpillDir,
+			DiskGuard:      spillGuard,
+			Logger:         logger.Session("fan-out"),
+		}, nil
+	}
+
+	return backupWriter, nil
+}
+
+func runStream(args []string) int {
+	options, err := stream.ParseOptions(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	reportTo := json.NewEncoder(os.Stderr)
+
+	xb, err := options.XtraBackup()
+	if err != nil {
+		_ = reportTo.Encode(stream.Result{Error: err.Error(), Kind: "FATAL"})
+		return 2
+	}
+
+	logLevel := lager.ERROR
+	if options.Verbose {
+		logLevel = lager.INFO
+	}
+	logger := lager.NewLogger("streaming-mysql-backup-tool")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, logLevel))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var log io.Writer
+	if options.Verbose {
+		log = os.Stderr
+	}
+
+	backupWriter, err := newBackupWriter(xb, c.FanOut{}, logger)
+	if err != nil {
+		_ = reportTo.Encode(stream.Result{Error: "FATAL: " + err.Error(), Kind: "FATAL"})
+		return 1
+	}
+
+	result := stream.Run(ctx, backupWriter, options, os.Stdout, log)
+	_ = reportTo.Encode(result)
+	if result.Error != "" {
+		return 1
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package main_test
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -95,6 +96,13 @@ var _ = Describe("streaming-mysql-backup-tool", func() {
 				TmpDir:       "/tmp",
 			},
 			PidFile: pidFile,
+			BackupLogs: config.BackupLogs{
+				Directory: filepath.Join(tmpDir, "backup-logs"),
+				MaxCount:  5,
+			},
+			History: config.History{
+				File: filepath.Join(tmpDir, "history.jsonl"),
+			},
 			Credentials: config.Credentials{
 				Username: "username",
 				Password: "password",
@@ -232,6 +240,46 @@ var _ = Describe("streaming-mysql-backup-tool", func() {
 					Expect(resp.Trailer.Get(http.CanonicalHeaderKey("X-Backup-Error"))).To(BeEmpty())
 				})
 
+				It("records the backup in the history at /backups", func() {
+					resp, err := httpClient.Do(request)
+					Expect(err).ShouldNot(HaveOccurred())
+					_, _ = io.Copy(io.Discard, resp.Body)
+					backupID := resp.Header.Get("X-Backup-Id")
+
+					historyRequest, err := http.NewRequest("GET", fmt.Sprintf("https://127.0.0.1:%d/backups", backupServerPort), nil)
+					Expect(err).NotTo(HaveOccurred())
+					historyRequest.SetBasicAuth("username", "password")
+
+					historyResp, err := httpClient.Do(historyRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(historyResp.StatusCode).To(Equal(http.StatusOK))
+
+					var records []map[string]interface{}
+					Expect(json.NewDecoder(historyResp.Body).Decode(&records)).To(Succeed())
+					Expect(records).To(HaveLen(1))
+					Expect(records[0]).To(HaveKeyWithValue("id", backupID))
+					Expect(records[0]).To(HaveKeyWithValue("requester", "username"))
+					Expect(records[0]).To(HaveKeyWithValue("outcome", "SUCCEEDED"))
+					Expect(records[0]).To(HaveKey("to_lsn"))
+				})
+
+				It("can be cancelled by an operator", func() {
+					resp, err := httpClient.Do(request)
+					Expect(err).ShouldNot(HaveOccurred())
+					backupID := resp.Header.Get("X-Backup-Id")
+
+					cancelRequest, err := http.NewRequest("POST", fmt.Sprintf("https://127.0.0.1:%d/backups/%s/cancel", backupServerPort, backupID), nil)
+					Expect(err).NotTo(HaveOccurred())
+					cancelRequest.SetBasicAuth("username", "password")
+
+					cancelResp, err := httpClient.Do(cancelRequest)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cancelResp.StatusCode).To(Equal(http.StatusOK))
+
+					_, _ = io.Copy(io.Discard, resp.Body)
+					Expect(resp.Trailer.Get(http.CanonicalHeaderKey("X-Backup-Error"))).To(Equal("CANCELLED: backup cancelled by operator"))
+				})
+
 				Context("when the backup is unsuccessful", func() {
 					BeforeEach(func() {
 						_, err := db.Exec(`REVOKE BACKUP_ADMIN ON *.* FROM root@localhost`)
@@ -251,6 +299,27 @@ var _ = Describe("streaming-mysql-backup-tool", func() {
 						Expect(resp.Trailer.Get(http.CanonicalHeaderKey("X-Backup-Error"))).To(ContainSubstring("exit status 1"))
 						Expect(session).To(gbytes.Say(`Access denied; you need \(at least one of\) the BACKUP_ADMIN privilege\(s\) for this operation`))
 					})
+
+					It("makes the xtrabackup output available at /backups/{id}/log", func() {
+						resp, err := httpClient.Do(request)
+						Expect(err).ShouldNot(HaveOccurred())
+						_, _ = io.Copy(io.Discard, resp.Body)
+
+						backupID := resp.Header.Get("X-Backup-Id")
+						Expect(backupID).NotTo(BeEmpty())
+
+						logRequest, err := http.NewRequest("GET", fmt.Sprintf("https://127.0.0.1:%d/backups/%s/log", backupServerPort, backupID), nil)
+						Expect(err).NotTo(HaveOccurred())
+						logRequest.SetBasicAuth("username", "password")
+
+						logResp, err := httpClient.Do(logRequest)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(logResp.StatusCode).To(Equal(http.StatusOK))
+
+						body, err := io.ReadAll(logResp.Body)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(body)).To(ContainSubstring("Access denied; you need (at least one of) the BACKUP_ADMIN privilege(s) for this operation"))
+					})
 				})
 
 				Context("REGRESSION: Hitting the same endpoint twice", func() {
@@ -711,6 +780,31 @@ var _ = Describe("streaming-mysql-backup-tool", func() {
 	})
 })
 
+var _ = Describe("streaming-mysql-backup-tool stream", func() {
+	It("reports a keyring that cannot be found as the result of the backup", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yml")
+		Expect(os.WriteFile(path, []byte(`
+XtraBackup:
+  DefaultsFile: /etc/my.cnf
+  TmpDir: /tmp
+  Keyring:
+    ComponentConfig: /does/not/exist/component_keyring_file.cnf
+    EncryptionKey: some-keyring-key
+`), 0600)).To(Succeed())
+
+		cmd := exec.Command(pathToMainBinary, "stream", "-configPath="+path)
+		stderr := gbytes.NewBuffer()
+		session, err := gexec.Start(cmd, GinkgoWriter, stderr)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(1))
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal(stderr.Contents(), &result)).To(Succeed())
+		Expect(result["kind"]).To(Equal("FATAL"))
+		Expect(result["error"]).To(ContainSubstring("FATAL: finding the keyring file failed"))
+	})
+})
+
 func containerDB(container *dockertest.Resource) (*sql.DB, error) {
 	return sql.Open("mysql", "root@tcp(127.0.0.1:"+container.GetPort("3306/tcp")+")/")
 }
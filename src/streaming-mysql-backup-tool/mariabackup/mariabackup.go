@@ -0,0 +1,24 @@
+package mariabackup
+
+import (
+	"context"
+	"io"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+// Writer takes backups with mariabackup, without the keyring and redo log archive options.
+type Writer struct {
+	xtrabackup.Writer
+}
+
+func (m Writer) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	x := m.Writer
+	x.Binary = "mariabackup"
+	x.KeyringFileData, x.ComponentKeyringConfig, x.TransitionKey = "", "", ""
+	x.RedoLogArchiveDir = ""
+	return x.StreamTo(ctx, req, w)
+}
+
+var _ api.BackupWriter = Writer{}
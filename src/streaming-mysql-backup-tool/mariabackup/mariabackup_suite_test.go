@@ -0,0 +1,13 @@
+package mariabackup_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMariabackup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mariabackup Suite")
+}
@@ -0,0 +1,35 @@
+package mariabackup_test
+
+import (
+	"bytes"
+	"context"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mariabackup"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+var _ = Describe("mariabackup.Writer", func() {
+	DescribeTable("runs mariabackup",
+		func(format string) {
+			GinkgoT().Setenv("PATH", GinkgoT().TempDir())
+
+			writer := mariabackup.Writer{Writer: xtrabackup.Writer{
+				DefaultsFile: "/etc/my.cnf",
+				TmpDir:       GinkgoT().TempDir(),
+				Logger:       lagertest.NewTestLogger("mariabackup"),
+			}}
+
+			var output bytes.Buffer
+			err := writer.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: format}, &output)
+			Expect(err).To(MatchError(ContainSubstring(`"mariabackup": executable file not found`)))
+			Expect(output.Len()).To(BeZero())
+		},
+		Entry("for xbstream", "xbstream"),
+		Entry("for tar", "tar"),
+	)
+})
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// AuthGuard backs off failed basic auth per source and username, never refusing valid credentials.
+type AuthGuard struct {
+	MaxFailures int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lockout     time.Duration
+	Logger      lager.Logger
+
+	mu       sync.Mutex
+	attempts map[attempt]*failures
+	refused  int
+	lockouts int
+	pruned   time.Time
+}
+
+type attempt struct {
+	source   string
+	username string
+}
+
+type failures struct {
+	count        int
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func (g *AuthGuard) BasicAuth(next http.Handler, requiredUsername, requiredPassword string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		key := attempt{source: sourceIP(req), username: username}
+
+		if ok &&
+			secureCompare(username, requiredUsername) &&
+			secureCompare(password, requiredPassword) {
+			g.succeeded(key)
+			next.ServeHTTP(rw, WithAuthenticatedUsername(req, username))
+			return
+		}
+
+		if ok {
+			if retry := g.blocked(key); retry > 0 {
+				rw.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+				http.Error(rw, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			g.failed(key)
+		}
+		rw.Header().Set("WWW-Authenticate", "Basic realm=\"Authorization Required\"")
+		http.Error(rw, "Not Authorized", http.StatusUnauthorized)
+	})
+}
+
+func (g *AuthGuard) blocked(key attempt) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	f := g.attempts[key]
+	if f == nil || !f.blockedUntil.After(now) {
+		return 0
+	}
+
+	g.refused++
+	g.Logger.Info("refusing request after failed authentication attempts", lager.Data{
+		"source":        key.source,
+		"username":      key.username,
+		"retry_after":   f.blockedUntil.Sub(now).Round(time.Millisecond).String(),
+		"refused_total": g.refused,
+	})
+	return f.blockedUntil.Sub(now)
+}
+
+func (g *AuthGuard) succeeded(key attempt) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.attempts, key)
+}
+
+func (g *AuthGuard) failed(key attempt) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.attempts == nil {
+		g.attempts = map[attempt]*failures{}
+	}
+
+	now := time.Now()
+	g.prune(now)
+
+	g.Logger.Info("failed authentication attempt", lager.Data{
+		"source":   key.source,
+		"username": key.username,
+	})
+
+	f := g.attempts[key]
+	if f == nil || now.Sub(f.last) > g.Lockout {
+		f = &failures{}
+		g.attempts[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if f.count >= g.MaxFailures {
+		g.lockouts++
+		f.blockedUntil = now.Add(g.Lockout)
+		g.Logger.Info("locking out after repeated failed authentication attempts", lager.Data{
+			"source":         key.source,
+			"username":       key.username,
+			"failures":       f.count,
+			"lockout":        g.Lockout.String(),
+			"lockouts_total": g.lockouts,
+		})
+		return
+	}
+
+	delay := g.BaseDelay << (f.count - 1)
+	if delay > g.MaxDelay || delay <= 0 {
+		delay = g.MaxDelay
+	}
+	f.blockedUntil = now.Add(delay)
+}
+
+func (g *AuthGuard) prune(now time.Time) {
+	if now.Sub(g.pruned) < time.Minute {
+		return
+	}
+	g.pruned = now
+
+	for key, f := range g.attempts {
+		if now.Sub(f.last) > g.Lockout && now.After(f.blockedUntil) {
+			delete(g.attempts, key)
+		}
+	}
+}
+
+func sourceIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
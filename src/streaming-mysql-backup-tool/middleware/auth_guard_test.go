@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+)
+
+var _ = Describe("AuthGuard", func() {
+	var (
+		logger  *lagertest.TestLogger
+		guard   *middleware.AuthGuard
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("auth-guard")
+		guard = &middleware.AuthGuard{
+			MaxFailures: 3,
+			BaseDelay:   50 * time.Millisecond,
+			MaxDelay:    time.Second,
+			Lockout:     time.Hour,
+			Logger:      logger,
+		}
+		handler = guard.BasicAuth(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}), "username", "password")
+	})
+
+	request := func(remoteAddr, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/backup", nil)
+		req.RemoteAddr = remoteAddr
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	It("lets requests with the right credentials through", func() {
+		Expect(request("10.0.0.1:1234", "username", "password").Code).To(Equal(http.StatusOK))
+	})
+
+	It("backs off a source guessing a username after a failed attempt", func() {
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+
+		recorder := request("10.0.0.1:1235", "username", "wrong")
+		Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("1"))
+
+		Eventually(func() int {
+			return request("10.0.0.1:1234", "username", "wrong").Code
+		}).Should(Equal(http.StatusUnauthorized))
+	})
+
+	It("does not back off other sources or other usernames", func() {
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(request("10.0.0.2:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("10.0.0.1:1234", "other", "wrong").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("never refuses the right credentials", func() {
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(request("10.0.0.1:1234", "username", "password").Code).To(Equal(http.StatusOK))
+	})
+
+	It("doubles the backoff with every failure", func() {
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		time.Sleep(60 * time.Millisecond)
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		time.Sleep(60 * time.Millisecond)
+
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("locks out a source guessing a username after too many failures", func() {
+		for i := 0; i < 3; i++ {
+			Eventually(func() int {
+				return request("10.0.0.1:1234", "username", "wrong").Code
+			}).Should(Equal(http.StatusUnauthorized))
+		}
+
+		Consistently(func() int {
+			return request("10.0.0.1:1234", "username", "wrong").Code
+		}, "300ms").Should(Equal(http.StatusTooManyRequests))
+		Expect(request("10.0.0.1:1234", "username", "wrong").Header().Get("Retry-After")).To(Equal("3600"))
+		Expect(logger).To(gbytes.Say("locking out after repeated failed authentication attempts"))
+
+		Expect(request("10.0.0.2:1234", "username", "password").Code).To(Equal(http.StatusOK))
+		Expect(request("10.0.0.1:1234", "username", "password").Code).To(Equal(http.StatusOK))
+	})
+
+	It("does not count requests without credentials as failures", func() {
+		Expect(request("10.0.0.1:1234", "", "").Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("forgets failures after a successful attempt", func() {
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+		Expect(request("10.0.0.1:1234", "username", "password").Code).To(Equal(http.StatusOK))
+
+		Expect(request("10.0.0.1:1234", "username", "wrong").Code).To(Equal(http.StatusUnauthorized))
+	})
+})
@@ -1,17 +1,20 @@
 package middleware
 
 import (
+	"context"
 	"crypto/subtle"
 	"net/http"
 )
 
+type usernameKey struct{}
+
 func BasicAuth(next http.Handler, requiredUsername, requiredPassword string) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		username, password, ok := req.BasicAuth()
 		if ok &&
 			secureCompare(username, requiredUsername) &&
 			secureCompare(password, requiredPassword) {
-			next.ServeHTTP(rw, req)
+			next.ServeHTTP(rw, WithAuthenticatedUsername(req, username))
 		} else {
 			rw.Header().Set("WWW-Authenticate", "Basic realm=\"Authorization Required\"")
 			http.Error(rw, "Not Authorized", http.StatusUnauthorized)
@@ -19,6 +22,16 @@ func BasicAuth(next http.Handler, requiredUsername, requiredPassword string) htt
 	})
 }
 
+func WithAuthenticatedUsername(req *http.Request, username string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), usernameKey{}, username))
+}
+
+// AuthenticatedUsername returns the username basic auth verified for req.
+func AuthenticatedUsername(req *http.Request) (string, bool) {
+	username, ok := req.Context().Value(usernameKey{}).(string)
+	return username, ok
+}
+
 func secureCompare(v1, v2 string) bool {
 	return subtle.ConstantTimeCompare([]byte(v1), []byte(v2)) == 1
 }
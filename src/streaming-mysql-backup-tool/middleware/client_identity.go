@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/identity"
+)
+
+func RequireClientIdentity(next http.Handler, identities []string) http.Handler {
+	if len(identities) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			if _, ok := identity.Match(req.TLS.PeerCertificates[0], identities); ok {
+				next.ServeHTTP(rw, req)
+				return
+			}
+		}
+
+		http.Error(rw, "Forbidden", http.StatusForbidden)
+	})
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"code.cloudfoundry.org/lager/v3"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/identity"
+)
+
+// Operations a Policy grants.
+const (
+	OperationBackup = "backup"
+	OperationStatus = "status"
+	OperationCancel = "cancel"
+)
+
+type Policy struct {
+	Rules  []Rule
+	Logger lager.Logger
+}
+
+type Rule struct {
+	Identities []string
+	Networks   []*net.IPNet
+	Operations []string
+	Formats    []string
+}
+
+// Authorize only lets requests for operation through that the policy allows.
+func (p *Policy) Authorize(next http.Handler, operation string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if reason := p.deny(req, operation); reason != "" {
+			p.Logger.Info("request denied by policy", lager.Data{
+				"operation": operation,
+				"path":      req.URL.Path,
+				"source":    sourceIP(req),
+				"reason":    reason,
+			})
+			http.Error(rw, "Forbidden: "+reason, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// deny returns why req may not perform operation, or "" if it may.
+func (p *Policy) deny(req *http.Request, operation string) string {
+	client := "client " + describeClient(req)
+	source := net.ParseIP(sourceIP(req))
+
+	var matched, granted bool
+	var formats []string
+	for _, rule := range p.Rules {
+		if !rule.matchesIdentity(req) || !rule.matchesNetwork(source) {
+			continue
+		}
+		matched = true
+		if !slices.Contains(rule.Operations, operation) {
+			continue
+		}
+		granted = true
+		if operation != OperationBackup || len(rule.Formats) == 0 {
+			return ""
+		}
+		if slices.Contains(rule.Formats, backupFormat(req)) {
+			return ""
+		}
+		formats = append(formats, rule.Formats...)
+	}
+
+	switch {
+	case !matched:
+		return fmt.Sprintf("no policy rule matches %s from %s", client, sourceIP(req))
+	case !granted:
+		return fmt.Sprintf("%s from %s may not %s", client, sourceIP(req), operation)
+	default:
+		return fmt.Sprintf("%s from %s may not take %s backups, only %s", client, sourceIP(req), backupFormat(req), strings.Join(formats, ", "))
+	}
+}
+
+func (r Rule) matchesIdentity(req *http.Request) bool {
+	if len(r.Identities) == 0 {
+		return true
+	}
+
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		if _, ok := identity.Match(req.TLS.PeerCertificates[0], r.Identities); ok {
+			return true
+		}
+	}
+
+	if username, ok := AuthenticatedUsername(req); ok {
+		return slices.Contains(r.Identities, username)
+	}
+	return false
+}
+
+func (r Rule) matchesNetwork(ip net.IP) bool {
+	if len(r.Networks) == 0 {
+		return true
+	}
+
+	for _, network := range r.Networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func backupFormat(req *http.Request) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	return "tar"
+}
+
+func describeClient(req *http.Request) string {
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		if name := identity.Name(req.TLS.PeerCertificates[0]); name != "" {
+			return "'" + name + "'"
+		}
+	}
+
+	if username, ok := AuthenticatedUsername(req); ok {
+		return "'" + username + "'"
+	}
+	return "without an identity"
+}
@@ -0,0 +1,129 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		logger *lagertest.TestLogger
+		policy *middleware.Policy
+	)
+
+	BeforeEach(func() {
+		_, serviceNetwork, err := net.ParseCIDR("10.0.0.0/24")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger = lagertest.NewTestLogger("policy")
+		policy = &middleware.Policy{
+			Rules: []middleware.Rule{
+				{
+					Identities: []string{"backup-client"},
+					Networks:   []*net.IPNet{serviceNetwork},
+					Operations: []string{middleware.OperationBackup, middleware.OperationStatus},
+					Formats:    []string{"xbstream"},
+				},
+				{
+					Identities: []string{"operator"},
+					Operations: []string{middleware.OperationStatus, middleware.OperationCancel},
+				},
+			},
+			Logger: logger,
+		}
+	})
+
+	withCertificate := func(req *http.Request, name string) *http.Request {
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{
+				Subject:  pkix.Name{CommonName: name},
+				DNSNames: []string{name},
+			}},
+		}
+		return req
+	}
+
+	serve := func(operation string, req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		policy.Authorize(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}), operation).ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	newRequest := func(target, remoteAddr string) *http.Request {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = remoteAddr
+		return req
+	}
+
+	It("allows operations a rule grants to the client certificate", func() {
+		req := withCertificate(newRequest("/backup?format=xbstream", "10.0.0.5:1234"), "backup-client")
+
+		Expect(serve(middleware.OperationBackup, req).Code).To(Equal(http.StatusOK))
+	})
+
+	It("matches basic auth usernames", func() {
+		req := newRequest("/backups/some-id/cancel", "192.168.0.1:1234")
+		req.SetBasicAuth("operator", "password")
+
+		recorder := httptest.NewRecorder()
+		middleware.BasicAuth(policy.Authorize(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}), middleware.OperationCancel), "operator", "password").ServeHTTP(recorder, req)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+	})
+
+	It("ignores usernames basic auth did not verify", func() {
+		req := withCertificate(newRequest("/backups/some-id/cancel", "192.168.0.1:1234"), "backup-client")
+		req.SetBasicAuth("operator", "x")
+
+		recorder := serve(middleware.OperationCancel, req)
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		Expect(recorder.Body.String()).To(ContainSubstring("no policy rule matches client 'backup-client' from 192.168.0.1"))
+	})
+
+	It("denies clients no rule matches", func() {
+		req := withCertificate(newRequest("/backup?format=xbstream", "10.0.0.5:1234"), "someone-else")
+
+		recorder := serve(middleware.OperationBackup, req)
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		Expect(recorder.Body.String()).To(ContainSubstring("Forbidden: no policy rule matches client 'someone-else' from 10.0.0.5"))
+		Expect(logger).To(gbytes.Say("request denied by policy"))
+	})
+
+	It("denies clients outside of the networks of a rule", func() {
+		req := withCertificate(newRequest("/backup?format=xbstream", "192.168.0.1:1234"), "backup-client")
+
+		recorder := serve(middleware.OperationBackup, req)
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		Expect(recorder.Body.String()).To(ContainSubstring("no policy rule matches client 'backup-client' from 192.168.0.1"))
+	})
+
+	It("denies operations no rule grants", func() {
+		req := withCertificate(newRequest("/backups/some-id/cancel", "10.0.0.5:1234"), "backup-client")
+
+		recorder := serve(middleware.OperationCancel, req)
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		Expect(recorder.Body.String()).To(ContainSubstring("client 'backup-client' from 10.0.0.5 may not cancel"))
+	})
+
+	It("denies backups in formats a rule does not allow", func() {
+		req := withCertificate(newRequest("/backup", "10.0.0.5:1234"), "backup-client")
+
+		recorder := serve(middleware.OperationBackup, req)
+		Expect(recorder.Code).To(Equal(http.StatusForbidden))
+		Expect(recorder.Body.String()).To(ContainSubstring("client 'backup-client' from 10.0.0.5 may not take tar backups, only xbstream"))
+	})
+})
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type RateLimiter struct {
+	Requests int
+	Interval time.Duration
+	Logger   lager.Logger
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	hits   int
+}
+
+// Limit refuses requests beyond the rate of l.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if retry, hits := l.take(); retry > 0 {
+			l.Logger.Info("rate limit hit", lager.Data{
+				"path":        req.URL.Path,
+				"source":      sourceIP(req),
+				"retry_after": retry.Round(time.Millisecond).String(),
+				"hits_total":  hits,
+			})
+			rw.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+			http.Error(rw, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func (l *RateLimiter) take() (time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(l.Requests)
+	perRequest := l.Interval / time.Duration(l.Requests)
+
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens += float64(now.Sub(l.last)) / float64(perRequest)
+		if l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, l.hits
+	}
+
+	l.hits++
+	return time.Duration((1 - l.tokens) * float64(perRequest)), l.hits
+}
@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/middleware"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		logger  *lagertest.TestLogger
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("rate-limit")
+		limiter := &middleware.RateLimiter{
+			Requests: 2,
+			Interval: 200 * time.Millisecond,
+			Logger:   logger,
+		}
+		handler = limiter.Limit(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/backup", nil))
+		return recorder
+	}
+
+	It("lets a burst of requests through and refuses the rest", func() {
+		Expect(request().Code).To(Equal(http.StatusOK))
+		Expect(request().Code).To(Equal(http.StatusOK))
+
+		recorder := request()
+		Expect(recorder.Code).To(Equal(http.StatusTooManyRequests))
+		Expect(recorder.Header().Get("Retry-After")).To(Equal("1"))
+	})
+
+	It("lets requests through again at the configured rate", func() {
+		request()
+		request()
+		Expect(request().Code).To(Equal(http.StatusTooManyRequests))
+
+		time.Sleep(110 * time.Millisecond)
+		Expect(request().Code).To(Equal(http.StatusOK))
+		Expect(request().Code).To(Equal(http.StatusTooManyRequests))
+	})
+
+	It("logs and counts the hits", func() {
+		request()
+		request()
+		request()
+		request()
+
+		Expect(logger).To(gbytes.Say(`"message":"rate-limit.rate limit hit".*"hits_total":1`))
+		Expect(logger).To(gbytes.Say(`"hits_total":2`))
+	})
+})
@@ -0,0 +1,27 @@
+package mysqlcli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func Query(ctx context.Context, defaultsFile, statement string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "mysql",
+		"--defaults-file="+defaultsFile,
+		"--batch",
+		"--skip-column-names",
+		"--execute="+statement,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
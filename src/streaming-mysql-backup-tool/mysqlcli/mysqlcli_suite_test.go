@@ -0,0 +1,22 @@
+package mysqlcli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMySQLCLI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MySQL CLI Suite")
+}
+
+var _ = BeforeSuite(func() {
+	scriptDir, err := filepath.Abs("scripts")
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(os.Setenv("PATH", scriptDir+":"+os.Getenv("PATH"))).To(Succeed())
+})
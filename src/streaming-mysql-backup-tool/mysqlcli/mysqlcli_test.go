@@ -0,0 +1,21 @@
+package mysqlcli_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mysqlcli"
+)
+
+var _ = Describe("Query", func() {
+	It("returns the rows the statement selects", func() {
+		Expect(mysqlcli.Query(context.Background(), "/etc/my.cnf", "SHOW GLOBAL VARIABLES")).To(Equal([]byte("innodb_buffer_pool_size\t134217728\nwsrep_on\tON\n")))
+	})
+
+	It("returns what the client says when it fails", func() {
+		_, err := mysqlcli.Query(context.Background(), "/etc/my.cnf", "SHOW GLOBAL VARIABLES LIKE 'wsrep_provider_options'")
+		Expect(err).To(MatchError("exit status 1: ERROR 2002 (HY000): Can't connect to local MySQL server"))
+	})
+})
@@ -0,0 +1,119 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultAttempts = 3
+
+// Client stores objects in an S3-compatible bucket using path-style URLs.
+type Client struct {
+	Endpoint   string
+	Bucket     string
+	Signer     Signer
+	HTTPClient *http.Client
+	Attempts   int
+}
+
+// PutObject stores body under key, retrying on server and network errors.
+func (c Client) PutObject(ctx context.Context, key string, body []byte) error {
+	attempts := c.Attempts
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var retry bool
+		if retry, err = c.putObject(ctx, key, body); err == nil || !retry {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+	return err
+}
+
+func (c Client) putObject(ctx context.Context, key string, body []byte) (retry bool, err error) {
+	objectURL, err := c.objectURL(key)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	c.Signer.Sign(req, hexSHA256(body), time.Now())
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("storing object %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode >= 500, fmt.Errorf("storing object %s failed: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return false, nil
+}
+
+func (c Client) DeleteObject(ctx context.Context, key string) error {
+	objectURL, err := c.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, objectURL, nil)
+	if err != nil {
+		return err
+	}
+	c.Signer.Sign(req, EmptyPayloadHash, time.Now())
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("deleting object %s failed: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("deleting object %s failed: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+func (c Client) objectURL(key string) (string, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid object store endpoint: %w", err)
+	}
+	return u.JoinPath(c.Bucket, key).String(), nil
+}
+
+// Location is where key is stored, e.g. s3://bucket/key.
+func (c Client) Location(key string) string {
+	return "s3://" + c.Bucket + "/" + key
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
@@ -0,0 +1,175 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+)
+
+const (
+	defaultChunkSize = 16 * 1024 * 1024
+	// Cipher seals each chunk with its name as additional data.
+	Cipher = "AES-256-GCM"
+	Scrypt = "scrypt"
+
+	scryptN, scryptR, scryptP = 32768, 8, 1
+	saltSize                  = 16
+	keySize                   = 32
+)
+
+// Uploader stores backups as encrypted chunks followed by a manifest.
+type Uploader struct {
+	Client        Client
+	Prefix        string
+	ChunkSize     int
+	EncryptionKey string
+}
+
+// Manifest describes a backup stored in object storage.
+type Manifest struct {
+	ID        string    `json:"id"`
+	Format    string    `json:"format"`
+	Bytes     int64     `json:"bytes"`
+	Chunks    []string  `json:"chunks"`
+	Cipher    string    `json:"cipher"`
+	KDF       KDF       `json:"kdf"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type KDF struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// Key derives the key of the backup from encryptionKey.
+func (k KDF) Key(encryptionKey string) ([]byte, error) {
+	if k.Name != Scrypt {
+		return nil, fmt.Errorf("unsupported kdf '%s'", k.Name)
+	}
+	return scrypt.Key([]byte(encryptionKey), k.Salt, k.N, k.R, k.P, keySize)
+}
+
+func (u Uploader) Upload(ctx context.Context, id, format string) (api.Upload, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	kdf := KDF{Name: Scrypt, Salt: salt, N: scryptN, R: scryptR, P: scryptP}
+	key, err := kdf.Key(u.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := u.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	return &upload{
+		ctx:      ctx,
+		client:   u.Client,
+		dir:      path.Join(u.Prefix, id),
+		aead:     aead,
+		buf:      make([]byte, 0, chunkSize),
+		manifest: Manifest{ID: id, Format: format, Chunks: []string{}, Cipher: Cipher, KDF: kdf},
+	}, nil
+}
+
+type upload struct {
+	ctx      context.Context
+	client   Client
+	dir      string
+	aead     cipher.AEAD
+	buf      []byte
+	manifest Manifest
+}
+
+func (u *upload) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(u.buf[len(u.buf):cap(u.buf)], p)
+		u.buf = u.buf[:len(u.buf)+n]
+		p = p[n:]
+		written += n
+
+		if len(u.buf) == cap(u.buf) {
+			if err := u.storeChunk(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (u *upload) Complete() (string, error) {
+	if len(u.buf) > 0 {
+		if err := u.storeChunk(); err != nil {
+			return "", err
+		}
+	}
+
+	u.manifest.CreatedAt = time.Now().UTC()
+	manifest, err := json.Marshal(u.manifest)
+	if err != nil {
+		return "", err
+	}
+	if err := u.client.PutObject(u.ctx, path.Join(u.dir, "manifest.json"), manifest); err != nil {
+		return "", err
+	}
+
+	return u.client.Location(u.dir), nil
+}
+
+func (u *upload) Abort() error {
+	keys := []string{path.Join(u.dir, "manifest.json")}
+	for _, name := range u.manifest.Chunks {
+		keys = append(keys, path.Join(u.dir, name))
+	}
+
+	var firstErr error
+	for _, key := range keys {
+		if err := u.client.DeleteObject(u.ctx, key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (u *upload) storeChunk() error {
+	name := fmt.Sprintf("%08d", len(u.manifest.Chunks)+1)
+
+	nonce := make([]byte, u.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := u.aead.Seal(nonce, nonce, u.buf, []byte(path.Join(u.manifest.ID, name)))
+
+	if err := u.client.PutObject(u.ctx, path.Join(u.dir, name), sealed); err != nil {
+		return err
+	}
+
+	u.manifest.Chunks = append(u.manifest.Chunks, name)
+	u.manifest.Bytes += int64(len(u.buf))
+	u.buf = u.buf[:0]
+	return nil
+}
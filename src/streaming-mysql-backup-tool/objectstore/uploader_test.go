@@ -0,0 +1,238 @@
+package objectstore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/objectstore"
+)
+
+var _ = Describe("Uploader", func() {
+	var (
+		store    *fakeObjectStore
+		server   *httptest.Server
+		signer   objectstore.Signer
+		uploader objectstore.Uploader
+	)
+
+	BeforeEach(func() {
+		signer = objectstore.Signer{
+			AccessKeyID:     "some-access-key",
+			SecretAccessKey: "some-secret-key",
+			Region:          "us-east-1",
+		}
+		store = &fakeObjectStore{signer: signer, bucket: "backups", objects: map[string][]byte{}}
+		server = httptest.NewServer(store)
+
+		uploader = objectstore.Uploader{
+			Client: objectstore.Client{
+				Endpoint: server.URL,
+				Bucket:   "backups",
+				Signer:   signer,
+			},
+			Prefix:        "mysql",
+			ChunkSize:     4,
+			EncryptionKey: "some-encryption-key",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	decrypt := func(kdf objectstore.KDF, name string, sealed []byte) []byte {
+		key, err := kdf.Key("some-encryption-key")
+		Expect(err).NotTo(HaveOccurred())
+		block, err := aes.NewCipher(key)
+		Expect(err).NotTo(HaveOccurred())
+		aead, err := cipher.NewGCM(block)
+		Expect(err).NotTo(HaveOccurred())
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		chunk, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+		Expect(err).NotTo(HaveOccurred())
+		return chunk
+	}
+
+	It("stores the backup as encrypted chunks followed by a manifest", func() {
+		upload, err := uploader.Upload(context.Background(), "some-id", "xbstream")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = io.WriteString(upload, "some-")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(upload, "backup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.keys()).NotTo(ContainElement("mysql/some-id/manifest.json"))
+
+		location, err := upload.Complete()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(location).To(Equal("s3://backups/mysql/some-id"))
+
+		var manifest objectstore.Manifest
+		Expect(json.Unmarshal(store.object("mysql/some-id/manifest.json"), &manifest)).To(Succeed())
+		Expect(manifest.ID).To(Equal("some-id"))
+		Expect(manifest.Format).To(Equal("xbstream"))
+		Expect(manifest.Bytes).To(BeEquivalentTo(len("some-backup")))
+		Expect(manifest.Cipher).To(Equal(objectstore.Cipher))
+		Expect(manifest.Chunks).To(Equal([]string{"00000001", "00000002", "00000003"}))
+		Expect(manifest.KDF.Name).To(Equal(objectstore.Scrypt))
+		Expect(manifest.KDF.Salt).To(HaveLen(16))
+		Expect([]int{manifest.KDF.N, manifest.KDF.R, manifest.KDF.P}).To(Equal([]int{32768, 8, 1}))
+
+		var backup bytes.Buffer
+		for _, name := range manifest.Chunks {
+			sealed := store.object("mysql/some-id/" + name)
+			Expect(sealed).NotTo(ContainSubstring("some"))
+			backup.Write(decrypt(manifest.KDF, "some-id/"+name, sealed))
+		}
+		Expect(backup.String()).To(Equal("some-backup"))
+	})
+
+	It("derives a key of its own for every backup", func() {
+		salts := map[string]bool{}
+		for _, id := range []string{"some-id", "other-id"} {
+			upload, err := uploader.Upload(context.Background(), id, "xbstream")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = upload.Complete()
+			Expect(err).NotTo(HaveOccurred())
+
+			var manifest map[string]any
+			Expect(json.Unmarshal(store.object("mysql/"+id+"/manifest.json"), &manifest)).To(Succeed())
+			salt, err := base64.StdEncoding.DecodeString(manifest["kdf"].(map[string]any)["salt"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			salts[string(salt)] = true
+		}
+		Expect(salts).To(HaveLen(2))
+	})
+
+	It("retries objects the store failed to take", func() {
+		store.failures = 1
+
+		upload, err := uploader.Upload(context.Background(), "some-id", "xbstream")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(upload, "some")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.keys()).To(ContainElement("mysql/some-id/00000001"))
+	})
+
+	It("deletes the chunks of a backup that is aborted", func() {
+		upload, err := uploader.Upload(context.Background(), "some-id", "xbstream")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(upload, "some-backup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.keys()).To(ConsistOf("mysql/some-id/00000001", "mysql/some-id/00000002"))
+
+		Expect(upload.Abort()).To(Succeed())
+		Expect(store.keys()).To(BeEmpty())
+	})
+
+	It("returns an error when the store rejects the request", func() {
+		uploader.Client.Signer.SecretAccessKey = "wrong-secret-key"
+
+		upload, err := uploader.Upload(context.Background(), "some-id", "xbstream")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(upload, "some")
+		Expect(err).To(MatchError(ContainSubstring("storing object mysql/some-id/00000001 failed: 403 Forbidden: SignatureDoesNotMatch")))
+	})
+})
+
+type fakeObjectStore struct {
+	signer objectstore.Signer
+	bucket string
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	failures int
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer GinkgoRecover()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, err := io.ReadAll(req.Body)
+	Expect(err).NotTo(HaveOccurred())
+
+	if !s.validSignature(req, body) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "SlowDown", http.StatusServiceUnavailable)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	switch req.Method {
+	case http.MethodPut:
+		s.objects[key] = body
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+func (s *fakeObjectStore) validSignature(req *http.Request, body []byte) bool {
+	sum := sha256.Sum256(body)
+	if req.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		return false
+	}
+
+	date, err := time.Parse("20060102T150405Z", req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	signed := req.Clone(context.Background())
+	signed.Header = http.Header{}
+	for _, name := range []string{"Content-Type", "X-Amz-Date", "X-Amz-Content-Sha256"} {
+		if value := req.Header.Get(name); value != "" {
+			signed.Header.Set(name, value)
+		}
+	}
+	s.signer.Sign(signed, hex.EncodeToString(sum[:]), date)
+	return signed.Header.Get("Authorization") == req.Header.Get("Authorization")
+}
+
+func (s *fakeObjectStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *fakeObjectStore) object(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.objects[key]
+}
@@ -0,0 +1,25 @@
+package redologarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mysqlcli"
+)
+
+var ErrNoArchiveDirs = errors.New("innodb_redo_log_archive_dirs is not set")
+
+// Check returns why the server cannot archive its redo log, or nil if it can.
+func Check(ctx context.Context, defaultsFile string) error {
+	out, err := mysqlcli.Query(ctx, defaultsFile, "SELECT @@GLOBAL.innodb_redo_log_archive_dirs")
+	if err != nil {
+		return fmt.Errorf("querying innodb_redo_log_archive_dirs failed: %w", err)
+	}
+
+	if dirs := strings.TrimSpace(string(out)); dirs == "" || dirs == "NULL" {
+		return ErrNoArchiveDirs
+	}
+	return nil
+}
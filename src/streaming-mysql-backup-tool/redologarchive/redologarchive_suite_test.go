@@ -0,0 +1,22 @@
+package redologarchive_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRedoLogArchive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Redo Log Archive Suite")
+}
+
+var _ = BeforeSuite(func() {
+	scriptDir, err := filepath.Abs("../mysqlcli/scripts")
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(os.Setenv("PATH", scriptDir+":"+os.Getenv("PATH"))).To(Succeed())
+})
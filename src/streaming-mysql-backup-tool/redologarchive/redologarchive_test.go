@@ -0,0 +1,31 @@
+package redologarchive_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/redologarchive"
+)
+
+var _ = Describe("Check", func() {
+	It("succeeds for servers that can archive their redo log", func() {
+		Expect(redologarchive.Check(context.Background(), "/etc/mysql-8.0.cnf")).To(Succeed())
+	})
+
+	DescribeTable("refuses servers that allow no directory to archive to",
+		func(dirs string) {
+			GinkgoT().Setenv("FAKE_MYSQL_REDO_LOG_ARCHIVE_DIRS", dirs)
+
+			Expect(redologarchive.Check(context.Background(), "/etc/mysql-8.0.cnf")).To(MatchError(redologarchive.ErrNoArchiveDirs))
+		},
+		Entry("when innodb_redo_log_archive_dirs is NULL", "NULL"),
+		Entry("when innodb_redo_log_archive_dirs is empty", ""),
+	)
+
+	It("says why servers without innodb_redo_log_archive_dirs cannot", func() {
+		err := redologarchive.Check(context.Background(), "/etc/mysql-5.7.cnf")
+		Expect(err).To(MatchError("querying innodb_redo_log_archive_dirs failed: exit status 1: ERROR 1193 (HY000) at line 1: Unknown system variable 'innodb_redo_log_archive_dirs'"))
+	})
+})
@@ -0,0 +1,100 @@
+package revocation
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// CRL checks certificates against the revocation lists in File.
+type CRL struct {
+	File   string
+	Logger lager.Logger
+
+	mu    sync.Mutex
+	lists []*x509.RevocationList
+}
+
+// Load reads File.
+func (c *CRL) Load() error {
+	contents, err := os.ReadFile(c.File)
+	if err != nil {
+		return fmt.Errorf("reading CRL file failed: %w", err)
+	}
+
+	var lists []*x509.RevocationList
+	if !bytes.Contains(contents, []byte("-----BEGIN")) {
+		list, err := x509.ParseRevocationList(contents)
+		if err != nil {
+			return fmt.Errorf("parsing CRL file failed: %w", err)
+		}
+		lists = append(lists, list)
+	}
+	for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "X509 CRL" {
+			continue
+		}
+		list, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parsing CRL file failed: %w", err)
+		}
+		lists = append(lists, list)
+	}
+	if len(lists) == 0 {
+		return errors.New("CRL file holds no CRL")
+	}
+
+	c.mu.Lock()
+	c.lists = lists
+	c.mu.Unlock()
+	return nil
+}
+
+// Watch reloads File every interval until ctx is done.
+func (c *CRL) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Load(); err != nil {
+			c.Logger.Error("reloading the CRL failed, keeping the CRL loaded before", err, lager.Data{"file": c.File})
+		}
+	}
+}
+
+func (c *CRL) Status(cert, issuer *x509.Certificate) (Status, error) {
+	c.mu.Lock()
+	lists := c.lists
+	c.mu.Unlock()
+
+	for _, list := range lists {
+		if !bytes.Equal(list.RawIssuer, issuer.RawSubject) || list.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		for _, entry := range list.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return Revoked, nil
+			}
+		}
+		if !list.NextUpdate.IsZero() && time.Now().After(list.NextUpdate) {
+			return Unknown, fmt.Errorf("the CRL of %s expired at %s", issuer.Subject, list.NextUpdate.Format(time.RFC3339))
+		}
+		return Good, nil
+	}
+
+	return Unknown, fmt.Errorf("no CRL of %s is loaded", issuer.Subject)
+}
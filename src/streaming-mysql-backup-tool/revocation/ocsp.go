@@ -0,0 +1,162 @@
+package revocation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	defaultOCSPTimeout   = 5 * time.Second
+	defaultOCSPMaxCached = 1024
+
+	ocspClockSkew = 5 * time.Minute
+	// ocspMaxAge is how old a response without a NextUpdate may be.
+	ocspMaxAge = time.Hour
+)
+
+type OCSP struct {
+	Responder string
+	Timeout   time.Duration
+	MaxCached int
+
+	mu    sync.Mutex
+	cache map[string]*ocsp.Response
+}
+
+func (o *OCSP) Status(cert, issuer *x509.Certificate) (Status, error) {
+	key := string(issuer.RawSubject) + "/" + cert.SerialNumber.String()
+	if response := o.cached(key); response != nil {
+		return result(response)
+	}
+
+	responder := o.Responder
+	if responder == "" {
+		if len(cert.OCSPServer) == 0 {
+			return Unknown, fmt.Errorf("the client certificate %s names no OCSP responder", cert.Subject)
+		}
+		responder = cert.OCSPServer[0]
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return Unknown, fmt.Errorf("creating the OCSP request failed: %w", err)
+	}
+
+	response, err := o.query(responder, request, cert, issuer)
+	if err != nil {
+		return Unknown, fmt.Errorf("querying the OCSP responder %s failed: %w", responder, err)
+	}
+
+	if err := fresh(response, time.Now()); err != nil {
+		return Unknown, fmt.Errorf("the OCSP responder %s sent a stale response for the client certificate %s: %w", responder, cert.Subject, err)
+	}
+
+	if !response.NextUpdate.IsZero() {
+		o.store(key, response)
+	}
+	return result(response)
+}
+
+func fresh(response *ocsp.Response, now time.Time) error {
+	if response.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return fmt.Errorf("it is not valid before %s", response.ThisUpdate.Format(time.RFC3339))
+	}
+	if response.NextUpdate.IsZero() {
+		if response.ThisUpdate.Before(now.Add(-ocspMaxAge)) {
+			return fmt.Errorf("it was produced at %s and names no next update", response.ThisUpdate.Format(time.RFC3339))
+		}
+		return nil
+	}
+	if now.After(response.NextUpdate) {
+		return fmt.Errorf("it expired at %s", response.NextUpdate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (o *OCSP) store(key string, response *ocsp.Response) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.cache == nil {
+		o.cache = map[string]*ocsp.Response{}
+	}
+	maxCached := o.MaxCached
+	if maxCached <= 0 {
+		maxCached = defaultOCSPMaxCached
+	}
+
+	if _, ok := o.cache[key]; !ok && len(o.cache) >= maxCached {
+		now := time.Now()
+		for k, cached := range o.cache {
+			if now.After(cached.NextUpdate) {
+				delete(o.cache, k)
+			}
+		}
+		for len(o.cache) >= maxCached {
+			var first string
+			for k, cached := range o.cache {
+				if first == "" || cached.NextUpdate.Before(o.cache[first].NextUpdate) {
+					first = k
+				}
+			}
+			delete(o.cache, first)
+		}
+	}
+	o.cache[key] = response
+}
+
+func (o *OCSP) query(responder string, request []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = defaultOCSPTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Post(responder, "application/ocsp-request", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(body, cert, issuer)
+}
+
+func (o *OCSP) cached(key string) *ocsp.Response {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	response := o.cache[key]
+	if response == nil {
+		return nil
+	}
+	if time.Now().After(response.NextUpdate) {
+		delete(o.cache, key)
+		return nil
+	}
+	return response
+}
+
+func result(response *ocsp.Response) (Status, error) {
+	switch response.Status {
+	case ocsp.Good:
+		return Good, nil
+	case ocsp.Revoked:
+		return Revoked, nil
+	default:
+		return Unknown, fmt.Errorf("the OCSP responder does not know the client certificate %s", response.SerialNumber)
+	}
+}
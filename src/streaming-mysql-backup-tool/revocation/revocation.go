@@ -0,0 +1,76 @@
+package revocation
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// ErrRevoked is returned for client certificates that have been revoked.
+var ErrRevoked = errors.New("client certificate has been revoked")
+
+// Status is what a source knows about a certificate.
+type Status int
+
+const (
+	Unknown Status = iota
+	Good
+	Revoked
+)
+
+// Source tells whether cert, issued by issuer, has been revoked.
+type Source interface {
+	Status(cert, issuer *x509.Certificate) (Status, error)
+}
+
+type Checker struct {
+	Sources  []Source
+	FailOpen bool
+	Logger   lager.Logger
+}
+
+// Check checks the leaf of a verified chain of client certificates.
+func (c *Checker) Check(chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("no client certificate to check for revocation")
+	}
+
+	cert, issuer := chain[0], chain[0]
+	if len(chain) > 1 {
+		issuer = chain[1]
+	}
+	data := lager.Data{"subject": cert.Subject.String(), "serial": cert.SerialNumber.String()}
+
+	var (
+		good     bool
+		failures []error
+	)
+	for _, source := range c.Sources {
+		status, err := source.Status(cert, issuer)
+		switch {
+		case status == Revoked:
+			c.Logger.Info("refusing revoked client certificate", data)
+			return ErrRevoked
+		case status == Good:
+			good = true
+		case err != nil:
+			failures = append(failures, err)
+		}
+	}
+	if good {
+		return nil
+	}
+
+	err := errors.Join(failures...)
+	if err == nil {
+		err = errors.New("no revocation information for the client certificate")
+	}
+	if c.FailOpen {
+		c.Logger.Error("revocation status of client certificate unknown, accepting it", err, data)
+		return nil
+	}
+	c.Logger.Error("revocation status of client certificate unknown, refusing it", err, data)
+	return fmt.Errorf("checking the client certificate for revocation failed: %w", err)
+}
@@ -0,0 +1,352 @@
+package revocation_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/revocation"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newAuthority() authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "clientCA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	return authority{cert: cert, key: key}
+}
+
+func (a authority) issue(serial int64, ocspServer string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "backup-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, key.Public(), a.key)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+	return cert
+}
+
+func (a authority) crl(nextUpdate time.Time, revoked ...*big.Int) []byte {
+	var entries []x509.RevocationListEntry
+	for _, serial := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}, a.cert, a.key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+var _ = Describe("CRL", func() {
+	var (
+		ca     authority
+		client *x509.Certificate
+		crl    *revocation.CRL
+	)
+
+	BeforeEach(func() {
+		ca = newAuthority()
+		client = ca.issue(42, "")
+		crl = &revocation.CRL{
+			File:   filepath.Join(GinkgoT().TempDir(), "client.crl"),
+			Logger: lagertest.NewTestLogger("crl"),
+		}
+	})
+
+	It("reports certificates on the list as revoked", func() {
+		Expect(os.WriteFile(crl.File, ca.crl(time.Now().Add(time.Hour), big.NewInt(42)), 0644)).To(Succeed())
+		Expect(crl.Load()).To(Succeed())
+
+		Expect(crl.Status(client, ca.cert)).To(Equal(revocation.Revoked))
+	})
+
+	It("reports other certificates as good", func() {
+		Expect(os.WriteFile(crl.File, ca.crl(time.Now().Add(time.Hour), big.NewInt(7)), 0644)).To(Succeed())
+		Expect(crl.Load()).To(Succeed())
+
+		Expect(crl.Status(client, ca.cert)).To(Equal(revocation.Good))
+	})
+
+	It("cannot vouch for certificates once the CRL has expired", func() {
+		Expect(os.WriteFile(crl.File, ca.crl(time.Now().Add(-time.Second)), 0644)).To(Succeed())
+		Expect(crl.Load()).To(Succeed())
+
+		status, err := crl.Status(client, ca.cert)
+		Expect(status).To(Equal(revocation.Unknown))
+		Expect(err).To(MatchError(ContainSubstring("expired")))
+	})
+
+	It("ignores CRLs of other issuers", func() {
+		Expect(os.WriteFile(crl.File, newAuthority().crl(time.Now().Add(time.Hour), big.NewInt(42)), 0644)).To(Succeed())
+		Expect(crl.Load()).To(Succeed())
+
+		status, err := crl.Status(client, ca.cert)
+		Expect(status).To(Equal(revocation.Unknown))
+		Expect(err).To(MatchError(ContainSubstring("no CRL of CN=clientCA is loaded")))
+	})
+
+	It("picks up a replaced CRL and keeps the old one if the new one is invalid", func() {
+		Expect(os.WriteFile(crl.File, ca.crl(time.Now().Add(time.Hour)), 0644)).To(Succeed())
+		Expect(crl.Load()).To(Succeed())
+
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
+		go crl.Watch(ctx, 10*time.Millisecond)
+
+		Expect(os.WriteFile(crl.File, ca.crl(time.Now().Add(time.Hour), big.NewInt(42)), 0644)).To(Succeed())
+		Eventually(func() revocation.Status {
+			status, _ := crl.Status(client, ca.cert)
+			return status
+		}).Should(Equal(revocation.Revoked))
+
+		Expect(os.WriteFile(crl.File, []byte("garbage"), 0644)).To(Succeed())
+		Consistently(func() revocation.Status {
+			status, _ := crl.Status(client, ca.cert)
+			return status
+		}, "100ms").Should(Equal(revocation.Revoked))
+	})
+})
+
+var _ = Describe("OCSP", func() {
+	var (
+		ca         authority
+		responder  *httptest.Server
+		status     int
+		thisUpdate time.Time
+		nextUpdate time.Time
+		requests   int
+	)
+
+	BeforeEach(func() {
+		ca = newAuthority()
+		status = ocsp.Good
+		thisUpdate = time.Now().Add(-time.Minute)
+		nextUpdate = time.Now().Add(time.Hour)
+		requests = 0
+
+		responder = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			request, err := ocsp.ParseRequest(body)
+			Expect(err).NotTo(HaveOccurred())
+
+			response, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+				Status:       status,
+				SerialNumber: request.SerialNumber,
+				ThisUpdate:   thisUpdate,
+				NextUpdate:   nextUpdate,
+				RevokedAt:    time.Now().Add(-time.Minute),
+			}, ca.key)
+			Expect(err).NotTo(HaveOccurred())
+			_, _ = w.Write(response)
+		}))
+		DeferCleanup(responder.Close)
+	})
+
+	It("asks the responder named by the certificate", func() {
+		client := ca.issue(42, responder.URL)
+
+		Expect((&revocation.OCSP{}).Status(client, ca.cert)).To(Equal(revocation.Good))
+	})
+
+	It("reports revoked certificates", func() {
+		status = ocsp.Revoked
+		client := ca.issue(42, "")
+
+		Expect((&revocation.OCSP{Responder: responder.URL}).Status(client, ca.cert)).To(Equal(revocation.Revoked))
+	})
+
+	It("caches responses until their next update", func() {
+		client := ca.issue(42, responder.URL)
+		o := &revocation.OCSP{}
+
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("keeps at most MaxCached responses, dropping the ones that expire first", func() {
+		first, second, third := ca.issue(1, responder.URL), ca.issue(2, responder.URL), ca.issue(3, responder.URL)
+		o := &revocation.OCSP{MaxCached: 2}
+
+		Expect(o.Status(first, ca.cert)).To(Equal(revocation.Good))
+		nextUpdate = nextUpdate.Add(time.Minute)
+		Expect(o.Status(second, ca.cert)).To(Equal(revocation.Good))
+		nextUpdate = nextUpdate.Add(time.Minute)
+		Expect(o.Status(third, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(3))
+
+		Expect(o.Status(third, ca.cert)).To(Equal(revocation.Good))
+		Expect(o.Status(second, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(3))
+		Expect(o.Status(first, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(4))
+	})
+
+	It("drops expired responses from the cache before live ones", func() {
+		live := ca.issue(1, responder.URL)
+		o := &revocation.OCSP{MaxCached: 2}
+		Expect(o.Status(live, ca.cert)).To(Equal(revocation.Good))
+
+		nextUpdate = time.Now().Add(time.Second)
+		Expect(o.Status(ca.issue(2, responder.URL), ca.cert)).To(Equal(revocation.Good))
+
+		time.Sleep(1100 * time.Millisecond)
+		nextUpdate = time.Now().Add(time.Hour)
+		client := ca.issue(3, responder.URL)
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(3))
+
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(o.Status(live, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(3))
+	})
+
+	DescribeTable("refuses stale responses",
+		func(setup func(), reason string) {
+			setup()
+			client := ca.issue(42, responder.URL)
+			o := &revocation.OCSP{}
+
+			status, err := o.Status(client, ca.cert)
+			Expect(status).To(Equal(revocation.Unknown))
+			Expect(err).To(MatchError(ContainSubstring("sent a stale response")))
+			Expect(err).To(MatchError(ContainSubstring(reason)))
+
+			_, _ = o.Status(client, ca.cert)
+			Expect(requests).To(Equal(2))
+		},
+		Entry("whose next update has passed", func() {
+			thisUpdate = time.Now().Add(-2 * time.Hour)
+			nextUpdate = time.Now().Add(-time.Hour)
+		}, "it expired at"),
+		Entry("that are not valid yet", func() {
+			thisUpdate = time.Now().Add(time.Hour)
+		}, "it is not valid before"),
+		Entry("without a next update that are too old", func() {
+			thisUpdate = time.Now().Add(-2 * time.Hour)
+			nextUpdate = time.Time{}
+		}, "names no next update"),
+	)
+
+	It("accepts recent responses without a next update but does not cache them", func() {
+		nextUpdate = time.Time{}
+		client := ca.issue(42, responder.URL)
+		o := &revocation.OCSP{}
+
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(o.Status(client, ca.cert)).To(Equal(revocation.Good))
+		Expect(requests).To(Equal(2))
+	})
+
+	It("fails when the responder cannot be reached", func() {
+		client := ca.issue(42, "http://127.0.0.1:1/ocsp")
+
+		status, err := (&revocation.OCSP{Timeout: time.Second}).Status(client, ca.cert)
+		Expect(status).To(Equal(revocation.Unknown))
+		Expect(err).To(MatchError(ContainSubstring("querying the OCSP responder http://127.0.0.1:1/ocsp failed")))
+	})
+})
+
+type staticSource struct {
+	status revocation.Status
+	err    error
+}
+
+func (s staticSource) Status(*x509.Certificate, *x509.Certificate) (revocation.Status, error) {
+	return s.status, s.err
+}
+
+var _ = Describe("Checker", func() {
+	var (
+		ca      authority
+		chain   []*x509.Certificate
+		logger  *lagertest.TestLogger
+		checker *revocation.Checker
+	)
+
+	BeforeEach(func() {
+		ca = newAuthority()
+		chain = []*x509.Certificate{ca.issue(42, ""), ca.cert}
+		logger = lagertest.NewTestLogger("revocation")
+		checker = &revocation.Checker{Logger: logger}
+	})
+
+	It("refuses certificates any source reports as revoked", func() {
+		checker.Sources = []revocation.Source{staticSource{status: revocation.Good}, staticSource{status: revocation.Revoked}}
+
+		Expect(checker.Check(chain)).To(MatchError(revocation.ErrRevoked))
+	})
+
+	It("accepts certificates a source vouches for", func() {
+		checker.Sources = []revocation.Source{staticSource{err: io.EOF}, staticSource{status: revocation.Good}}
+
+		Expect(checker.Check(chain)).To(Succeed())
+	})
+
+	It("fails closed by default", func() {
+		checker.Sources = []revocation.Source{staticSource{err: io.EOF}}
+
+		Expect(checker.Check(chain)).To(MatchError(ContainSubstring("checking the client certificate for revocation failed: EOF")))
+	})
+
+	It("fails open when configured to", func() {
+		checker.Sources = []revocation.Source{staticSource{err: io.EOF}}
+		checker.FailOpen = true
+
+		Expect(checker.Check(chain)).To(Succeed())
+		Expect(logger).To(gbytes.Say("revocation status of client certificate unknown, accepting it"))
+	})
+})
@@ -0,0 +1,13 @@
+package serverversion_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestServerVersion(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Server Version Suite")
+}
@@ -0,0 +1,52 @@
+package serverversion
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/mysqlcli"
+)
+
+var versionNumber = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+type Version struct {
+	Raw   string
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v Version) MariaDB() bool {
+	return strings.Contains(strings.ToLower(v.Raw), "mariadb")
+}
+
+func (v Version) String() string {
+	return v.Raw
+}
+
+func Parse(raw string) (Version, error) {
+	raw = strings.TrimSpace(raw)
+
+	m := versionNumber.FindStringSubmatch(raw)
+	if m == nil {
+		return Version{}, fmt.Errorf("unrecognized server version %q", raw)
+	}
+
+	v := Version{Raw: raw}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+func Query(ctx context.Context, defaultsFile string) (Version, error) {
+	out, err := mysqlcli.Query(ctx, defaultsFile, "SELECT VERSION()")
+	if err != nil {
+		return Version{}, fmt.Errorf("querying the server version failed: %w", err)
+	}
+
+	return Parse(string(out))
+}
@@ -0,0 +1,29 @@
+package serverversion_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/serverversion"
+)
+
+var _ = Describe("Parse", func() {
+	DescribeTable("parses the output of SELECT VERSION()",
+		func(raw string, major, minor, patch int, mariaDB bool) {
+			v, err := serverversion.Parse(raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.Major).To(Equal(major))
+			Expect(v.Minor).To(Equal(minor))
+			Expect(v.Patch).To(Equal(patch))
+			Expect(v.MariaDB()).To(Equal(mariaDB))
+		},
+		Entry("Percona Server 8.0", "8.0.35-27\n", 8, 0, 35, false),
+		Entry("MySQL 5.7", "5.7.44-log", 5, 7, 44, false),
+		Entry("MariaDB", "10.6.16-MariaDB-log\n", 10, 6, 16, true),
+	)
+
+	It("refuses anything that is not a version", func() {
+		_, err := serverversion.Parse("ERROR 2002 (HY000)")
+		Expect(err).To(MatchError(`unrecognized server version "ERROR 2002 (HY000)"`))
+	})
+})
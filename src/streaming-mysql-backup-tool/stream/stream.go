@@ -0,0 +1,186 @@
+// Package stream writes a single backup to stdout.
+package stream
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+
+	"github.com/google/uuid"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/config"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/engine"
+)
+
+type Options struct {
+	ConfigPath   string
+	DefaultsFile string
+	TmpDir       string
+	Engine       string
+	Format       string
+	Compress     string
+	Checksum     string
+	Verbose      bool
+}
+
+func ParseOptions(args []string, output io.Writer) (Options, error) {
+	var o Options
+
+	flags := flag.NewFlagSet("stream", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&o.ConfigPath, "configPath", "", "config file of the tool to read the XtraBackup options from")
+	flags.StringVar(&o.DefaultsFile, "defaults-file", "", "MySQL defaults file to connect with")
+	flags.StringVar(&o.TmpDir, "tmp-dir", "", "directory for temporary files of the backup")
+	flags.StringVar(&o.Engine, "engine", "", "xtrabackup, mariabackup, clone or auto")
+	flags.StringVar(&o.Format, "format", "tar", "tar or xbstream")
+	flags.StringVar(&o.Compress, "compress", "none", "none or gzip")
+	flags.StringVar(&o.Checksum, "checksum", "none", "none or sha256, reported once the backup has been written")
+	flags.BoolVar(&o.Verbose, "verbose", false, "log progress and the output of the backup to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		return o, err
+	}
+	if err := o.validate(flags.Args()); err != nil {
+		fmt.Fprintln(output, err)
+		return o, err
+	}
+	return o, nil
+}
+
+func (o Options) validate(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+
+	switch o.Format {
+	case "tar", "xbstream":
+	default:
+		return fmt.Errorf("invalid format '%s', must be 'tar' or 'xbstream'", o.Format)
+	}
+	switch o.Engine {
+	case "", engine.XtraBackup, engine.MariaBackup, engine.Clone, engine.Auto:
+	default:
+		return fmt.Errorf("invalid engine '%s', must be 'xtrabackup', 'mariabackup', 'clone' or 'auto'", o.Engine)
+	}
+	switch o.Compress {
+	case "none", "gzip":
+	default:
+		return fmt.Errorf("invalid compression '%s', must be 'none' or 'gzip'", o.Compress)
+	}
+	switch o.Checksum {
+	case "none", "sha256":
+	default:
+		return fmt.Errorf("invalid checksum '%s', must be 'none' or 'sha256'", o.Checksum)
+	}
+	if o.ConfigPath == "" && o.DefaultsFile == "" {
+		return errors.New("either -configPath or -defaults-file is required")
+	}
+	return nil
+}
+
+// XtraBackup returns the XtraBackup options the backup is taken with.
+func (o Options) XtraBackup() (config.XtraBackup, error) {
+	x := config.XtraBackup{Engine: "xtrabackup", TmpDir: "/tmp"}
+	if o.ConfigPath != "" {
+		var err error
+		if x, err = config.ReadXtraBackup(o.ConfigPath); err != nil {
+			return x, err
+		}
+	}
+
+	if o.DefaultsFile != "" {
+		x.DefaultsFile = o.DefaultsFile
+	}
+	if o.TmpDir != "" {
+		x.TmpDir = o.TmpDir
+	}
+	if o.Engine != "" {
+		x.Engine = o.Engine
+	}
+	return x, nil
+}
+
+// Result is reported on stderr as a single line of JSON.
+type Result struct {
+	BackupID    string `json:"backup_id"`
+	Format      string `json:"format"`
+	Compression string `json:"compression"`
+	Bytes       int64  `json:"bytes"`
+	SHA256      string `json:"sha256,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+}
+
+var failureKind = regexp.MustCompile(`^([A-Z][A-Z_]+):`)
+
+func Run(ctx context.Context, writer api.BackupWriter, o Options, stdout, log io.Writer) Result {
+	result := Result{
+		BackupID:    uuid.New().String(),
+		Format:      o.Format,
+		Compression: o.Compress,
+	}
+
+	counter := &countingWriter{w: stdout}
+	var out io.Writer = counter
+
+	var sum hash.Hash
+	if o.Checksum == "sha256" {
+		sum = sha256.New()
+		out = io.MultiWriter(counter, sum)
+	}
+
+	var gz *gzip.Writer
+	if o.Compress == "gzip" {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+
+	err := writer.StreamTo(ctx, api.BackupRequest{
+		ID:     result.BackupID,
+		Format: o.Format,
+		Log:    log,
+	}, out)
+	if gz != nil {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	result.Bytes = counter.n
+	if err != nil {
+		result.Error = err.Error()
+		switch m := failureKind.FindStringSubmatch(result.Error); {
+		case m != nil:
+			result.Kind = m[1]
+		case ctx.Err() != nil:
+			result.Kind = "CANCELLED"
+		default:
+			result.Kind = "FATAL"
+		}
+		return result
+	}
+
+	if sum != nil {
+		result.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	}
+	return result
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
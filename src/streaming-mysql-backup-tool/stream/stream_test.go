@@ -0,0 +1,159 @@
+package stream_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/stream"
+)
+
+var _ = Describe("ParseOptions", func() {
+	var output *bytes.Buffer
+
+	BeforeEach(func() {
+		output = &bytes.Buffer{}
+	})
+
+	It("defaults to an uncompressed tar stream", func() {
+		o, err := stream.ParseOptions([]string{"-defaults-file=/my.cnf"}, output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(o).To(Equal(stream.Options{
+			DefaultsFile: "/my.cnf",
+			Format:       "tar",
+			Compress:     "none",
+			Checksum:     "none",
+		}))
+	})
+
+	DescribeTable("rejects invalid options",
+		func(args []string, message string) {
+			_, err := stream.ParseOptions(args, output)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+			Expect(output.String()).To(ContainSubstring(message))
+		},
+		Entry("format", []string{"-defaults-file=/my.cnf", "-format=zip"}, "invalid format 'zip'"),
+		Entry("engine", []string{"-defaults-file=/my.cnf", "-engine=mysqldump"}, "invalid engine 'mysqldump'"),
+		Entry("compression", []string{"-defaults-file=/my.cnf", "-compress=lz4"}, "invalid compression 'lz4'"),
+		Entry("checksum", []string{"-defaults-file=/my.cnf", "-checksum=md5"}, "invalid checksum 'md5'"),
+		Entry("no server", []string{}, "either -configPath or -defaults-file is required"),
+		Entry("arguments", []string{"-defaults-file=/my.cnf", "extra"}, "unexpected arguments [extra]"),
+	)
+
+	It("reads the XtraBackup options from the config file and overrides them with the flags", func() {
+		configPath := filepath.Join(GinkgoT().TempDir(), "config.yml")
+		Expect(os.WriteFile(configPath, []byte(`
+XtraBackup:
+  DefaultsFile: /var/vcap/jobs/mysql/config/mylogin.cnf
+  TmpDir: /var/vcap/data/tmp
+`), 0600)).To(Succeed())
+
+		o, err := stream.ParseOptions([]string{"-configPath=" + configPath, "-tmp-dir=/scratch", "-engine=clone"}, output)
+		Expect(err).NotTo(HaveOccurred())
+
+		xb, err := o.XtraBackup()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(xb.DefaultsFile).To(Equal("/var/vcap/jobs/mysql/config/mylogin.cnf"))
+		Expect(xb.TmpDir).To(Equal("/scratch"))
+		Expect(xb.Engine).To(Equal("clone"))
+	})
+})
+
+var _ = Describe("Run", func() {
+	var (
+		writer  *stubBackupWriter
+		options stream.Options
+		stdout  *bytes.Buffer
+		log     *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		writer = &stubBackupWriter{content: "some-backup", log: "xtrabackup output\n"}
+		options = stream.Options{Format: "xbstream", Compress: "none", Checksum: "none"}
+		stdout = &bytes.Buffer{}
+		log = &bytes.Buffer{}
+	})
+
+	It("writes the backup to stdout in the requested format", func() {
+		result := stream.Run(context.Background(), writer, options, stdout, log)
+
+		Expect(result.Error).To(BeEmpty())
+		Expect(stdout.String()).To(Equal("some-backup"))
+		Expect(log.String()).To(Equal("xtrabackup output\n"))
+		Expect(writer.req.Format).To(Equal("xbstream"))
+		Expect(writer.req.ID).NotTo(BeEmpty())
+		Expect(result.BackupID).To(Equal(writer.req.ID))
+		Expect(result.Bytes).To(BeEquivalentTo(len("some-backup")))
+		Expect(result.SHA256).To(BeEmpty())
+	})
+
+	It("compresses the backup with gzip", func() {
+		options.Compress = "gzip"
+
+		result := stream.Run(context.Background(), writer, options, stdout, log)
+		Expect(result.Error).To(BeEmpty())
+		Expect(result.Bytes).To(BeEquivalentTo(stdout.Len()))
+
+		r, err := gzip.NewReader(stdout)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(io.ReadAll(r)).To(BeEquivalentTo("some-backup"))
+	})
+
+	It("reports the checksum of what was written to stdout", func() {
+		options.Compress = "gzip"
+		options.Checksum = "sha256"
+
+		result := stream.Run(context.Background(), writer, options, stdout, log)
+		Expect(result.Error).To(BeEmpty())
+
+		sum := sha256.Sum256(stdout.Bytes())
+		Expect(result.SHA256).To(Equal(hex.EncodeToString(sum[:])))
+	})
+
+	DescribeTable("classifies failures",
+		func(err error, kind string) {
+			writer.err = err
+
+			result := stream.Run(context.Background(), writer, options, stdout, log)
+			Expect(result.Error).To(Equal(err.Error()))
+			Expect(result.Kind).To(Equal(kind))
+		},
+		Entry("by their code", errors.New("REDO_LOG_OVERRUN: xtrabackup could not keep up with the redo log"), "REDO_LOG_OVERRUN"),
+		Entry("as fatal otherwise", errors.New("exit status 1"), "FATAL"),
+	)
+
+	It("classifies failures after the context is done as cancelled", func() {
+		writer.err = errors.New("signal: killed")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result := stream.Run(ctx, writer, options, stdout, log)
+		Expect(result.Kind).To(Equal("CANCELLED"))
+	})
+})
+
+type stubBackupWriter struct {
+	req     api.BackupRequest
+	content string
+	log     string
+	err     error
+}
+
+func (s *stubBackupWriter) StreamTo(_ context.Context, req api.BackupRequest, w io.Writer) error {
+	s.req = req
+	if req.Log != nil {
+		_, _ = io.WriteString(req.Log, s.log)
+	}
+	_, _ = io.WriteString(w, s.content)
+	return s.err
+}
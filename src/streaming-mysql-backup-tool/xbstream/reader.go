@@ -0,0 +1,162 @@
+package xbstream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	chunkTypeSparse = 'S'
+
+	chunkFlagIgnorable = 0x01
+)
+
+// maxPathLen keeps a corrupt stream from allocating arbitrary amounts of memory.
+const maxPathLen = 512
+
+// Chunk is a part of a file in an xbstream archive.
+type Chunk struct {
+	Path      string
+	EOF       bool
+	Offset    uint64
+	Payload   []byte
+	SparseMap []SparseEntry
+}
+
+type SparseEntry struct {
+	Hole uint32
+	Len  uint32
+}
+
+// End returns the offset in the file just after the chunk.
+func (c Chunk) End() uint64 {
+	if c.SparseMap == nil {
+		return c.Offset + uint64(len(c.Payload))
+	}
+	end := c.Offset
+	for _, e := range c.SparseMap {
+		end += uint64(e.Hole) + uint64(e.Len)
+	}
+	return end
+}
+
+// Reader reads the chunks of an xbstream archive.
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next chunk, whose payload is only valid until the next call.
+func (x *Reader) Next() (Chunk, error) {
+	for {
+		c, skip, err := x.next()
+		if err != nil || !skip {
+			return c, err
+		}
+	}
+}
+
+func (x *Reader) next() (c Chunk, skip bool, err error) {
+	header := make([]byte, len(chunkMagic)+6)
+	if _, err := io.ReadFull(x.r, header); err != nil {
+		if errors.Is(err, io.EOF) {
+			return c, false, io.EOF
+		}
+		return c, false, fmt.Errorf("xbstream: reading chunk header: %w", unexpected(err))
+	}
+	if string(header[:len(chunkMagic)]) != chunkMagic {
+		return c, false, errors.New("xbstream: wrong chunk magic")
+	}
+
+	flags, chunkType := header[len(chunkMagic)], header[len(chunkMagic)+1]
+	pathLen := binary.LittleEndian.Uint32(header[len(chunkMagic)+2:])
+	if pathLen > maxPathLen {
+		return c, false, fmt.Errorf("xbstream: path of %d bytes is too long", pathLen)
+	}
+
+	path := make([]byte, pathLen)
+	if _, err := io.ReadFull(x.r, path); err != nil {
+		return c, false, fmt.Errorf("xbstream: reading chunk path: %w", unexpected(err))
+	}
+	c.Path = string(path)
+
+	switch chunkType {
+	case chunkTypeEOF:
+		c.EOF = true
+		return c, false, nil
+	case chunkTypePayload, chunkTypeSparse:
+	default:
+		if flags&chunkFlagIgnorable == 0 {
+			return c, false, fmt.Errorf("xbstream: unknown chunk type '%c' for %s", chunkType, c.Path)
+		}
+		// Ignorable chunks have the layout of payload chunks.
+		skip = true
+	}
+
+	var sparseMapSize uint32
+	if chunkType == chunkTypeSparse {
+		if err := binary.Read(x.r, binary.LittleEndian, &sparseMapSize); err != nil {
+			return c, false, fmt.Errorf("xbstream: reading sparse map size of %s: %w", c.Path, unexpected(err))
+		}
+	}
+
+	var fields struct {
+		Length   uint64
+		Offset   uint64
+		Checksum uint32
+	}
+	if err := binary.Read(x.r, binary.LittleEndian, &fields); err != nil {
+		return c, false, fmt.Errorf("xbstream: reading chunk of %s: %w", c.Path, unexpected(err))
+	}
+	c.Offset = fields.Offset
+
+	if sparseMapSize > 0 {
+		c.SparseMap = make([]SparseEntry, sparseMapSize)
+		if err := binary.Read(x.r, binary.LittleEndian, c.SparseMap); err != nil {
+			return c, false, fmt.Errorf("xbstream: reading sparse map of %s: %w", c.Path, unexpected(err))
+		}
+	}
+
+	if skip {
+		if _, err := x.r.Discard(int(fields.Length)); err != nil {
+			return c, false, fmt.Errorf("xbstream: skipping chunk of %s: %w", c.Path, unexpected(err))
+		}
+		return c, true, nil
+	}
+
+	if fields.Length > uint64(cap(x.buf)) {
+		x.buf = make([]byte, fields.Length)
+	}
+	c.Payload = x.buf[:fields.Length]
+	if _, err := io.ReadFull(x.r, c.Payload); err != nil {
+		return c, false, fmt.Errorf("xbstream: reading payload of %s: %w", c.Path, unexpected(err))
+	}
+	if crc32.ChecksumIEEE(c.Payload) != fields.Checksum {
+		return c, false, fmt.Errorf("xbstream: checksum mismatch in %s at offset %d", c.Path, c.Offset)
+	}
+
+	var mapped uint64
+	for _, e := range c.SparseMap {
+		mapped += uint64(e.Len)
+	}
+	if c.SparseMap != nil && mapped != fields.Length {
+		return c, false, fmt.Errorf("xbstream: sparse map of %s covers %d bytes, the payload has %d", c.Path, mapped, fields.Length)
+	}
+
+	return c, false, nil
+}
+
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
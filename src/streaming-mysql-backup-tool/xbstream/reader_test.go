@@ -0,0 +1,109 @@
+package xbstream_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+// sparseMap holds pairs of hole and length.
+func writeChunk(archive *bytes.Buffer, flags, chunkType byte, path string, offset uint64, payload string, sparseMap ...uint32) {
+	archive.WriteString("XBSTCK01")
+	archive.Write([]byte{flags, chunkType})
+	_ = binary.Write(archive, binary.LittleEndian, uint32(len(path)))
+	archive.WriteString(path)
+	if chunkType == 'E' {
+		return
+	}
+	if chunkType == 'S' {
+		_ = binary.Write(archive, binary.LittleEndian, uint32(len(sparseMap)/2))
+	}
+	_ = binary.Write(archive, binary.LittleEndian, uint64(len(payload)))
+	_ = binary.Write(archive, binary.LittleEndian, offset)
+	_ = binary.Write(archive, binary.LittleEndian, crc32.ChecksumIEEE([]byte(payload)))
+	_ = binary.Write(archive, binary.LittleEndian, sparseMap)
+	archive.WriteString(payload)
+}
+
+func readAll(r *xbstream.Reader) ([]xbstream.Chunk, error) {
+	var chunks []xbstream.Chunk
+	for {
+		c, err := r.Next()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		c.Payload = bytes.Clone(c.Payload)
+		chunks = append(chunks, c)
+	}
+}
+
+var _ = Describe("Reader", func() {
+	It("reads the chunks written by Writer", func() {
+		var archive bytes.Buffer
+		w := xbstream.NewWriterSize(&archive, 4)
+		Expect(w.WriteFile("ibdata1", strings.NewReader("012345"))).To(Succeed())
+
+		chunks, err := readAll(xbstream.NewReader(&archive))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chunks).To(Equal([]xbstream.Chunk{
+			{Path: "ibdata1", Offset: 0, Payload: []byte("0123")},
+			{Path: "ibdata1", Offset: 4, Payload: []byte("45")},
+			{Path: "ibdata1", EOF: true},
+		}))
+	})
+
+	It("reads sparse chunks", func() {
+		var archive bytes.Buffer
+		writeChunk(&archive, 0, 'S', "big.ibd", 8, "abcde", 2, 3, 4, 2)
+
+		chunks, err := readAll(xbstream.NewReader(&archive))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chunks).To(Equal([]xbstream.Chunk{{
+			Path:      "big.ibd",
+			Offset:    8,
+			Payload:   []byte("abcde"),
+			SparseMap: []xbstream.SparseEntry{{Hole: 2, Len: 3}, {Hole: 4, Len: 2}},
+		}}))
+		Expect(chunks[0].End()).To(BeEquivalentTo(8 + 2 + 3 + 4 + 2))
+	})
+
+	It("skips ignorable chunks of unknown types", func() {
+		var archive bytes.Buffer
+		writeChunk(&archive, 1, 'X', "ibdata1", 0, "unknown")
+		writeChunk(&archive, 0, 'E', "ibdata1", 0, "")
+
+		chunks, err := readAll(xbstream.NewReader(&archive))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chunks).To(Equal([]xbstream.Chunk{{Path: "ibdata1", EOF: true}}))
+	})
+
+	DescribeTable("rejects corrupt archives",
+		func(write func(*bytes.Buffer), message string) {
+			var archive bytes.Buffer
+			write(&archive)
+
+			_, err := readAll(xbstream.NewReader(&archive))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("wrong magic", func(b *bytes.Buffer) { b.WriteString("XBSTCK02\x00P\x00\x00\x00\x00") }, "wrong chunk magic"),
+		Entry("unknown chunk type", func(b *bytes.Buffer) { writeChunk(b, 0, 'X', "ibdata1", 0, "unknown") }, "unknown chunk type 'X' for ibdata1"),
+		Entry("truncated chunk", func(b *bytes.Buffer) {
+			writeChunk(b, 0, 'P', "ibdata1", 0, "0123")
+			b.Truncate(b.Len() - 1)
+		}, "reading payload of ibdata1: unexpected EOF"),
+		Entry("checksum mismatch", func(b *bytes.Buffer) {
+			writeChunk(b, 0, 'P', "ibdata1", 0, "0123")
+			b.Bytes()[b.Len()-1] = 'x'
+		}, "checksum mismatch in ibdata1 at offset 0"),
+	)
+})
@@ -0,0 +1,145 @@
+package xbstream
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ToTar transcodes xbstream to tar, spooling each file under spoolDir until it is complete.
+func ToTar(r io.Reader, w io.Writer, spoolDir string) error {
+	dir, err := os.MkdirTemp(spoolDir, "xbstream-tar-")
+	if err != nil {
+		return fmt.Errorf("creating spool directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	t := transcoder{
+		dir:   dir,
+		tw:    tar.NewWriter(w),
+		files: map[string]*spooledFile{},
+	}
+	defer t.discard()
+
+	xr := NewReader(r)
+	for {
+		c, err := xr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.add(c); err != nil {
+			return err
+		}
+	}
+
+	if len(t.files) > 0 {
+		var incomplete []string
+		for name := range t.files {
+			incomplete = append(incomplete, name)
+		}
+		sort.Strings(incomplete)
+		return fmt.Errorf("xbstream: archive ended before %s was complete", strings.Join(incomplete, ", "))
+	}
+
+	return t.tw.Close()
+}
+
+type spooledFile struct {
+	*os.File
+	size int64
+}
+
+type transcoder struct {
+	dir   string
+	tw    *tar.Writer
+	files map[string]*spooledFile
+}
+
+func (t *transcoder) add(c Chunk) error {
+	name, err := cleanPath(c.Path)
+	if err != nil {
+		return err
+	}
+
+	f := t.files[name]
+	if f == nil {
+		file, err := os.CreateTemp(t.dir, "file-")
+		if err != nil {
+			return fmt.Errorf("spooling %s: %w", name, err)
+		}
+		f = &spooledFile{File: file}
+		t.files[name] = f
+	}
+
+	if c.EOF {
+		delete(t.files, name)
+		defer f.Close()
+		return t.writeEntry(name, f)
+	}
+
+	offset, payload := int64(c.Offset), c.Payload
+	entries := c.SparseMap
+	if entries == nil {
+		entries = []SparseEntry{{Len: uint32(len(payload))}}
+	}
+	for _, e := range entries {
+		offset += int64(e.Hole)
+		if _, err := f.WriteAt(payload[:e.Len], offset); err != nil {
+			return fmt.Errorf("spooling %s: %w", name, err)
+		}
+		offset += int64(e.Len)
+		payload = payload[e.Len:]
+	}
+	f.size = max(f.size, int64(c.End()))
+
+	return nil
+}
+
+func (t *transcoder) writeEntry(name string, f *spooledFile) error {
+	// A trailing hole is not backed by any write.
+	if err := f.Truncate(f.size); err != nil {
+		return fmt.Errorf("spooling %s: %w", name, err)
+	}
+	defer os.Remove(f.Name())
+
+	err := t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     f.size,
+		Mode:     0640,
+		ModTime:  time.Now(),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tw, f.File)
+	return err
+}
+
+func (t *transcoder) discard() {
+	for _, f := range t.files {
+		_ = f.Close()
+	}
+}
+
+func cleanPath(name string) (string, error) {
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("xbstream: invalid path %q", name)
+	}
+	return clean, nil
+}
@@ -0,0 +1,106 @@
+package xbstream_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+func untar(r io.Reader) map[string]string {
+	files := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(tr)
+		Expect(err).NotTo(HaveOccurred())
+		files[header.Name] = string(content)
+	}
+}
+
+var _ = Describe("ToTar", func() {
+	var (
+		spoolDir string
+		archive  bytes.Buffer
+		out      bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		spoolDir = GinkgoT().TempDir()
+		archive.Reset()
+		out.Reset()
+	})
+
+	It("transcodes the files of the archive to tar entries", func() {
+		w := xbstream.NewWriterSize(&archive, 4)
+		Expect(w.WriteFile("ibdata1", strings.NewReader("0123456789"))).To(Succeed())
+		Expect(w.WriteFile("mysql/user.ibd", strings.NewReader("users"))).To(Succeed())
+		Expect(w.WriteFile("xtrabackup_checkpoints", strings.NewReader(""))).To(Succeed())
+
+		Expect(xbstream.ToTar(&archive, &out, spoolDir)).To(Succeed())
+		Expect(untar(&out)).To(Equal(map[string]string{
+			"ibdata1":                "0123456789",
+			"mysql/user.ibd":         "users",
+			"xtrabackup_checkpoints": "",
+		}))
+	})
+
+	It("transcodes files whose chunks are interleaved", func() {
+		writeChunk(&archive, 0, 'P', "a.ibd", 0, "aa")
+		writeChunk(&archive, 0, 'P', "b.ibd", 0, "bb")
+		writeChunk(&archive, 0, 'P', "a.ibd", 2, "AA")
+		writeChunk(&archive, 0, 'E', "a.ibd", 0, "")
+		writeChunk(&archive, 0, 'P', "b.ibd", 2, "BB")
+		writeChunk(&archive, 0, 'E', "b.ibd", 0, "")
+
+		Expect(xbstream.ToTar(&archive, &out, spoolDir)).To(Succeed())
+		Expect(untar(&out)).To(Equal(map[string]string{
+			"a.ibd": "aaAA",
+			"b.ibd": "bbBB",
+		}))
+	})
+
+	It("fills the holes of sparse files with zeros", func() {
+		writeChunk(&archive, 0, 'P', "big.ibd", 0, "ab")
+		writeChunk(&archive, 0, 'S', "big.ibd", 2, "cde", 2, 1, 3, 2)
+		writeChunk(&archive, 0, 'S', "big.ibd", 10, "", 4, 0)
+		writeChunk(&archive, 0, 'E', "big.ibd", 0, "")
+
+		Expect(xbstream.ToTar(&archive, &out, spoolDir)).To(Succeed())
+		Expect(untar(&out)).To(Equal(map[string]string{
+			"big.ibd": "ab\x00\x00c\x00\x00\x00de\x00\x00\x00\x00",
+		}))
+	})
+
+	It("fails when the archive ends before a file is complete", func() {
+		writeChunk(&archive, 0, 'P', "ibdata1", 0, "0123")
+
+		err := xbstream.ToTar(&archive, &out, spoolDir)
+		Expect(err).To(MatchError("xbstream: archive ended before ibdata1 was complete"))
+	})
+
+	It("rejects paths outside of the archive", func() {
+		writeChunk(&archive, 0, 'E', "../etc/passwd", 0, "")
+
+		err := xbstream.ToTar(&archive, &out, spoolDir)
+		Expect(err).To(MatchError(`xbstream: invalid path "../etc/passwd"`))
+	})
+
+	It("removes the spooled files", func() {
+		writeChunk(&archive, 0, 'P', "ibdata1", 0, "0123")
+		_ = xbstream.ToTar(&archive, &out, spoolDir)
+
+		Expect(os.ReadDir(spoolDir)).To(BeEmpty())
+	})
+})
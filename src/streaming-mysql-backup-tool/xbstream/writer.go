@@ -0,0 +1,84 @@
+package xbstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	chunkMagic = "XBSTCK01"
+
+	chunkTypePayload = 'P'
+	chunkTypeEOF     = 'E'
+
+	DefaultChunkSize = 10 * 1024 * 1024
+)
+
+type Writer struct {
+	w         io.Writer
+	chunkSize int
+	buf       []byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterSize(w, DefaultChunkSize)
+}
+
+func NewWriterSize(w io.Writer, chunkSize int) *Writer {
+	return &Writer{w: w, chunkSize: chunkSize}
+}
+
+func (x *Writer) WriteFile(name string, r io.Reader) error {
+	if x.buf == nil {
+		x.buf = make([]byte, x.chunkSize)
+	}
+
+	var offset uint64
+	for {
+		n, err := io.ReadFull(r, x.buf)
+		if n > 0 {
+			if werr := x.writePayload(name, x.buf[:n], offset); werr != nil {
+				return werr
+			}
+			offset += uint64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return x.writeHeader(name, chunkTypeEOF)
+}
+
+func (x *Writer) writeHeader(name string, chunkType byte) error {
+	header := make([]byte, 0, len(chunkMagic)+6+len(name))
+	header = append(header, chunkMagic...)
+	header = append(header, 0, chunkType)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(name)))
+	header = append(header, name...)
+
+	_, err := x.w.Write(header)
+	return err
+}
+
+func (x *Writer) writePayload(name string, payload []byte, offset uint64) error {
+	if err := x.writeHeader(name, chunkTypePayload); err != nil {
+		return err
+	}
+
+	fields := make([]byte, 0, 20)
+	fields = binary.LittleEndian.AppendUint64(fields, uint64(len(payload)))
+	fields = binary.LittleEndian.AppendUint64(fields, offset)
+	fields = binary.LittleEndian.AppendUint32(fields, crc32.ChecksumIEEE(payload))
+	if _, err := x.w.Write(fields); err != nil {
+		return err
+	}
+
+	_, err := x.w.Write(payload)
+	return err
+}
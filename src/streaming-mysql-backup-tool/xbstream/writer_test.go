@@ -0,0 +1,78 @@
+package xbstream_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
+)
+
+type chunk struct {
+	Type     byte
+	Path     string
+	Offset   uint64
+	Payload  string
+	Checksum uint32
+}
+
+func readChunks(r io.Reader) []chunk {
+	var chunks []chunk
+	for {
+		magic := make([]byte, 8)
+		if _, err := io.ReadFull(r, magic); err == io.EOF {
+			return chunks
+		} else {
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(string(magic)).To(Equal("XBSTCK01"))
+
+		var header struct {
+			Flags   byte
+			Type    byte
+			PathLen uint32
+		}
+		Expect(binary.Read(r, binary.LittleEndian, &header)).To(Succeed())
+		path := make([]byte, header.PathLen)
+		_, err := io.ReadFull(r, path)
+		Expect(err).NotTo(HaveOccurred())
+
+		c := chunk{Type: header.Type, Path: string(path)}
+		if c.Type == 'P' {
+			var fields struct {
+				Length   uint64
+				Offset   uint64
+				Checksum uint32
+			}
+			Expect(binary.Read(r, binary.LittleEndian, &fields)).To(Succeed())
+			payload := make([]byte, fields.Length)
+			_, err := io.ReadFull(r, payload)
+			Expect(err).NotTo(HaveOccurred())
+			c.Offset, c.Payload, c.Checksum = fields.Offset, string(payload), fields.Checksum
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+var _ = Describe("Writer", func() {
+	It("writes each file as payload chunks followed by an EOF chunk", func() {
+		var archive bytes.Buffer
+		w := xbstream.NewWriterSize(&archive, 4)
+
+		Expect(w.WriteFile("ibdata1", strings.NewReader("0123456789"))).To(Succeed())
+		Expect(w.WriteFile("mysql/empty.ibd", strings.NewReader(""))).To(Succeed())
+
+		Expect(readChunks(&archive)).To(Equal([]chunk{
+			{Type: 'P', Path: "ibdata1", Offset: 0, Payload: "0123", Checksum: crc32.ChecksumIEEE([]byte("0123"))},
+			{Type: 'P', Path: "ibdata1", Offset: 4, Payload: "4567", Checksum: crc32.ChecksumIEEE([]byte("4567"))},
+			{Type: 'P', Path: "ibdata1", Offset: 8, Payload: "89", Checksum: crc32.ChecksumIEEE([]byte("89"))},
+			{Type: 'E', Path: "ibdata1"},
+			{Type: 'E', Path: "mysql/empty.ibd"},
+		}))
+	})
+})
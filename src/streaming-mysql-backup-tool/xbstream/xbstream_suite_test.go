@@ -0,0 +1,13 @@
+package xbstream_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestXbstream(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Xbstream Suite")
+}
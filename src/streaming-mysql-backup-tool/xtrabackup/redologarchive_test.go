@@ -0,0 +1,94 @@
+package xtrabackup_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+const fakeXtrabackup = `#!/usr/bin/env bash
+echo >&2 "xtrabackup $*"
+if [[ -n "${FAKE_XTRABACKUP_REDO_LOG_ARCHIVE_FAILURE:-}" ]]; then
+  echo >&2 "2024-04-22T18:03:29.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Redo Log Archiving is not set up."
+fi
+echo >&2 "2024-04-22T18:03:35.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] completed OK!"
+`
+
+var _ = Describe("xtrabackup.Writer with RedoLogArchiveDir", func() {
+	var (
+		writer    xtrabackup.Writer
+		backupLog bytes.Buffer
+		metadata  map[string]string
+	)
+
+	BeforeEach(func() {
+		scriptsDir, err := filepath.Abs("../mysqlcli/scripts")
+		Expect(err).NotTo(HaveOccurred())
+		GinkgoT().Setenv("PATH", scriptsDir+":"+os.Getenv("PATH"))
+
+		binary := filepath.Join(GinkgoT().TempDir(), "xtrabackup")
+		Expect(os.WriteFile(binary, []byte(fakeXtrabackup), 0755)).To(Succeed())
+
+		backupLog.Reset()
+		metadata = map[string]string{}
+		writer = xtrabackup.Writer{
+			Binary:            binary,
+			DefaultsFile:      "/etc/mysql-8.0.cnf",
+			TmpDir:            "/tmp",
+			RedoLogArchiveDir: "backup:/var/vcap/store/redo-log-archive",
+			Logger:            lagertest.NewTestLogger("xtrabackup"),
+		}
+	})
+
+	streamTo := func() error {
+		return writer.StreamTo(context.Background(), api.BackupRequest{
+			ID:     "some-id",
+			Format: "xbstream",
+			Log:    &backupLog,
+			SetMetadata: func(key, value string) {
+				metadata[key] = value
+			},
+		}, io.Discard)
+	}
+
+	It("archives the redo log when the server can", func() {
+		Expect(streamTo()).To(Succeed())
+
+		Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=/etc/mysql-8.0.cnf --backup --stream=xbstream --target-dir=/tmp --redo-log-arch-dir=backup:/var/vcap/store/redo-log-archive\n"))
+		Expect(metadata).To(Equal(map[string]string{"redo_log_archiving": "true"}))
+	})
+
+	It("records that the redo log was not archived when xtrabackup fails to archive it", func() {
+		GinkgoT().Setenv("FAKE_XTRABACKUP_REDO_LOG_ARCHIVE_FAILURE", "true")
+
+		Expect(streamTo()).To(Succeed())
+
+		Expect(backupLog.String()).To(ContainSubstring("--redo-log-arch-dir=backup:/var/vcap/store/redo-log-archive"))
+		Expect(metadata).To(Equal(map[string]string{"redo_log_archiving": "false"}))
+	})
+
+	DescribeTable("falls back to backing up without archiving when the server cannot archive its redo log",
+		func(defaultsFile, archiveDirs string) {
+			GinkgoT().Setenv("FAKE_MYSQL_REDO_LOG_ARCHIVE_DIRS", archiveDirs)
+			writer.DefaultsFile = defaultsFile
+
+			Expect(streamTo()).To(Succeed())
+
+			Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=" + defaultsFile + " --backup --stream=xbstream --target-dir=/tmp\n"))
+			Expect(backupLog.String()).NotTo(ContainSubstring("--redo-log-arch-dir"))
+			Expect(metadata).To(Equal(map[string]string{"redo_log_archiving": "false"}))
+		},
+		Entry("for MySQL 5.7", "/etc/mysql-5.7.cnf", "backup:/var/vcap/store/redo-log-archive"),
+		Entry("when innodb_redo_log_archive_dirs is NULL", "/etc/mysql-8.0.cnf", "NULL"),
+		Entry("when innodb_redo_log_archive_dirs is empty", "/etc/mysql-8.0.cnf", ""),
+	)
+})
@@ -0,0 +1,88 @@
+package xtrabackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrStalled = errors.New("STALLED: no backup output from xtrabackup")
+
+var ErrOutputStalled = errors.New("STALLED: backup output not consumed")
+
+// The clock of the watchdog stops while the backup is paused.
+type watchdog struct {
+	w       io.Writer
+	timeout time.Duration
+
+	mu        sync.Mutex
+	lastWrite time.Time
+	writing   bool
+	paused    bool
+}
+
+func newWatchdog(w io.Writer, timeout time.Duration) *watchdog {
+	return &watchdog{w: w, timeout: timeout, lastWrite: time.Now()}
+}
+
+func (d *watchdog) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	d.lastWrite = time.Now()
+	d.writing = true
+	d.mu.Unlock()
+
+	n, err := d.w.Write(p)
+
+	d.mu.Lock()
+	d.lastWrite = time.Now()
+	d.writing = false
+	d.mu.Unlock()
+	return n, err
+}
+
+func (d *watchdog) pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = true
+}
+
+func (d *watchdog) resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = false
+	d.lastWrite = time.Now()
+}
+
+func (d *watchdog) stalled() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case d.paused || time.Since(d.lastWrite) < d.timeout:
+		return nil
+	case d.writing:
+		return ErrOutputStalled
+	default:
+		return ErrStalled
+	}
+}
+
+// run cancels the backup once it stalls, or returns when ctx is done.
+func (d *watchdog) run(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(d.timeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.stalled(); err != nil {
+				cancel(fmt.Errorf("%w for %s", err, d.timeout))
+				return
+			}
+		}
+	}
+}
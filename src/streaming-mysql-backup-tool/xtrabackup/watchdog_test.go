@@ -0,0 +1,78 @@
+package xtrabackup_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
+)
+
+// fakeStreamingXtrabackup writes backup output until it is killed.
+const fakeStreamingXtrabackup = `#!/usr/bin/env bash
+while true; do
+  head -c 65536 /dev/zero
+  sleep 0.1
+done
+`
+
+type slowWriter struct {
+	delay time.Duration
+	once  bool
+	wrote bool
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	if !w.once || !w.wrote {
+		time.Sleep(w.delay)
+	}
+	w.wrote = true
+	return len(p), nil
+}
+
+var _ = Describe("xtrabackup.Writer with a StallTimeout", func() {
+	var writer xtrabackup.Writer
+
+	BeforeEach(func() {
+		binary := filepath.Join(GinkgoT().TempDir(), "xtrabackup")
+		Expect(os.WriteFile(binary, []byte(fakeStreamingXtrabackup), 0755)).To(Succeed())
+
+		writer = xtrabackup.Writer{
+			Binary:       binary,
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       "/tmp",
+			StallTimeout: time.Second,
+			Logger:       lagertest.NewTestLogger("xtrabackup"),
+		}
+	})
+
+	It("reports a client that does not take the output as such, not as xtrabackup stalling", func() {
+		err := writer.StreamTo(context.Background(), api.BackupRequest{
+			ID:     "some-id",
+			Format: "xbstream",
+		}, &slowWriter{delay: 2 * time.Second, once: true})
+
+		Expect(err).To(MatchError(xtrabackup.ErrOutputStalled))
+		Expect(err).NotTo(MatchError(xtrabackup.ErrStalled))
+		Expect(err).To(MatchError(ContainSubstring("STALLED: backup output not consumed for 1s")))
+	})
+
+	It("does not stop xtrabackup while the output is consumed slowly but steadily", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		err := writer.StreamTo(ctx, api.BackupRequest{
+			ID:     "some-id",
+			Format: "xbstream",
+		}, &slowWriter{delay: 600 * time.Millisecond})
+
+		Expect(err).NotTo(MatchError(xtrabackup.ErrOutputStalled))
+		Expect(err).NotTo(MatchError(xtrabackup.ErrStalled))
+	})
+})
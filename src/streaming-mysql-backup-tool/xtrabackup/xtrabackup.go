@@ -1,35 +1,292 @@
 package xtrabackup
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3"
+	"github.com/cloudfoundry/xtrabackuplog"
 
 	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/redologarchive"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/xbstream"
 )
 
-type LoggerWriter struct {
-	logger lager.Logger
+type Writer struct {
+	Binary                 string
+	DefaultsFile           string
+	TmpDir                 string
+	StallTimeout           time.Duration
+	DiskGuard              *diskguard.Guard
+	SlaveInfo              bool
+	SafeSlaveBackup        bool
+	SafeSlaveBackupTimeout time.Duration
+	GaleraInfo             bool
+	// The TransitionKey is passed in an option file, never on the command line.
+	KeyringFileData        string
+	ComponentKeyringConfig string
+	TransitionKey          string
+	RedoLogArchiveDir      string
+	Logger                 lager.Logger
+}
+
+const redoLogArchivingKey = "redo_log_archiving"
+
+const waitDelay = 10 * time.Second
+
+func (x Writer) StreamTo(ctx context.Context, req api.BackupRequest, w io.Writer) error {
+	logger := x.Logger.WithData(lager.Data{"backup_id": req.ID})
+
+	parser := xtrabackuplog.NewParser(func(e xtrabackuplog.Event) {
+		e.LogTo(logger, x.binary())
+	})
+
+	var stderr io.Writer = parser
+	if req.Log != nil {
+		stderr = io.MultiWriter(parser, req.Log)
+	}
+
+	defaultsFile := x.DefaultsFile
+	if x.TransitionKey != "" {
+		file, err := x.transitionKeyDefaultsFile()
+		if err != nil {
+			logger.Error("writing the transition key option file failed", err)
+			return err
+		}
+		defer os.Remove(file)
+		defaultsFile = file
+	}
+
+	stream := "xbstream"
+	transcode := false
+	if req.Format == "tar" {
+		if x.streamsTar(ctx, logger) {
+			stream = "tar"
+		} else {
+			transcode = true
+		}
+	}
+
+	args := []string{"--defaults-file=" + defaultsFile, "--backup", "--stream=" + stream, "--target-dir=" + x.TmpDir}
+	args = append(args, x.replicationArgs()...)
+	args = append(args, x.keyringArgs()...)
+	if req.HistoryName != "" {
+		args = append(args, "--history="+req.HistoryName)
+	}
+	archiveRedoLog := false
+	if x.RedoLogArchiveDir != "" {
+		if err := redologarchive.Check(ctx, x.DefaultsFile); err != nil {
+			logger.Info("redo log archiving is not available, backing up without it", lager.Data{"error": err.Error()})
+			req.AddMetadata(redoLogArchivingKey, "false")
+		} else {
+			args = append(args, "--redo-log-arch-dir="+x.RedoLogArchiveDir)
+			archiveRedoLog = true
+		}
+	}
+
+	if x.DiskGuard != nil {
+		if err := x.DiskGuard.Check(); err != nil {
+			logger.Error("not enough free disk space to start xtrabackup", err)
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		pw         *io.PipeWriter
+		transcoded chan error
+	)
+	if transcode {
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		transcoded = make(chan error, 1)
+		go func(w io.Writer) {
+			err := xbstream.ToTar(pr, w, x.TmpDir)
+			if err != nil {
+				cancel(fmt.Errorf("transcoding the backup to tar failed: %w", err))
+			}
+			_ = pr.CloseWithError(err)
+			transcoded <- err
+		}(w)
+		w = pw
+	}
+
+	var dog *watchdog
+	if x.StallTimeout > 0 {
+		dog = newWatchdog(w, x.StallTimeout)
+		w = dog
+		go dog.run(ctx, cancel)
+	}
+
+	if x.DiskGuard != nil {
+		go x.DiskGuard.Watch(ctx, cancel)
+	}
+
+	cmd := exec.CommandContext(ctx, x.binary(), args...)
+	cmd.Stdout = w
+	cmd.Stderr = stderr
+	// Pausing or cancelling the group also reaches the processes xtrabackup spawns.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = waitDelay
+
+	if err := cmd.Start(); err != nil {
+		if transcoded != nil {
+			_ = pw.CloseWithError(err)
+			<-transcoded
+		}
+		return err
+	}
+	if req.Started != nil {
+		req.Started(processGroup{pid: cmd.Process.Pid, watchdog: dog})
+	}
+	err := cmd.Wait()
+	parser.Flush()
+
+	if archiveRedoLog {
+		req.AddMetadata(redoLogArchivingKey, strconv.FormatBool(!parser.Progress().RedoLogArchiveFailed))
+	}
+
+	if transcoded != nil {
+		_ = pw.CloseWithError(err)
+		if transcodeErr := <-transcoded; err == nil && transcodeErr != nil {
+			logger.Error("transcoding the backup to tar failed", transcodeErr)
+			return fmt.Errorf("FATAL: transcoding the backup to tar failed: %w", transcodeErr)
+		}
+	}
+
+	progress := parser.Progress()
+	logger.Info("xtrabackup finished", lager.Data{
+		"binary":       x.binary(),
+		"phase":        progress.Phase,
+		"files_copied": progress.FilesCopied,
+		"lsn":          progress.LSN,
+	})
+
+	if err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, ErrStalled) || errors.Is(cause, diskguard.ErrDiskFull) {
+			logger.Error("xtrabackup was stopped", cause)
+			return fmt.Errorf("%w (%v)", cause, err)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("xtrabackup was stopped: %w (%v)", context.Cause(ctx), err)
+		}
+		if failure := parser.Failure(); failure != nil {
+			return fmt.Errorf("%w (%v)", failure, err)
+		}
+		return err
+	}
+
+	return nil
 }
 
-func (lw *LoggerWriter) Write(p []byte) (int, error) {
-	lw.logger.Error("xtrabackup", errors.New(string(p[:])))
-	return len(p), nil
+func (x Writer) binary() string {
+	if x.Binary == "" {
+		return "xtrabackup"
+	}
+	return x.Binary
 }
 
-type Writer struct {
-	DefaultsFile string
-	TmpDir       string
-	Logger       lager.Logger
+var binaryVersion = regexp.MustCompile(`(?m)^xtrabackup version (\d+)\.`)
+
+// --stream=tar was removed in xtrabackup 8.0 and mariabackup never had it.
+func (x Writer) streamsTar(ctx context.Context, logger lager.Logger) bool {
+	out, err := exec.CommandContext(ctx, x.binary(), "--version").CombinedOutput()
+	if err != nil {
+		logger.Info("checking whether xtrabackup can stream tar failed, transcoding", lager.Data{"binary": x.binary(), "error": err.Error()})
+		return false
+	}
+
+	m := binaryVersion.FindSubmatch(out)
+	if m == nil {
+		return false
+	}
+	major, _ := strconv.Atoi(string(m[1]))
+	return major < 8
 }
 
-func (x Writer) StreamTo(format string, w io.Writer) error {
-	cmd := exec.Command("xtrabackup", "--defaults-file="+x.DefaultsFile, "--backup", "--stream="+format, "--target-dir="+x.TmpDir)
-	cmd.Stdout = w
-	cmd.Stderr = &LoggerWriter{logger: x.Logger}
-	return cmd.Run()
+func (x Writer) replicationArgs() []string {
+	var args []string
+	if x.SlaveInfo {
+		args = append(args, "--slave-info")
+	}
+	if x.SafeSlaveBackup {
+		args = append(args, "--safe-slave-backup")
+		if x.SafeSlaveBackupTimeout > 0 {
+			args = append(args, "--safe-slave-backup-timeout="+strconv.Itoa(int(x.SafeSlaveBackupTimeout/time.Second)))
+		}
+	}
+	if x.GaleraInfo {
+		args = append(args, "--galera-info")
+	}
+	return args
+}
+
+func (x Writer) keyringArgs() []string {
+	var args []string
+	if x.KeyringFileData != "" {
+		args = append(args, "--keyring-file-data="+x.KeyringFileData)
+	}
+	if x.ComponentKeyringConfig != "" {
+		args = append(args, "--component-keyring-config="+x.ComponentKeyringConfig)
+	}
+	return args
+}
+
+// The transition key would be readable by every user of the host on the command line.
+func (x Writer) transitionKeyDefaultsFile() (string, error) {
+	file, err := os.CreateTemp("", "xtrabackup-defaults-*.cnf")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(x.TransitionKey)
+	if _, err := fmt.Fprintf(file, "!include %s\n\n[xtrabackup]\ntransition-key=\"%s\"\n", x.DefaultsFile, quoted); err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+type processGroup struct {
+	pid      int
+	watchdog *watchdog
+}
+
+func (p processGroup) Pid() int {
+	return p.pid
+}
+
+func (p processGroup) Suspend() error {
+	if err := syscall.Kill(-p.pid, syscall.SIGSTOP); err != nil {
+		return err
+	}
+	if p.watchdog != nil {
+		p.watchdog.pause()
+	}
+	return nil
+}
+
+func (p processGroup) Resume() error {
+	if p.watchdog != nil {
+		p.watchdog.resume()
+	}
+	return syscall.Kill(-p.pid, syscall.SIGCONT)
 }
 
 var _ api.BackupWriter = &Writer{}
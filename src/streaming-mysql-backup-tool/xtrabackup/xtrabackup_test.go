@@ -1,9 +1,16 @@
 package xtrabackup_test
 
 import (
+	"archive/tar"
 	"bytes"
+	"context"
 	"database/sql"
 	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/lager/v3/lagertest"
 	. "github.com/onsi/ginkgo/v2"
@@ -11,6 +18,8 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	"github.com/ory/dockertest/v3"
 
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/api"
+	"github.com/cloudfoundry/streaming-mysql-backup-tool/diskguard"
 	"github.com/cloudfoundry/streaming-mysql-backup-tool/xtrabackup"
 )
 
@@ -46,14 +55,202 @@ var _ = Describe("xtrabackup.Writer", func() {
 	})
 
 	It("streams xtrabackup output in the desired format", func() {
-		var buf bytes.Buffer
+		var buf, backupLog bytes.Buffer
 		err := xtrabackup.Writer{
 			DefaultsFile: "/etc/my.cnf",
 			TmpDir:       "/tmp",
 			Logger:       testLogger,
-		}.StreamTo("xbstream", &buf)
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &backupLog}, &buf)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(testLogger.Buffer()).To(gbytes.Say(`(?s)"xtrabackup --defaults-file=/etc/my.cnf --backup --stream=xbstream --target-dir=/tmp\\n"`))
+		Expect(testLogger.Buffer()).To(gbytes.Say(`"line":"xtrabackup --defaults-file=/etc/my.cnf --backup --stream=xbstream --target-dir=/tmp"`))
+		Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=/etc/my.cnf --backup --stream=xbstream --target-dir=/tmp\n"))
+		Expect(backupLog.String()).To(ContainSubstring("completed OK!"))
+	})
+
+	It("transcodes the xbstream output of xtrabackup when tar is asked for", func() {
+		var buf, backupLog bytes.Buffer
+		err := xtrabackup.Writer{
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       "/tmp",
+			Logger:       testLogger,
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &backupLog}, &buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backupLog.String()).To(ContainSubstring("--stream=xbstream"))
+
+		var names []string
+		tr := tar.NewReader(&buf)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			names = append(names, header.Name)
+		}
+		Expect(names).To(ContainElements("ibdata1", "xtrabackup_checkpoints", "mysql.ibd"))
+	})
+
+	It("lets xtrabackup 2.4 stream tar itself", func() {
+		binary := filepath.Join(GinkgoT().TempDir(), "xtrabackup")
+		Expect(os.WriteFile(binary, []byte(`#!/usr/bin/env bash
+if [[ "$1" == --version ]]; then
+  echo >&2 "xtrabackup version 2.4.29 based on MySQL server 5.7.44 Linux (x86_64)"
+  exit 0
+fi
+echo >&2 "xtrabackup $*"
+echo -n some-tar-stream
+`), 0755)).To(Succeed())
+
+		var buf, backupLog bytes.Buffer
+		err := xtrabackup.Writer{
+			Binary:       binary,
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       "/tmp",
+			Logger:       testLogger,
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "tar", Log: &backupLog}, &buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=/etc/my.cnf --backup --stream=tar --target-dir=/tmp\n"))
+		Expect(buf.String()).To(Equal("some-tar-stream"))
+	})
+
+	It("records the backup in the xtrabackup history when a history name is given", func() {
+		var backupLog bytes.Buffer
+		err := xtrabackup.Writer{
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       "/tmp",
+			Logger:       testLogger,
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", HistoryName: "nightly", Log: &backupLog}, io.Discard)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=/etc/my.cnf --backup --stream=xbstream --target-dir=/tmp --history=nightly\n"))
+	})
+
+	It("stops xtrabackup when the context is cancelled", func() {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		err := xtrabackup.Writer{
+			DefaultsFile: "/etc/my.cnf",
+			TmpDir:       "/tmp",
+			Logger:       testLogger,
+		}.StreamTo(ctx, api.BackupRequest{
+			ID:     "some-id",
+			Format: "xbstream",
+			Started: func(p api.Process) {
+				Expect(p.Suspend()).To(Succeed())
+				Expect(p.Resume()).To(Succeed())
+				cancel(api.ErrCancelled)
+			},
+		}, io.Discard)
+		Expect(err).To(MatchError(api.ErrCancelled))
+	})
+
+	It("passes the replication options to xtrabackup", func() {
+		var backupLog bytes.Buffer
+		_ = xtrabackup.Writer{
+			DefaultsFile:           "/etc/my.cnf",
+			TmpDir:                 "/tmp",
+			SlaveInfo:              true,
+			SafeSlaveBackup:        true,
+			SafeSlaveBackupTimeout: 2 * time.Minute,
+			GaleraInfo:             true,
+			Logger:                 testLogger,
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &backupLog}, io.Discard)
+		Expect(backupLog.String()).To(HavePrefix("xtrabackup --defaults-file=/etc/my.cnf --backup --stream=xbstream --target-dir=/tmp --slave-info --safe-slave-backup --safe-slave-backup-timeout=120 --galera-info\n"))
+	})
+
+	It("passes the keyring options to xtrabackup", func() {
+		var backupLog bytes.Buffer
+		_ = xtrabackup.Writer{
+			DefaultsFile:           "/etc/my.cnf",
+			TmpDir:                 "/tmp",
+			ComponentKeyringConfig: "/etc/component_keyring_file.cnf",
+			TransitionKey:          "some-transition-key",
+			Logger:                 testLogger,
+		}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream", Log: &backupLog}, io.Discard)
+		Expect(backupLog.String()).To(MatchRegexp(`^xtrabackup --defaults-file=\S+/xtrabackup-defaults-\d+\.cnf --backup --stream=xbstream --target-dir=/tmp --component-keyring-config=/etc/component_keyring_file.cnf\n`))
+		Expect(backupLog.String()).NotTo(ContainSubstring("some-transition-key"))
+	})
+
+	When("xtrabackup stops writing the backup", func() {
+		It("stops xtrabackup after the StallTimeout", func() {
+			err := xtrabackup.Writer{
+				DefaultsFile: "/etc/my.cnf",
+				TmpDir:       "/tmp",
+				StallTimeout: 2 * time.Second,
+				Logger:       testLogger,
+			}.StreamTo(context.Background(), api.BackupRequest{
+				ID:     "some-id",
+				Format: "xbstream",
+				Started: func(p api.Process) {
+					// Going through p would also pause the watchdog.
+					Expect(syscall.Kill(-p.(interface{ Pid() int }).Pid(), syscall.SIGSTOP)).To(Succeed())
+				},
+			}, io.Discard)
+			Expect(err).To(MatchError(xtrabackup.ErrStalled))
+			Expect(err).To(MatchError(ContainSubstring("STALLED: no backup output from xtrabackup for 2s")))
+		})
+
+		It("does not count the time the backup is paused", func() {
+			err := xtrabackup.Writer{
+				DefaultsFile: "/etc/my.cnf",
+				TmpDir:       "/tmp",
+				StallTimeout: 2 * time.Second,
+				Logger:       testLogger,
+			}.StreamTo(context.Background(), api.BackupRequest{
+				ID:     "some-id",
+				Format: "xbstream",
+				Started: func(p api.Process) {
+					Expect(p.Suspend()).To(Succeed())
+					time.Sleep(3 * time.Second)
+					Expect(p.Resume()).To(Succeed())
+				},
+			}, io.Discard)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("the disk under TmpDir is running out of space", func() {
+		var usage atomic.Uint64
+
+		newWriter := func() xtrabackup.Writer {
+			return xtrabackup.Writer{
+				DefaultsFile: "/etc/my.cnf",
+				TmpDir:       "/tmp",
+				DiskGuard: &diskguard.Guard{
+					Path:          "/tmp",
+					MinFreeBytes:  1000,
+					CheckInterval: 10 * time.Millisecond,
+					Logger:        testLogger,
+					StatFS: func(string) (diskguard.Usage, error) {
+						return diskguard.Usage{Free: usage.Load(), Total: 10000}, nil
+					},
+				},
+				Logger: testLogger,
+			}
+		}
+
+		It("refuses to start xtrabackup", func() {
+			usage.Store(500)
+			var buf bytes.Buffer
+
+			err := newWriter().StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "xbstream"}, &buf)
+			Expect(err).To(MatchError(diskguard.ErrDiskFull))
+			Expect(buf.Len()).To(BeZero())
+		})
+
+		It("stops xtrabackup before the floor is crossed", func() {
+			usage.Store(5000)
+
+			err := newWriter().StreamTo(context.Background(), api.BackupRequest{
+				ID:     "some-id",
+				Format: "xbstream",
+				Started: func(api.Process) {
+					usage.Store(900)
+				},
+			}, io.Discard)
+			Expect(err).To(MatchError(diskguard.ErrDiskFull))
+			Expect(err).To(MatchError(ContainSubstring("DISK_FULL: 900 bytes free on /tmp, below the floor of 1000 bytes")))
+		})
 	})
 
 	When("specifying an invalid stream format", func() {
@@ -62,8 +259,8 @@ var _ = Describe("xtrabackup.Writer", func() {
 				DefaultsFile: "/etc/my.cnf",
 				TmpDir:       "/tmp",
 				Logger:       testLogger,
-			}.StreamTo("invalid", io.Discard)
-			Expect(err).To(HaveOccurred())
+			}.StreamTo(context.Background(), api.BackupRequest{ID: "some-id", Format: "invalid"}, io.Discard)
+			Expect(err).To(MatchError(ContainSubstring("FATAL: Invalid --stream argument: invalid")))
 			Expect(testLogger.Buffer()).To(gbytes.Say(`\[Xtrabackup\] Invalid --stream argument: invalid`))
 		})
 	})
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+const (
+	ExporterNone = ""
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+type Config struct {
+	Exporter string `yaml:"Exporter"`
+	Endpoint string `yaml:"Endpoint"`
+	File     string `yaml:"File"`
+}
+
+func (c Config) Enabled() bool {
+	return c.Exporter != ExporterNone
+}
+
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterOTLP:
+	case ExporterFile:
+		if c.File == "" {
+			return fmt.Errorf("Tracing.File must be set to export spans to a file")
+		}
+	default:
+		return fmt.Errorf("invalid Tracing.Exporter '%s', must be '%s', '%s' or empty", c.Exporter, ExporterOTLP, ExporterFile)
+	}
+	return nil
+}
+
+// NewTracer returns a Tracer that records nothing without an exporter.
+func (c Config) NewTracer(service string, logger lager.Logger) (*Tracer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	tracer := &Tracer{Service: service}
+	switch c.Exporter {
+	case ExporterOTLP:
+		endpoint := c.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultEndpoint
+		}
+		tracer.Exporter = NewOTLPExporter(endpoint, logger)
+	case ExporterFile:
+		exporter, err := NewFileExporter(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("opening Tracing.File failed: %w", err)
+		}
+		tracer.Exporter = exporter
+	}
+	return tracer, nil
+}
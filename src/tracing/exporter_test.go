@@ -0,0 +1,171 @@
+package tracing_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/tracing"
+)
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []struct {
+				Key   string `json:"key"`
+				Value struct {
+					StringValue string `json:"stringValue"`
+				} `json:"value"`
+			} `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []struct {
+				TraceID      string `json:"traceId"`
+				SpanID       string `json:"spanId"`
+				ParentSpanID string `json:"parentSpanId"`
+				Name         string `json:"name"`
+				Kind         int    `json:"kind"`
+				Attributes   []struct {
+					Key   string         `json:"key"`
+					Value map[string]any `json:"value"`
+				} `json:"attributes"`
+				Status struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+var _ = Describe("OTLPExporter", func() {
+	var (
+		collector *httptest.Server
+		requests  chan exportRequest
+		status    int
+		logger    *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		requests = make(chan exportRequest, 10)
+		status = http.StatusOK
+		logger = lagertest.NewTestLogger("tracing")
+
+		collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/v1/traces"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			var request exportRequest
+			Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+			requests <- request
+			w.WriteHeader(status)
+		}))
+		DeferCleanup(collector.Close)
+	})
+
+	It("sends the spans queued when shut down", func() {
+		tracer := &tracing.Tracer{Service: "streaming-mysql-backup-tool", Exporter: tracing.NewOTLPExporter(collector.URL+"/v1/traces", logger)}
+
+		ctx, parent := tracer.Start(context.Background(), "backup")
+		parent.SetKind(tracing.KindServer)
+		_, child := tracer.Start(ctx, "xtrabackup")
+		child.SetAttribute("backup.bytes", int64(1024))
+		child.SetError(errors.New("xtrabackup failed"))
+		child.End()
+		parent.End()
+
+		Expect(tracer.Shutdown(context.Background())).To(Succeed())
+
+		var request exportRequest
+		Eventually(requests).Should(Receive(&request))
+		Expect(request.ResourceSpans).To(HaveLen(1))
+		Expect(request.ResourceSpans[0].Resource.Attributes[0].Key).To(Equal("service.name"))
+		Expect(request.ResourceSpans[0].Resource.Attributes[0].Value.StringValue).To(Equal("streaming-mysql-backup-tool"))
+
+		spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("xtrabackup"))
+		Expect(spans[0].Kind).To(Equal(1))
+		Expect(spans[0].TraceID).To(Equal(parent.SpanContext().TraceID.String()))
+		Expect(spans[0].ParentSpanID).To(Equal(parent.SpanContext().SpanID.String()))
+		Expect(spans[0].Attributes[0].Key).To(Equal("backup.bytes"))
+		Expect(spans[0].Attributes[0].Value).To(Equal(map[string]any{"intValue": "1024"}))
+		Expect(spans[0].Status.Code).To(Equal(2))
+		Expect(spans[0].Status.Message).To(Equal("xtrabackup failed"))
+		Expect(spans[1].Name).To(Equal("backup"))
+		Expect(spans[1].Kind).To(Equal(2))
+		Expect(spans[1].ParentSpanID).To(BeEmpty())
+	})
+
+	It("reports spans the collector refused", func() {
+		status = http.StatusServiceUnavailable
+		tracer := &tracing.Tracer{Service: "streaming-mysql-backup-client", Exporter: tracing.NewOTLPExporter(collector.URL+"/v1/traces", logger)}
+
+		_, span := tracer.Start(context.Background(), "backup")
+		span.End()
+
+		Expect(tracer.Shutdown(context.Background())).To(MatchError(ContainSubstring("collector answered 503 Service Unavailable, dropped 1 spans")))
+	})
+})
+
+var _ = Describe("FileExporter", func() {
+	It("appends a line per span", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "spans.json")
+		exporter, err := tracing.NewFileExporter(path)
+		Expect(err).NotTo(HaveOccurred())
+		tracer := &tracing.Tracer{Service: "streaming-mysql-backup-client", Exporter: exporter}
+
+		ctx, parent := tracer.Start(context.Background(), "backup")
+		_, child := tracer.Start(ctx, "prepare")
+		child.End()
+		parent.End()
+		Expect(tracer.Shutdown(context.Background())).To(Succeed())
+
+		file, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		var names []string
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var request exportRequest
+			Expect(json.Unmarshal(scanner.Bytes(), &request)).To(Succeed())
+			names = append(names, request.ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+		}
+		Expect(names).To(Equal([]string{"prepare", "backup"}))
+	})
+})
+
+var _ = Describe("Config", func() {
+	It("records nothing by default", func() {
+		tracer, err := tracing.Config{}.NewTracer("streaming-mysql-backup-tool", lagertest.NewTestLogger("tracing"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracer.Exporter).To(BeNil())
+	})
+
+	It("exports to a collector on the same VM", func() {
+		tracer, err := tracing.Config{Exporter: "otlp"}.NewTracer("streaming-mysql-backup-tool", lagertest.NewTestLogger("tracing"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracer.Exporter).To(BeAssignableToTypeOf(&tracing.OTLPExporter{}))
+		Expect(tracer.Shutdown(context.Background())).To(Succeed())
+	})
+
+	It("requires a file for the file exporter", func() {
+		Expect(tracing.Config{Exporter: "file"}.Validate()).To(MatchError("Tracing.File must be set to export spans to a file"))
+	})
+
+	It("rejects unknown exporters", func() {
+		Expect(tracing.Config{Exporter: "zipkin"}.Validate()).To(MatchError("invalid Tracing.Exporter 'zipkin', must be 'otlp', 'file' or empty"))
+	})
+})
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileExporter writes the format the otlpjsonfile receiver of the collector reads.
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+// NewFileExporter opens path for appending, creating it if need be.
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: file}, nil
+}
+
+func (e *FileExporter) Export(span SpanData) {
+	line, err := json.Marshal(newExportRequest([]SpanData{span}))
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return
+	}
+	if _, err := e.file.Write(append(line, '\n')); err != nil && e.err == nil {
+		e.err = err
+	}
+}
+
+// Shutdown closes the file, returning the first error writing to it.
+func (e *FileExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.file == nil {
+		return e.err
+	}
+	if err := e.file.Close(); err != nil && e.err == nil {
+		e.err = err
+	}
+	e.file = nil
+	return e.err
+}
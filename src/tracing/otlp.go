@@ -0,0 +1,272 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+// DefaultEndpoint is where a collector on the same VM receives OTLP/HTTP.
+const DefaultEndpoint = "http://127.0.0.1:4318/v1/traces"
+
+const (
+	defaultBatchSize     = 256
+	defaultFlushInterval = 5 * time.Second
+	exportTimeout        = 10 * time.Second
+	maxQueuedSpans       = 8192
+)
+
+// OTLPExporter drops spans it fails to send: tracing never fails a backup.
+type OTLPExporter struct {
+	endpoint string
+	client   *http.Client
+	logger   lager.Logger
+
+	mu      sync.Mutex
+	queue   []SpanData
+	flush   chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewOTLPExporter starts exporting to endpoint, e.g. DefaultEndpoint.
+func NewOTLPExporter(endpoint string, logger lager.Logger) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: exportTimeout},
+		logger:   logger,
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go e.run(defaultFlushInterval)
+	return e
+}
+
+func (e *OTLPExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) >= maxQueuedSpans {
+		e.logger.Debug("dropping span, export queue is full", lager.Data{"span": span.Name})
+		return
+	}
+	e.queue = append(e.queue, span)
+	if len(e.queue) >= defaultBatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Shutdown sends the queued spans and stops exporting.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() { close(e.stop) })
+
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return e.send(ctx, e.take())
+}
+
+func (e *OTLPExporter) run(interval time.Duration) {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		if err := e.send(ctx, e.take()); err != nil {
+			e.logger.Error("exporting spans failed", err, lager.Data{"endpoint": e.endpoint})
+		}
+		cancel()
+	}
+}
+
+func (e *OTLPExporter) take() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	spans := e.queue
+	e.queue = nil
+	return spans
+}
+
+func (e *OTLPExporter) send(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector answered %s, dropped %d spans", resp.Status, len(spans))
+	}
+	return nil
+}
+
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	TraceState        string     `json:"traceState,omitempty"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const statusCodeError = 2
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+const scopeName = "github.com/cloudfoundry/tracing"
+
+func newExportRequest(spans []SpanData) exportRequest {
+	var request exportRequest
+	byService := map[string]int{}
+
+	for _, span := range spans {
+		i, ok := byService[span.Service]
+		if !ok {
+			i = len(request.ResourceSpans)
+			byService[span.Service] = i
+			request.ResourceSpans = append(request.ResourceSpans, resourceSpans{
+				Resource:   resource{Attributes: attributes(map[string]any{"service.name": span.Service})},
+				ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}}},
+			})
+		}
+		scope := &request.ResourceSpans[i].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, newOTLPSpan(span))
+	}
+	return request
+}
+
+func newOTLPSpan(span SpanData) otlpSpan {
+	s := otlpSpan{
+		TraceID:           span.SpanContext.TraceID.String(),
+		SpanID:            span.SpanContext.SpanID.String(),
+		TraceState:        span.SpanContext.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Attributes:        attributes(span.Attributes),
+	}
+	if span.Parent.IsValid() {
+		s.ParentSpanID = span.Parent.String()
+	}
+	if span.Error != "" {
+		s.Status = status{Code: statusCodeError, Message: span.Error}
+	}
+	return s
+}
+
+func attributes(values map[string]any) []keyValue {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var kvs []keyValue
+	for _, key := range keys {
+		var v anyValue
+		switch value := values[key].(type) {
+		case string:
+			v.StringValue = &value
+		case bool:
+			v.BoolValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case uint64:
+			s := strconv.FormatUint(value, 10)
+			v.IntValue = &s
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		kvs = append(kvs, keyValue{Key: key, Value: v})
+	}
+	return kvs
+}
@@ -0,0 +1,134 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/tracing"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *recorder) Export(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *recorder) Shutdown(context.Context) error { return nil }
+
+var _ = Describe("Tracer", func() {
+	var (
+		exporter *recorder
+		tracer   *tracing.Tracer
+	)
+
+	BeforeEach(func() {
+		exporter = &recorder{}
+		tracer = &tracing.Tracer{Service: "backup-client", Exporter: exporter}
+	})
+
+	It("exports spans once they end", func() {
+		_, span := tracer.Start(context.Background(), "prepare")
+		span.SetAttribute("backup.id", "some-backup-id")
+		Expect(exporter.spans).To(BeEmpty())
+
+		span.End()
+		span.End()
+
+		Expect(exporter.spans).To(HaveLen(1))
+		Expect(exporter.spans[0].Service).To(Equal("backup-client"))
+		Expect(exporter.spans[0].Name).To(Equal("prepare"))
+		Expect(exporter.spans[0].Kind).To(Equal(tracing.KindInternal))
+		Expect(exporter.spans[0].Attributes).To(HaveKeyWithValue("backup.id", "some-backup-id"))
+		Expect(exporter.spans[0].End).NotTo(BeTemporally("<", exporter.spans[0].Start))
+	})
+
+	It("starts child spans in the trace of their parent", func() {
+		ctx, parent := tracer.Start(context.Background(), "backup")
+		_, child := tracer.Start(ctx, "download")
+		child.SetError(errors.New("some-error"))
+		child.End()
+		parent.End()
+
+		Expect(exporter.spans).To(HaveLen(2))
+		Expect(exporter.spans[0].SpanContext.TraceID).To(Equal(parent.SpanContext().TraceID))
+		Expect(exporter.spans[0].Parent).To(Equal(parent.SpanContext().SpanID))
+		Expect(exporter.spans[0].Error).To(Equal("some-error"))
+		Expect(exporter.spans[1].Parent.IsValid()).To(BeFalse())
+	})
+
+	It("records nothing when disabled", func() {
+		var disabled *tracing.Tracer
+		ctx, span := disabled.Start(context.Background(), "backup")
+		span.SetAttribute("key", "value")
+		span.SetError(errors.New("some-error"))
+		span.End()
+
+		Expect(span).To(BeNil())
+		Expect(tracing.SpanContextFromContext(ctx).IsValid()).To(BeFalse())
+		Expect(disabled.Shutdown(context.Background())).To(Succeed())
+	})
+
+	Describe("W3C trace context", func() {
+		const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		It("continues the trace of an incoming request", func() {
+			header := http.Header{}
+			header.Set("traceparent", traceparent)
+			header.Set("tracestate", "vendor=value")
+
+			_, span := tracer.Start(tracing.Extract(context.Background(), header), "backup")
+			span.End()
+
+			Expect(span.SpanContext().TraceID.String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+			Expect(exporter.spans[0].Parent.String()).To(Equal("00f067aa0ba902b7"))
+			Expect(exporter.spans[0].SpanContext.TraceState).To(Equal("vendor=value"))
+		})
+
+		It("adds the current span to outgoing requests", func() {
+			ctx, span := tracer.Start(context.Background(), "download")
+			header := http.Header{}
+			tracing.Inject(ctx, header)
+
+			Expect(header.Get("traceparent")).To(Equal("00-" + span.SpanContext().TraceID.String() + "-" + span.SpanContext().SpanID.String() + "-01"))
+		})
+
+		It("does not export traces the caller does not sample", func() {
+			header := http.Header{}
+			header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+			ctx, span := tracer.Start(tracing.Extract(context.Background(), header), "backup")
+			span.End()
+
+			Expect(exporter.spans).To(BeEmpty())
+			outgoing := http.Header{}
+			tracing.Inject(ctx, outgoing)
+			Expect(outgoing.Get("traceparent")).To(HaveSuffix("-00"))
+		})
+
+		DescribeTable("parsing traceparent headers",
+			func(value string, valid bool) {
+				_, ok := tracing.ParseTraceparent(value)
+				Expect(ok).To(Equal(valid))
+			},
+			Entry("version 00", traceparent, true),
+			Entry("a later version with more fields", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true),
+			Entry("version 00 with more fields", traceparent+"-extra", false),
+			Entry("version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false),
+			Entry("an all zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false),
+			Entry("an all zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false),
+			Entry("uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false),
+			Entry("a short trace id", "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false),
+			Entry("nothing", "", false),
+		)
+	})
+})
@@ -0,0 +1,113 @@
+package xtrabackuplog
+
+import "strings"
+
+const (
+	BinlogInfoFile  = "xtrabackup_binlog_info"
+	GaleraInfoFile  = "xtrabackup_galera_info"
+	CheckpointsFile = "xtrabackup_checkpoints"
+)
+
+type BackupInfo struct {
+	BinlogFile     string
+	BinlogPosition string
+	GTIDExecuted   string
+	GaleraUUID     string
+	GaleraSeqno    string
+	BackupType     string
+	FromLSN        string
+	ToLSN          string
+	LastLSN        string
+}
+
+func IsBackupInfoFile(name string) bool {
+	switch strings.TrimPrefix(name, "./") {
+	case BinlogInfoFile, GaleraInfoFile, CheckpointsFile:
+		return true
+	}
+	return false
+}
+
+func (i *BackupInfo) Parse(name string, content []byte) {
+	switch strings.TrimPrefix(name, "./") {
+	case BinlogInfoFile:
+		i.parseBinlogInfo(string(content))
+	case GaleraInfoFile:
+		i.parseGaleraInfo(string(content))
+	case CheckpointsFile:
+		i.parseCheckpoints(string(content))
+	}
+}
+
+// mysql-bin.000003	157	3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5
+func (i *BackupInfo) parseBinlogInfo(content string) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return
+	}
+	i.BinlogFile = fields[0]
+	i.BinlogPosition = fields[1]
+	i.GTIDExecuted = strings.Join(fields[2:], "")
+}
+
+// 8f3e6cba-6c79-11ee-8c99-0242ac120002:42
+func (i *BackupInfo) parseGaleraInfo(content string) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return
+	}
+	uuid, seqno, ok := strings.Cut(fields[0], ":")
+	if !ok {
+		return
+	}
+	i.GaleraUUID = uuid
+	i.GaleraSeqno = seqno
+}
+
+// to_lsn = 19006600
+func (i *BackupInfo) parseCheckpoints(content string) {
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "backup_type":
+			i.BackupType = value
+		case "from_lsn":
+			i.FromLSN = value
+		case "to_lsn":
+			i.ToLSN = value
+		case "last_lsn":
+			i.LastLSN = value
+		}
+	}
+}
+
+func (i BackupInfo) Metadata() map[string]string {
+	metadata := map[string]string{}
+	for key, value := range map[string]string{
+		"binlog_file":     i.BinlogFile,
+		"binlog_position": i.BinlogPosition,
+		"gtid_executed":   i.GTIDExecuted,
+		"galera_uuid":     i.GaleraUUID,
+		"galera_seqno":    i.GaleraSeqno,
+		"galera_position": galeraPosition(i.GaleraUUID, i.GaleraSeqno),
+		"from_lsn":        i.FromLSN,
+		"to_lsn":          i.ToLSN,
+		"last_lsn":        i.LastLSN,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+func galeraPosition(uuid, seqno string) string {
+	if uuid == "" {
+		return ""
+	}
+	return uuid + ":" + seqno
+}
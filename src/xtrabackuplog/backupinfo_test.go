@@ -0,0 +1,58 @@
+package xtrabackuplog_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/xtrabackuplog"
+)
+
+var _ = Describe("BackupInfo", func() {
+	It("parses where a MySQL backup was taken", func() {
+		var info xtrabackuplog.BackupInfo
+		info.Parse("xtrabackup_binlog_info", []byte("binlog.000003\t157\t3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,\n4ece5c21-71ca-11e1-9e33-c80aa9429562:1-3\n"))
+		info.Parse("./xtrabackup_galera_info", []byte("8f3e6cba-6c79-11ee-8c99-0242ac120002:42\n"))
+		info.Parse("xtrabackup_checkpoints", []byte("backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 19006600\nlast_lsn = 19006610\nflushed_lsn = 19006600\n"))
+		info.Parse("xtrabackup_info", []byte("uuid = some-uuid\n"))
+
+		Expect(info).To(Equal(xtrabackuplog.BackupInfo{
+			BinlogFile:     "binlog.000003",
+			BinlogPosition: "157",
+			GTIDExecuted:   "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4ece5c21-71ca-11e1-9e33-c80aa9429562:1-3",
+			GaleraUUID:     "8f3e6cba-6c79-11ee-8c99-0242ac120002",
+			GaleraSeqno:    "42",
+			BackupType:     "full-backuped",
+			FromLSN:        "0",
+			ToLSN:          "19006600",
+			LastLSN:        "19006610",
+		}))
+		Expect(info.Metadata()).To(Equal(map[string]string{
+			"binlog_file":     "binlog.000003",
+			"binlog_position": "157",
+			"gtid_executed":   "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4ece5c21-71ca-11e1-9e33-c80aa9429562:1-3",
+			"galera_uuid":     "8f3e6cba-6c79-11ee-8c99-0242ac120002",
+			"galera_seqno":    "42",
+			"galera_position": "8f3e6cba-6c79-11ee-8c99-0242ac120002:42",
+			"from_lsn":        "0",
+			"to_lsn":          "19006600",
+			"last_lsn":        "19006610",
+		}))
+	})
+
+	It("parses a MariaDB binlog position without GTIDs", func() {
+		var info xtrabackuplog.BackupInfo
+		info.Parse("xtrabackup_binlog_info", []byte("mysql-bin.000007\t1024\n"))
+
+		Expect(info.Metadata()).To(Equal(map[string]string{
+			"binlog_file":     "mysql-bin.000007",
+			"binlog_position": "1024",
+		}))
+	})
+
+	It("recognizes the files it is parsed from", func() {
+		Expect(xtrabackuplog.IsBackupInfoFile("xtrabackup_checkpoints")).To(BeTrue())
+		Expect(xtrabackuplog.IsBackupInfoFile("./xtrabackup_galera_info")).To(BeTrue())
+		Expect(xtrabackuplog.IsBackupInfoFile("mysql/xtrabackup_checkpoints")).To(BeFalse())
+		Expect(xtrabackuplog.IsBackupInfoFile("xtrabackup_info")).To(BeFalse())
+	})
+})
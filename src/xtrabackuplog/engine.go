@@ -0,0 +1,26 @@
+package xtrabackuplog
+
+import "regexp"
+
+// mariabackup based on MariaDB server 10.6.16-MariaDB Linux (x86_64)
+var engineBanner = regexp.MustCompile(`^(xtrabackup|mariabackup)(?: version (\S+))? based on (?:MySQL|MariaDB) server (\S+)`)
+
+// Engine is the tool that took the backup; mariabackup has the Version of its server.
+type Engine struct {
+	Name          string
+	Version       string
+	ServerVersion string
+}
+
+func parseEngine(message string) *Engine {
+	m := engineBanner.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+
+	e := &Engine{Name: m[1], Version: m[2], ServerVersion: m[3]}
+	if e.Version == "" {
+		e.Version = e.ServerVersion
+	}
+	return e
+}
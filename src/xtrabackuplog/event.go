@@ -0,0 +1,87 @@
+package xtrabackuplog
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/lager/v3"
+)
+
+type EventType string
+
+const (
+	Message               EventType = "message"
+	PhaseChange           EventType = "phase"
+	FileCopied            EventType = "file-copied"
+	LSNCheckpoint         EventType = "lsn-checkpoint"
+	RedoLogOverrun        EventType = "redo-log-overrun"
+	Fatal                 EventType = "fatal"
+	ReplicaCoordinates    EventType = "replica-coordinates"
+	EngineVersion         EventType = "engine-version"
+	RedoLogArchiveFailure EventType = "redo-log-archive-failure"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+type Phase string
+
+const (
+	PhaseConnecting       Phase = "connecting"
+	PhaseCopyingInnoDB    Phase = "copying-innodb"
+	PhaseLocking          Phase = "locking"
+	PhaseCopyingNonInnoDB Phase = "copying-non-innodb"
+	PhaseUnlocking        Phase = "unlocking"
+	PhasePreparing        Phase = "preparing"
+	PhaseCompleted        Phase = "completed"
+)
+
+type Event struct {
+	Type    EventType
+	Level   Level
+	Line    string
+	Message string
+	Phase   Phase
+	File    string
+	FromLSN uint64
+	LSN     uint64
+	Replica *ReplicaPosition
+	Engine  *Engine
+}
+
+func (e Event) LogTo(logger lager.Logger, action string) {
+	data := lager.Data{
+		"type": e.Type,
+		"line": e.Line,
+	}
+	if e.Phase != "" {
+		data["phase"] = e.Phase
+	}
+	if e.File != "" {
+		data["file"] = e.File
+	}
+	if e.LSN != 0 {
+		data["lsn"] = e.LSN
+	}
+	if e.FromLSN != 0 {
+		data["from_lsn"] = e.FromLSN
+	}
+
+	switch e.Level {
+	case LevelError:
+		delete(data, "line")
+		logger.Error(action, errors.New(e.Line), data)
+	case LevelWarn:
+		data["level"] = "warning"
+		logger.Info(action, data)
+	case LevelInfo:
+		logger.Info(action, data)
+	default:
+		logger.Debug(action, data)
+	}
+}
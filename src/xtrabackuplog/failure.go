@@ -0,0 +1,18 @@
+package xtrabackuplog
+
+type FailureKind string
+
+const (
+	RedoLogOverrunFailure FailureKind = "REDO_LOG_OVERRUN"
+	FatalFailure          FailureKind = "FATAL"
+)
+
+// Failure is the reason xtrabackup gave for a failed run.
+type Failure struct {
+	Kind    FailureKind
+	Message string
+}
+
+func (f *Failure) Error() string {
+	return string(f.Kind) + ": " + f.Message
+}
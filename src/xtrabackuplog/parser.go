@@ -0,0 +1,294 @@
+package xtrabackuplog
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// 8.0: 2024-04-22T18:03:29.123456-00:00 0 [Note] [MY-011825] [Xtrabackup] message
+	mysql8Header = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\S+\s+\d+\s+\[(\w+)\]\s+(?:\[MY-\d+\]\s+)?(?:\[\w+\]\s+)?(.*)$`)
+	// 2.4 and mariabackup: 240422 18:03:29 message
+	legacyHeader = regexp.MustCompile(`^\[?\d{6}\s+\d{1,2}:\d{2}:\d{2}\]?\s+(.*)$`)
+	workerPrefix = regexp.MustCompile(`^\[(\d+)\]\s+(.*)$`)
+
+	fileDone      = regexp.MustCompile(`^Done: (?:Streaming|Copying|Writing file) (.+?)(?: to <STDOUT>)?$`)
+	fileStart     = regexp.MustCompile(`^(?:Streaming|Copying|Writing) (.+?)(?: to <STDOUT>)?$`)
+	logScanned    = regexp.MustCompile(`>> log scanned up to \((\d+)\)`)
+	logCopied     = regexp.MustCompile(`Transaction log of lsn \((\d+)\) to \((\d+)\) was copied`)
+	redoOverruns  = []string{"log block numbers mismatch", "log has wrapped around", "Was only able to copy log from"}
+	phasePrefixes = []struct {
+		prefix string
+		phase  Phase
+	}{
+		{"Connecting to MySQL server", PhaseConnecting},
+		{"Generating a list of tablespaces", PhaseCopyingInnoDB},
+		{"Executing FLUSH TABLES WITH READ LOCK", PhaseLocking},
+		{"Executing LOCK TABLES FOR BACKUP", PhaseLocking},
+		{"Executing LOCK INSTANCE FOR BACKUP", PhaseLocking},
+		{"Starting to backup non-InnoDB tables and files", PhaseCopyingNonInnoDB},
+		{"Executing UNLOCK TABLES", PhaseUnlocking},
+		{"Starting InnoDB instance for recovery", PhasePreparing},
+		{"completed OK!", PhaseCompleted},
+	}
+)
+
+// Progress summarises the events seen so far in a run.
+type Progress struct {
+	Phase                Phase
+	FilesCopied          int
+	LastFile             string
+	FromLSN              uint64
+	LSN                  uint64
+	Replica              *ReplicaPosition
+	Engine               *Engine
+	RedoLogArchiveFailed bool
+}
+
+type Parser struct {
+	handler func(Event)
+
+	mu       sync.Mutex
+	partial  []byte
+	workers  map[string]string
+	progress Progress
+	failure  *Failure
+}
+
+func NewParser(handler func(Event)) *Parser {
+	if handler == nil {
+		handler = func(Event) {}
+	}
+
+	return &Parser{
+		handler: handler,
+		workers: map[string]string{},
+	}
+}
+
+func (p *Parser) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	p.partial = append(p.partial, b...)
+
+	var events []Event
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(p.partial[:i]), "\r")
+		p.partial = p.partial[i+1:]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		events = append(events, p.parse(line))
+	}
+	p.mu.Unlock()
+
+	for _, e := range events {
+		p.handler(e)
+	}
+
+	return len(b), nil
+}
+
+// Flush should be called once the process producing the output exits.
+func (p *Parser) Flush() {
+	p.mu.Lock()
+	line := strings.TrimSpace(string(p.partial))
+	p.partial = nil
+	if line == "" {
+		p.mu.Unlock()
+		return
+	}
+	e := p.parse(line)
+	p.mu.Unlock()
+
+	p.handler(e)
+}
+
+func (p *Parser) Progress() Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.progress
+}
+
+func (p *Parser) Failure() *Failure {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.failure
+}
+
+func (p *Parser) parse(line string) Event {
+	e := ParseLine(line)
+
+	if e.Type == Message {
+		e = p.trackWorker(e)
+	}
+
+	switch e.Type {
+	case PhaseChange:
+		p.progress.Phase = e.Phase
+	case FileCopied:
+		p.progress.FilesCopied++
+		p.progress.LastFile = e.File
+	case LSNCheckpoint:
+		if e.FromLSN != 0 {
+			p.progress.FromLSN = e.FromLSN
+		}
+		p.progress.LSN = e.LSN
+	case ReplicaCoordinates:
+		p.progress.Replica = e.Replica
+	case EngineVersion:
+		p.progress.Engine = e.Engine
+	case RedoLogArchiveFailure:
+		p.progress.RedoLogArchiveFailed = true
+	case RedoLogOverrun:
+		if p.failure == nil || p.failure.Kind != RedoLogOverrunFailure {
+			p.failure = &Failure{Kind: RedoLogOverrunFailure, Message: e.Message}
+		}
+	case Fatal:
+		if p.failure == nil {
+			p.failure = &Failure{Kind: FatalFailure, Message: e.Message}
+		}
+	}
+
+	return e
+}
+
+// xtrabackup 2.4 reports a finished copy as "[01]        ...done".
+func (p *Parser) trackWorker(e Event) Event {
+	m := workerPrefix.FindStringSubmatch(e.Message)
+	if m == nil {
+		return e
+	}
+
+	worker, rest := m[1], m[2]
+	if rest == "...done" {
+		if file, ok := p.workers[worker]; ok {
+			delete(p.workers, worker)
+			e.Type = FileCopied
+			e.Level = LevelDebug
+			e.File = file
+		}
+		return e
+	}
+
+	if f := fileStart.FindStringSubmatch(rest); f != nil {
+		p.workers[worker] = f[1]
+		e.Level = LevelDebug
+	}
+
+	return e
+}
+
+func ParseLine(line string) Event {
+	severity, message := splitHeader(line)
+
+	e := Event{
+		Type:    Message,
+		Level:   LevelDebug,
+		Line:    line,
+		Message: message,
+	}
+
+	for _, s := range redoOverruns {
+		if strings.Contains(message, s) {
+			e.Type = RedoLogOverrun
+			e.Level = LevelError
+			return e
+		}
+	}
+
+	if severity == "ERROR" || isErrorMessage(message) {
+		e.Type = Fatal
+		e.Level = LevelError
+		return e
+	}
+
+	if isRedoLogArchiveFailure(message) {
+		e.Type = RedoLogArchiveFailure
+		e.Level = LevelWarn
+		return e
+	}
+
+	if replica := parseReplicaPosition(message); replica != nil {
+		e.Type = ReplicaCoordinates
+		e.Level = LevelInfo
+		e.Replica = replica
+		return e
+	}
+
+	if engine := parseEngine(message); engine != nil {
+		e.Type = EngineVersion
+		e.Level = LevelInfo
+		e.Engine = engine
+		return e
+	}
+
+	if m := logScanned.FindStringSubmatch(message); m != nil {
+		e.Type = LSNCheckpoint
+		e.LSN, _ = strconv.ParseUint(m[1], 10, 64)
+		return e
+	}
+
+	if m := logCopied.FindStringSubmatch(message); m != nil {
+		e.Type = LSNCheckpoint
+		e.Level = LevelInfo
+		e.FromLSN, _ = strconv.ParseUint(m[1], 10, 64)
+		e.LSN, _ = strconv.ParseUint(m[2], 10, 64)
+		return e
+	}
+
+	if m := fileDone.FindStringSubmatch(message); m != nil {
+		e.Type = FileCopied
+		e.File = m[1]
+		return e
+	}
+
+	for _, p := range phasePrefixes {
+		if strings.HasPrefix(message, p.prefix) {
+			e.Type = PhaseChange
+			e.Level = LevelInfo
+			e.Phase = p.phase
+			return e
+		}
+	}
+
+	if severity == "Warning" || strings.HasPrefix(strings.ToLower(message), "warning") {
+		e.Level = LevelWarn
+	}
+
+	return e
+}
+
+func splitHeader(line string) (severity, message string) {
+	message = strings.TrimSpace(line)
+
+	if m := mysql8Header.FindStringSubmatch(message); m != nil {
+		severity, message = m[1], m[2]
+	} else if m := legacyHeader.FindStringSubmatch(message); m != nil {
+		message = m[1]
+	}
+
+	for _, prefix := range []string{"xtrabackup: ", "mariabackup: ", "InnoDB: "} {
+		message = strings.TrimPrefix(message, prefix)
+	}
+
+	return severity, strings.TrimSpace(message)
+}
+
+func isErrorMessage(message string) bool {
+	lower := strings.ToLower(message)
+
+	return strings.HasPrefix(lower, "error:") ||
+		strings.HasPrefix(lower, "error ") ||
+		strings.HasPrefix(lower, "fatal") ||
+		strings.HasPrefix(message, "xbstream: Can't") ||
+		strings.HasPrefix(lower, "xbstream: error")
+}
@@ -0,0 +1,255 @@
+package xtrabackuplog_test
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/cloudfoundry/xtrabackuplog"
+)
+
+var _ = Describe("ParseLine", func() {
+	DescribeTable("classifies xtrabackup 8.0 output",
+		func(line string, eventType xtrabackuplog.EventType, level xtrabackuplog.Level) {
+			e := xtrabackuplog.ParseLine(line)
+			Expect(e.Type).To(Equal(eventType))
+			Expect(e.Level).To(Equal(level))
+			Expect(e.Line).To(Equal(line))
+		},
+		Entry("routine notes",
+			`2024-04-22T18:03:29.512931-00:00 0 [Note] [MY-011825] [Xtrabackup] uses posix_fadvise().`,
+			xtrabackuplog.Message, xtrabackuplog.LevelDebug),
+		Entry("warnings",
+			`2024-04-22T18:03:29.512931-00:00 0 [Warning] [MY-011825] [Xtrabackup] Please set parameter 'datadir'`,
+			xtrabackuplog.Message, xtrabackuplog.LevelWarn),
+		Entry("phase changes",
+			`2024-04-22T18:03:30.119876-00:00 0 [Note] [MY-011825] [Xtrabackup] Executing LOCK INSTANCE FOR BACKUP ...`,
+			xtrabackuplog.PhaseChange, xtrabackuplog.LevelInfo),
+		Entry("copied files",
+			`2024-04-22T18:03:30.119876-00:00 1 [Note] [MY-011825] [Xtrabackup] Done: Streaming ./ibdata1`,
+			xtrabackuplog.FileCopied, xtrabackuplog.LevelDebug),
+		Entry("scanned lsns",
+			`2024-04-22T18:03:30.119876-00:00 0 [Note] [MY-011825] [Xtrabackup] >> log scanned up to (19006600)`,
+			xtrabackuplog.LSNCheckpoint, xtrabackuplog.LevelDebug),
+		Entry("redo log overruns",
+			`2024-04-22T18:03:30.119876-00:00 0 [ERROR] [MY-011825] [Xtrabackup] log block numbers mismatch:`,
+			xtrabackuplog.RedoLogOverrun, xtrabackuplog.LevelError),
+		Entry("errors",
+			`2024-04-22T18:03:30.119876-00:00 0 [ERROR] [MY-011825] [Xtrabackup] Invalid --stream argument: invalid`,
+			xtrabackuplog.Fatal, xtrabackuplog.LevelError),
+	)
+
+	DescribeTable("classifies xtrabackup 2.4 output",
+		func(line string, eventType xtrabackuplog.EventType, level xtrabackuplog.Level) {
+			e := xtrabackuplog.ParseLine(line)
+			Expect(e.Type).To(Equal(eventType))
+			Expect(e.Level).To(Equal(level))
+		},
+		Entry("routine notes", `240422 18:03:29 Connecting to MySQL server host: localhost, user: root`,
+			xtrabackuplog.PhaseChange, xtrabackuplog.LevelInfo),
+		Entry("completion", `240422 18:03:35 completed OK!`,
+			xtrabackuplog.PhaseChange, xtrabackuplog.LevelInfo),
+		Entry("copied redo log", `xtrabackup: Transaction log of lsn (2638457) to (2638466) was copied.`,
+			xtrabackuplog.LSNCheckpoint, xtrabackuplog.LevelInfo),
+		Entry("redo log overruns", `xtrabackup: error: it looks like InnoDB log has wrapped around before xtrabackup could process all records`,
+			xtrabackuplog.RedoLogOverrun, xtrabackuplog.LevelError),
+		Entry("errors", `xtrabackup: error: failed to connect to MySQL server`,
+			xtrabackuplog.Fatal, xtrabackuplog.LevelError),
+		Entry("xbstream errors", `xbstream: Can't change dir to '/some/fake/directory' (OS errno 2 - No such file or directory)`,
+			xtrabackuplog.Fatal, xtrabackuplog.LevelError),
+	)
+
+	It("extracts the lsn range of the copied redo log", func() {
+		e := xtrabackuplog.ParseLine(`2024-04-22T18:03:31.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Transaction log of lsn (19006580) to (19006600) was copied.`)
+		Expect(e.FromLSN).To(Equal(uint64(19006580)))
+		Expect(e.LSN).To(Equal(uint64(19006600)))
+	})
+
+	DescribeTable("extracts the replica position reported by --slave-info",
+		func(line string, expected xtrabackuplog.ReplicaPosition) {
+			e := xtrabackuplog.ParseLine(line)
+			Expect(e.Type).To(Equal(xtrabackuplog.ReplicaCoordinates))
+			Expect(e.Level).To(Equal(xtrabackuplog.LevelInfo))
+			Expect(e.Replica).To(PointTo(Equal(expected)))
+		},
+		Entry("8.0 with binlog coordinates",
+			`2024-04-22T18:03:34.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] MySQL slave binlog position: master host '10.0.0.1', filename 'mysql-bin.000002', position '157', channel name: ''`,
+			xtrabackuplog.ReplicaPosition{SourceHost: "10.0.0.1", BinlogFile: "mysql-bin.000002", BinlogPosition: "157"}),
+		Entry("8.0 with GTIDs",
+			`2024-04-22T18:03:34.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] MySQL slave binlog position: master host '10.0.0.1', purge list '3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5', channel name: 'source-1'`,
+			xtrabackuplog.ReplicaPosition{SourceHost: "10.0.0.1", GTIDSet: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5", Channel: "source-1"}),
+		Entry("2.4",
+			`240422 18:03:34 MySQL slave binlog position: master host '10.0.0.1', filename 'mysql-bin.000002', position '154'`,
+			xtrabackuplog.ReplicaPosition{SourceHost: "10.0.0.1", BinlogFile: "mysql-bin.000002", BinlogPosition: "154"}),
+		Entry("mariabackup",
+			`[00] 2024-04-22 18:03:34 MySQL slave binlog position: master host '10.0.0.1', gtid_slave_pos 0-1-100`,
+			xtrabackuplog.ReplicaPosition{SourceHost: "10.0.0.1", GTIDSet: "0-1-100"}),
+	)
+
+	DescribeTable("extracts the engine that is taking the backup",
+		func(line string, expected xtrabackuplog.Engine) {
+			e := xtrabackuplog.ParseLine(line)
+			Expect(e.Type).To(Equal(xtrabackuplog.EngineVersion))
+			Expect(e.Level).To(Equal(xtrabackuplog.LevelInfo))
+			Expect(e.Engine).To(PointTo(Equal(expected)))
+		},
+		Entry("xtrabackup 8.0",
+			`2024-04-22T18:03:29.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] xtrabackup version 8.0.35-30 based on MySQL server 8.0.35 Linux (x86_64) (revision id: 6beb4b49)`,
+			xtrabackuplog.Engine{Name: "xtrabackup", Version: "8.0.35-30", ServerVersion: "8.0.35"}),
+		Entry("xtrabackup 2.4",
+			`xtrabackup version 2.4.29 based on MySQL server 5.7.44 Linux (x86_64) (revision id: 2e6c0951)`,
+			xtrabackuplog.Engine{Name: "xtrabackup", Version: "2.4.29", ServerVersion: "5.7.44"}),
+		Entry("mariabackup",
+			`mariabackup based on MariaDB server 10.6.16-MariaDB Linux (x86_64)`,
+			xtrabackuplog.Engine{Name: "mariabackup", Version: "10.6.16-MariaDB", ServerVersion: "10.6.16-MariaDB"}),
+	)
+
+	It("extracts the phase", func() {
+		e := xtrabackuplog.ParseLine(`2024-04-22T18:03:31.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Starting to backup non-InnoDB tables and files`)
+		Expect(e.Phase).To(Equal(xtrabackuplog.PhaseCopyingNonInnoDB))
+	})
+})
+
+var _ = Describe("Parser", func() {
+	var (
+		events []xtrabackuplog.Event
+		parser *xtrabackuplog.Parser
+	)
+
+	BeforeEach(func() {
+		events = nil
+		parser = xtrabackuplog.NewParser(func(e xtrabackuplog.Event) {
+			events = append(events, e)
+		})
+	})
+
+	It("emits one event per line, even when lines are split across writes", func() {
+		_, _ = parser.Write([]byte("240422 18:03:29 Connecting to MySQL"))
+		Expect(events).To(BeEmpty())
+
+		_, _ = parser.Write([]byte(" server host: localhost\n\n240422 18:03:29 Using server version 5.7.44\n240422 18:03:35 completed OK!"))
+		Expect(events).To(HaveLen(2))
+
+		parser.Flush()
+		Expect(events).To(HaveLen(3))
+		Expect(events[2].Phase).To(Equal(xtrabackuplog.PhaseCompleted))
+	})
+
+	It("pairs xtrabackup 2.4 worker completion lines with the file being copied", func() {
+		_, _ = parser.Write([]byte("240422 18:03:29 [01] Streaming ./ibdata1\n" +
+			"240422 18:03:29 [02] Streaming ./mysql/user.frm to <STDOUT>\n" +
+			"240422 18:03:30 [02]        ...done\n" +
+			"240422 18:03:30 [01]        ...done\n"))
+
+		Expect(events).To(HaveLen(4))
+		Expect(events[2].Type).To(Equal(xtrabackuplog.FileCopied))
+		Expect(events[2].File).To(Equal("./mysql/user.frm"))
+		Expect(events[3].Type).To(Equal(xtrabackuplog.FileCopied))
+		Expect(events[3].File).To(Equal("./ibdata1"))
+	})
+
+	It("tracks progress", func() {
+		_, _ = parser.Write([]byte(
+			"2024-04-22T18:03:29.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Generating a list of tablespaces\n" +
+				"2024-04-22T18:03:29.000000-00:00 1 [Note] [MY-011825] [Xtrabackup] Done: Streaming ./ibdata1\n" +
+				"2024-04-22T18:03:29.000000-00:00 1 [Note] [MY-011825] [Xtrabackup] Done: Streaming ./undo_001\n" +
+				"2024-04-22T18:03:29.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] >> log scanned up to (19006600)\n"))
+
+		Expect(parser.Progress()).To(Equal(xtrabackuplog.Progress{
+			Phase:       xtrabackuplog.PhaseCopyingInnoDB,
+			FilesCopied: 2,
+			LastFile:    "./undo_001",
+			LSN:         19006600,
+		}))
+
+		_, _ = parser.Write([]byte("2024-04-22T18:03:31.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Transaction log of lsn (19006580) to (19006620) was copied.\n"))
+
+		Expect(parser.Progress().FromLSN).To(Equal(uint64(19006580)))
+		Expect(parser.Progress().LSN).To(Equal(uint64(19006620)))
+	})
+
+	It("keeps the replica position", func() {
+		Expect(parser.Progress().Replica).To(BeNil())
+
+		_, _ = parser.Write([]byte("240422 18:03:34 MySQL slave binlog position: master host '10.0.0.1', filename 'mysql-bin.000002', position '154'\n"))
+
+		Expect(parser.Progress().Replica).To(PointTo(MatchFields(IgnoreExtras, Fields{
+			"BinlogFile":     Equal("mysql-bin.000002"),
+			"BinlogPosition": Equal("154"),
+		})))
+	})
+
+	Describe("redo log archiving", func() {
+		It("is not reported as failed when xtrabackup does not say so", func() {
+			_, _ = parser.Write([]byte("240422 18:03:35 completed OK!\n"))
+			Expect(parser.Progress().RedoLogArchiveFailed).To(BeFalse())
+		})
+
+		It("is reported as failed when xtrabackup fails to start it", func() {
+			_, _ = parser.Write([]byte("2024-04-22T18:03:29.000000-00:00 0 [Note] [MY-011825] [Xtrabackup] Redo Log Archiving is not set up.\n"))
+			Expect(parser.Progress().RedoLogArchiveFailed).To(BeTrue())
+			Expect(parser.Failure()).To(BeNil())
+		})
+
+		It("does not hide errors that mention it", func() {
+			_, _ = parser.Write([]byte("2024-04-22T18:03:29.000000-00:00 0 [ERROR] [MY-011825] [Xtrabackup] Redo Log Archiving failed: the archive directory is full\n"))
+			Expect(parser.Failure()).To(PointTo(MatchFields(IgnoreExtras, Fields{
+				"Kind": Equal(xtrabackuplog.FatalFailure),
+			})))
+		})
+	})
+
+	Describe("Failure", func() {
+		It("is nil when nothing went wrong", func() {
+			_, _ = parser.Write([]byte("240422 18:03:35 completed OK!\n"))
+			Expect(parser.Failure()).To(BeNil())
+		})
+
+		It("reports the first fatal error", func() {
+			_, _ = parser.Write([]byte("xtrabackup: error: first\nxtrabackup: error: second\n"))
+			Expect(parser.Failure()).To(MatchError("FATAL: error: first"))
+		})
+
+		It("prefers a redo log overrun over other errors", func() {
+			_, _ = parser.Write([]byte("xtrabackup: error: copying failed\nxtrabackup: error: log block numbers mismatch:\n"))
+			Expect(parser.Failure().Kind).To(Equal(xtrabackuplog.RedoLogOverrunFailure))
+		})
+	})
+})
+
+var _ = Describe("Event.LogTo", func() {
+	var logger *lagertest.TestLogger
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	It("logs errors at the error level", func() {
+		xtrabackuplog.ParseLine("xtrabackup: error: failed to connect").LogTo(logger, "xtrabackup")
+
+		Expect(logger.Logs()).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Message":  Equal("test.xtrabackup"),
+			"LogLevel": Equal(lager.ERROR),
+			"Data":     HaveKeyWithValue("error", "xtrabackup: error: failed to connect"),
+		})))
+	})
+
+	It("logs phase changes at the info level", func() {
+		xtrabackuplog.ParseLine("240422 18:03:35 completed OK!").LogTo(logger, "xtrabackup")
+
+		Expect(logger.Logs()).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"LogLevel": Equal(lager.INFO),
+			"Data":     HaveKeyWithValue("phase", "completed"),
+		})))
+	})
+
+	It("logs routine output at the debug level", func() {
+		xtrabackuplog.ParseLine("240422 18:03:35 Using server version 5.7.44").LogTo(logger, "xtrabackup")
+
+		Expect(logger.Logs()).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"LogLevel": Equal(lager.DEBUG),
+		})))
+	})
+})
@@ -0,0 +1,18 @@
+package xtrabackuplog
+
+import "strings"
+
+var redoLogArchiveFailures = []string{"not set up", "not available", "failed", "unable", "error"}
+
+func isRedoLogArchiveFailure(message string) bool {
+	lower := strings.ToLower(message)
+	if !strings.Contains(lower, "redo log archiving") {
+		return false
+	}
+	for _, f := range redoLogArchiveFailures {
+		if strings.Contains(lower, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package xtrabackuplog
+
+import (
+	"regexp"
+	"strings"
+)
+
+const replicaPositionPrefix = "MySQL slave binlog position:"
+
+var (
+	replicaField        = regexp.MustCompile(`(master host|purge list|filename|position|channel name:?)\s*'([^']*)'`)
+	replicaGTIDSlavePos = regexp.MustCompile(`gtid_slave_pos\s+(\S+)`)
+)
+
+type ReplicaPosition struct {
+	SourceHost     string
+	BinlogFile     string
+	BinlogPosition string
+	GTIDSet        string
+	Channel        string
+}
+
+func parseReplicaPosition(message string) *ReplicaPosition {
+	i := strings.Index(message, replicaPositionPrefix)
+	if i < 0 {
+		return nil
+	}
+	rest := message[i+len(replicaPositionPrefix):]
+
+	p := &ReplicaPosition{}
+	for _, m := range replicaField.FindAllStringSubmatch(rest, -1) {
+		switch strings.TrimSuffix(m[1], ":") {
+		case "master host":
+			p.SourceHost = m[2]
+		case "purge list":
+			p.GTIDSet = m[2]
+		case "filename":
+			p.BinlogFile = m[2]
+		case "position":
+			p.BinlogPosition = m[2]
+		case "channel name":
+			p.Channel = m[2]
+		}
+	}
+	if m := replicaGTIDSlavePos.FindStringSubmatch(rest); m != nil {
+		p.GTIDSet = strings.TrimSuffix(m[1], ",")
+	}
+
+	return p
+}
@@ -0,0 +1,13 @@
+package xtrabackuplog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestXtrabackuplog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Xtrabackup Log Suite")
+}